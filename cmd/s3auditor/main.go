@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"os"
 
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/cli"
@@ -17,6 +18,11 @@ func main() {
 		return
 	}
 
+	// Subcommands run headless for cron, CI and containers
+	if len(os.Args) > 1 {
+		os.Exit(cli.Run(os.Args[1:]))
+	}
+
 	ui.ShowWelcomeScreen()
 
 	clients, err := awsutils.NewAWSClients(context.Background())
@@ -42,7 +48,7 @@ func main() {
 				log.Printf("Error listing buckets: %v", err)
 				continue
 			}
-			cli.DisplayBucketsList(clients.S3Client, buckets)
+			cli.DisplayBucketsList(clients.Config, clients.S3Client, buckets)
 		case "Audit a Bucket":
 			cli.HandleBucketAudit(clients.Config, clients.S3Client, clients.MacieClient)
 		case "Exit":
@@ -0,0 +1,55 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Names of the backends that can look for sensitive data
+const (
+	ClassifierMacie = "macie"
+	ClassifierLocal = "local"
+	ClassifierLLM   = "llm"
+)
+
+// Classifiers lists the available sensitive data backends
+var Classifiers = []string{ClassifierMacie, ClassifierLocal, ClassifierLLM}
+
+// Classifier finds sensitive data in the objects of a bucket
+type Classifier interface {
+	// Classify returns the sensitive data found per object and one finding
+	// for each affected object
+	Classify(ctx context.Context, bucketName string) (*models.SensitiveDataSummary, []models.Finding, error)
+}
+
+// macieClassifier runs a Macie classification job through the scanner, which
+// holds the job scope, data identifiers and job store
+type macieClassifier struct {
+	scanner *Scanner
+}
+
+func (c macieClassifier) Classify(ctx context.Context, bucketName string) (*models.SensitiveDataSummary, []models.Finding, error) {
+	return c.scanner.checkSensitiveData(ctx, bucketName)
+}
+
+// sensitiveObjectFinding reports the sensitive data found in one object
+func sensitiveObjectFinding(bucketName, title, source string, object models.SensitiveObject) models.Finding {
+	f := models.Finding{
+		CheckID:     CheckIDSensitiveData,
+		Severity:    object.Severity,
+		Title:       title,
+		Resource:    models.BucketARN(bucketName),
+		Evidence:    []string{fmt.Sprintf("%s (%s)", source, object.FindingType)},
+		Remediation: "Review the affected objects and remove or protect the sensitive data",
+	}
+	if object.Key != "" {
+		f.Resource = models.ObjectARN(bucketName, object.Key)
+		f.Evidence = append(f.Evidence, fmt.Sprintf("Object: %s", object.Key))
+	}
+	for _, detection := range object.Detections {
+		f.Evidence = append(f.Evidence, fmt.Sprintf("%s (%s): %d occurrence(s)", detection.Type, detection.Category, detection.Count))
+	}
+	return f
+}
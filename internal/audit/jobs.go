@@ -0,0 +1,110 @@
+package audit
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"sync"
+
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// JobStore persists the Macie jobs the tool creates, so that a later run can
+// reattach to a job that outlived the process or clean it up
+type JobStore struct {
+	path string
+	mu   sync.Mutex
+}
+
+// NewJobStore returns a store that keeps its records in the JSON file at path
+func NewJobStore(path string) *JobStore {
+	return &JobStore{path: path}
+}
+
+// Jobs returns the recorded jobs, oldest first
+func (s *JobStore) Jobs() ([]models.MacieJobRecord, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.load()
+}
+
+// Add records a newly created job
+func (s *JobStore) Add(job models.MacieJobRecord) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return err
+	}
+	return s.save(append(jobs, job))
+}
+
+// Remove forgets a job once its results are collected or it is cancelled
+func (s *JobStore) Remove(jobID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	jobs, err := s.load()
+	if err != nil {
+		return err
+	}
+	kept := jobs[:0]
+	for _, job := range jobs {
+		if job.JobID != jobID {
+			kept = append(kept, job)
+		}
+	}
+	return s.save(kept)
+}
+
+// LatestForBucket returns the most recently created job for the bucket in the
+// given account and region, if any
+func (s *JobStore) LatestForBucket(accountID, region, bucketName string) (*models.MacieJobRecord, error) {
+	jobs, err := s.Jobs()
+	if err != nil {
+		return nil, err
+	}
+	for i := len(jobs) - 1; i >= 0; i-- {
+		if jobs[i].Bucket == bucketName && jobs[i].InSession(accountID, region) {
+			return &jobs[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (s *JobStore) load() ([]models.MacieJobRecord, error) {
+	data, err := os.ReadFile(s.path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to read Macie job records: %w", err)
+	}
+
+	var jobs []models.MacieJobRecord
+	if err := json.Unmarshal(data, &jobs); err != nil {
+		return nil, fmt.Errorf("unable to parse Macie job records in %s: %w", s.path, err)
+	}
+	return jobs, nil
+}
+
+func (s *JobStore) save(jobs []models.MacieJobRecord) error {
+	if len(jobs) == 0 {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("unable to remove Macie job records: %w", err)
+		}
+		return nil
+	}
+
+	data, err := json.MarshalIndent(jobs, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, data, 0o600); err != nil {
+		return fmt.Errorf("unable to write Macie job records: %w", err)
+	}
+	return nil
+}
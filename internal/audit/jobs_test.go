@@ -0,0 +1,200 @@
+package audit
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/macie2"
+	macie2types "github.com/aws/aws-sdk-go-v2/service/macie2/types"
+	"github.com/aws/smithy-go"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestJobStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	store := NewJobStore(path)
+
+	jobs, err := store.Jobs()
+	assert.NoError(t, err)
+	assert.Empty(t, jobs)
+
+	created := time.Date(2024, time.March, 1, 12, 0, 0, 0, time.UTC)
+	job := func(id, bucket, region string, created time.Time) models.MacieJobRecord {
+		return models.MacieJobRecord{JobID: id, Bucket: bucket, AccountID: "111122223333", Region: region, CreatedAt: created}
+	}
+	assert.NoError(t, store.Add(job("job-1", "bucket-a", "us-east-1", created)))
+	assert.NoError(t, store.Add(job("job-2", "bucket-b", "us-east-1", created)))
+	assert.NoError(t, store.Add(job("job-3", "bucket-a", "us-east-1", created.Add(time.Hour))))
+	assert.NoError(t, store.Add(job("job-4", "bucket-a", "eu-west-1", created.Add(2*time.Hour))))
+
+	latest, err := NewJobStore(path).LatestForBucket("111122223333", "us-east-1", "bucket-a")
+	assert.NoError(t, err)
+	expected := job("job-3", "bucket-a", "us-east-1", created.Add(time.Hour))
+	assert.Equal(t, &expected, latest)
+
+	latest, err = store.LatestForBucket("111122223333", "us-east-1", "bucket-c")
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	// Jobs in other accounts are invisible to this session
+	latest, err = store.LatestForBucket("444455556666", "eu-west-1", "bucket-a")
+	assert.NoError(t, err)
+	assert.Nil(t, latest)
+
+	assert.NoError(t, store.Remove("job-3"))
+	latest, err = store.LatestForBucket("111122223333", "us-east-1", "bucket-a")
+	assert.NoError(t, err)
+	assert.Equal(t, "job-1", latest.JobID)
+
+	assert.NoError(t, store.Remove("job-4"))
+	assert.NoError(t, store.Remove("job-1"))
+	assert.NoError(t, store.Remove("job-2"))
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err), "the file is removed once no jobs are left")
+}
+
+func TestJobStore_Corrupt(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "jobs.json")
+	assert.NoError(t, os.WriteFile(path, []byte("not json"), 0o600))
+
+	_, err := NewJobStore(path).Jobs()
+	assert.Error(t, err)
+}
+
+func TestScanner_ReattachableJob(t *testing.T) {
+	scope := awsutils.MacieJobScope{IncludePrefixes: []string{"exports/"}}
+	definition := func(scope awsutils.MacieJobScope) string {
+		scanner := NewScanner(aws.Config{}, nil, nil, nil)
+		scanner.SetMacieScope(scope)
+		return scanner.macieJobDefinition()
+	}
+
+	tests := []struct {
+		name         string
+		status       macie2types.JobStatus
+		describeErr  error
+		cancelErr    error
+		reattach     func(models.MacieJobRecord) bool
+		record       func(job *models.MacieJobRecord)
+		expectedJob  string
+		expectRecord bool
+		expectCancel bool
+	}{
+		{
+			name:         "Running job",
+			status:       macie2types.JobStatusRunning,
+			expectedJob:  "job-1",
+			expectRecord: true,
+		},
+		{
+			name:         "Complete job whose results were not collected",
+			status:       macie2types.JobStatusComplete,
+			expectedJob:  "job-1",
+			expectRecord: true,
+		},
+		{
+			name:         "User declines",
+			status:       macie2types.JobStatusRunning,
+			reattach:     func(models.MacieJobRecord) bool { return false },
+			expectRecord: true,
+		},
+		{
+			name:         "Job with another scope",
+			status:       macie2types.JobStatusComplete,
+			record:       func(job *models.MacieJobRecord) { job.Definition = definition(awsutils.MacieJobScope{}) },
+			expectRecord: true,
+		},
+		{
+			name:         "Job older than a day",
+			status:       macie2types.JobStatusComplete,
+			record:       func(job *models.MacieJobRecord) { job.CreatedAt = time.Now().Add(-48 * time.Hour) },
+			expectRecord: true,
+		},
+		{
+			name:         "Job in another region",
+			status:       macie2types.JobStatusComplete,
+			record:       func(job *models.MacieJobRecord) { job.Region = "eu-west-1" },
+			expectRecord: true,
+		},
+		{
+			name:   "Cancelled job is forgotten",
+			status: macie2types.JobStatusCancelled,
+		},
+		{
+			name:         "Paused job is cancelled and forgotten",
+			status:       macie2types.JobStatusUserPaused,
+			expectCancel: true,
+		},
+		{
+			name:         "Paused job that cannot be cancelled is kept",
+			status:       macie2types.JobStatusPaused,
+			cancelErr:    &smithy.GenericAPIError{Code: "AccessDeniedException"},
+			expectRecord: true,
+			expectCancel: true,
+		},
+		{
+			name:        "Deleted job is forgotten",
+			describeErr: &smithy.GenericAPIError{Code: "ResourceNotFoundException"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewJobStore(filepath.Join(t.TempDir(), "jobs.json"))
+			job := models.MacieJobRecord{JobID: "job-1", Bucket: "bucket", AccountID: "111122223333", Region: "us-east-1", Definition: definition(scope), CreatedAt: time.Now()}
+			if tt.record != nil {
+				tt.record(&job)
+			}
+			assert.NoError(t, store.Add(job))
+
+			mockMacie := new(MockMacieClient)
+			mockMacie.On("DescribeClassificationJob", mock.Anything, mock.MatchedBy(func(in *macie2.DescribeClassificationJobInput) bool {
+				return aws.ToString(in.JobId) == "job-1"
+			})).Return(&macie2.DescribeClassificationJobOutput{JobStatus: tt.status}, tt.describeErr)
+			mockMacie.On("UpdateClassificationJob", mock.Anything, mock.MatchedBy(func(in *macie2.UpdateClassificationJobInput) bool {
+				return aws.ToString(in.JobId) == "job-1" && in.JobStatus == macie2types.JobStatusCancelled
+			})).Return(&macie2.UpdateClassificationJobOutput{}, tt.cancelErr)
+
+			scanner := NewScanner(aws.Config{Region: "us-east-1"}, new(mockS3Client), mockMacie, new(mockSTSClient))
+			scanner.SetJobStore(store)
+			scanner.SetMacieScope(scope)
+			scanner.SetMacieReattach(tt.reattach)
+
+			assert.Equal(t, tt.expectedJob, scanner.reattachableJob(context.Background(), "111122223333", "bucket"))
+			jobs, err := store.Jobs()
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectRecord, len(jobs) == 1)
+			if tt.expectCancel {
+				mockMacie.AssertCalled(t, "UpdateClassificationJob", mock.Anything, mock.Anything)
+			} else {
+				mockMacie.AssertNotCalled(t, "UpdateClassificationJob", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestScanner_CancelMacieJob(t *testing.T) {
+	store := NewJobStore(filepath.Join(t.TempDir(), "jobs.json"))
+	assert.NoError(t, store.Add(models.MacieJobRecord{JobID: "job-1", Bucket: "bucket", CreatedAt: time.Now()}))
+
+	mockMacie := new(MockMacieClient)
+	mockMacie.On("UpdateClassificationJob", mock.Anything, mock.MatchedBy(func(in *macie2.UpdateClassificationJobInput) bool {
+		return aws.ToString(in.JobId) == "job-1" && in.JobStatus == macie2types.JobStatusCancelled
+	})).Return(&macie2.UpdateClassificationJobOutput{}, nil)
+
+	scanner := NewScanner(aws.Config{Region: "us-east-1"}, new(mockS3Client), mockMacie, new(mockSTSClient))
+	scanner.SetJobStore(store)
+	scanner.cancelMacieJob("job-1")
+
+	mockMacie.AssertExpectations(t)
+	jobs, err := store.Jobs()
+	assert.NoError(t, err)
+	assert.Empty(t, jobs)
+}
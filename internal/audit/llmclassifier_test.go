@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/llm"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+// llmStub serves chat completions, answering each object with the verdict
+// registered for its key
+func llmStub(t *testing.T, verdicts map[string]string) (*httptest.Server, *int) {
+	requests := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		var request struct {
+			Messages []struct {
+				Content string `json:"content"`
+			} `json:"messages"`
+		}
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		user := request.Messages[len(request.Messages)-1].Content
+		key, _, _ := strings.Cut(strings.TrimPrefix(user, "Object: "), "\n")
+		verdict, ok := verdicts[key]
+		if !ok {
+			http.Error(w, "unexpected object "+key, http.StatusBadRequest)
+			return
+		}
+		content, _ := json.Marshal(verdict)
+		fmt.Fprintf(w, `{"choices":[{"message":{"role":"assistant","content":%s}}],"usage":{"prompt_tokens":400,"completion_tokens":40}}`, content)
+	}))
+	t.Cleanup(server.Close)
+	return server, &requests
+}
+
+func llmTestBucket(objects map[string]string) *mockS3Client {
+	mockS3 := new(mockS3Client)
+	var contents []s3types.Object
+	for _, key := range []string{"customers.csv", "image.png", "notes.txt"} {
+		if _, ok := objects[key]; ok {
+			contents = append(contents, s3types.Object{Key: aws.String(key), Size: aws.Int64(100)})
+		}
+	}
+	mockS3.On("ListObjectsV2", mock.Anything, mock.Anything).Return(&s3.ListObjectsV2Output{Contents: contents}, nil)
+	for key, content := range objects {
+		key, content := key, content
+		mockS3.On("GetObject", mock.Anything, mock.MatchedBy(func(in *s3.GetObjectInput) bool {
+			return aws.ToString(in.Key) == key
+		})).Return(&s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(content))}, nil)
+	}
+	return mockS3
+}
+
+func TestLLMClassifier_Classify(t *testing.T) {
+	mockS3 := llmTestBucket(map[string]string{
+		"customers.csv": "name,email,phone\njane,jane@example.com,555-0100\n",
+		"image.png":     "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR",
+		"notes.txt":     "nothing to see here\n",
+	})
+	server, requests := llmStub(t, map[string]string{
+		"customers.csv": "```json\n" + `{"detections":[` +
+			`{"category":"PERSONAL_INFORMATION","type":"email address","count":1,"lines":[2]},` +
+			`{"category":"personal_information","type":"PHONE_NUMBER","lines":[2,99]},` +
+			`{"category":"OTHER","type":"NAME","count":1,"lines":[2]}]}` + "\n```",
+		"notes.txt": `{"detections":[]}`,
+	})
+	client, err := llm.NewClient(llm.Config{Endpoint: server.URL, Model: "llama3.1"})
+	assert.NoError(t, err)
+	budget := &llm.Budget{}
+
+	classifier := NewLLMClassifier(mockS3, client, LLMClassifierOptions{
+		Objects:         LocalScanOptions{MaxObjects: 10, MaxBytes: 1024},
+		MaxOutputTokens: 256,
+		Budget:          budget,
+	})
+	summary, findings, err := classifier.Classify(context.Background(), "bucket")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, *requests)
+	assert.Equal(t, ClassifierLLM, summary.Classifier)
+	assert.Equal(t, 2, summary.ObjectsScanned)
+	assert.Equal(t, 1, summary.FindingCount)
+	object := summary.Objects[0]
+	assert.Equal(t, "customers.csv", object.Key)
+	assert.Equal(t, models.SeverityMedium, object.Severity)
+	assert.Equal(t, []models.SensitiveDetection{
+		{Category: "PERSONAL_INFORMATION", Type: "EMAIL_ADDRESS", Count: 1, Locations: []string{"line 2"}},
+		{Category: "PERSONAL_INFORMATION", Type: "PHONE_NUMBER", Count: 1, Locations: []string{"line 2"}},
+	}, object.Detections)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, CheckIDSensitiveData, findings[0].CheckID)
+	assert.Equal(t, "arn:aws:s3:::bucket/customers.csv", findings[0].Resource)
+	usage, _ := budget.Used()
+	assert.Equal(t, llm.Usage{InputTokens: 800, OutputTokens: 80}, usage)
+}
+
+func TestLLMClassifier_Budget(t *testing.T) {
+	mockS3 := llmTestBucket(map[string]string{
+		"customers.csv": "jane,4111111111111111\n",
+		"notes.txt":     "nothing to see here\n",
+	})
+	server, requests := llmStub(t, map[string]string{
+		"customers.csv": `{"detections":[{"category":"FINANCIAL_INFORMATION","type":"CREDIT_CARD_NUMBER","count":1,"lines":[1]}]}`,
+		"notes.txt":     `{"detections":[]}`,
+	})
+	client, err := llm.NewClient(llm.Config{Endpoint: server.URL, Model: "llama3.1"})
+	assert.NoError(t, err)
+
+	// Room for one request: the stub reports 440 tokens, leaving too little
+	// for the second reservation
+	classifier := NewLLMClassifier(mockS3, client, LLMClassifierOptions{
+		MaxOutputTokens: 256,
+		Budget:          &llm.Budget{MaxTokens: 800},
+	})
+	summary, findings, err := classifier.Classify(context.Background(), "bucket")
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, *requests)
+	assert.Equal(t, 1, summary.ObjectsScanned)
+	assert.Equal(t, models.SeverityHigh, summary.Objects[0].Severity)
+	assert.Len(t, findings, 1)
+
+	// A budget too small for the first request fails the check
+	*requests = 0
+	classifier = NewLLMClassifier(mockS3, client, LLMClassifierOptions{
+		MaxOutputTokens: 256,
+		Budget:          &llm.Budget{MaxTokens: 100},
+	})
+	_, _, err = classifier.Classify(context.Background(), "bucket")
+
+	assert.ErrorIs(t, err, llm.ErrBudgetExhausted)
+	assert.Equal(t, 0, *requests)
+}
+
+func TestLLMClassifier_EndpointDown(t *testing.T) {
+	mockS3 := llmTestBucket(map[string]string{"notes.txt": "nothing to see here\n"})
+	server, _ := llmStub(t, nil)
+	client, err := llm.NewClient(llm.Config{Endpoint: server.URL, Model: "llama3.1"})
+	assert.NoError(t, err)
+
+	_, _, err = NewLLMClassifier(mockS3, client, LLMClassifierOptions{}).Classify(context.Background(), "bucket")
+
+	assert.ErrorContains(t, err, "model requests failed")
+}
@@ -0,0 +1,463 @@
+package audit
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/big"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	macie2types "github.com/aws/aws-sdk-go-v2/service/macie2/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/fatih/color"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/extract"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+const (
+	// maxLocations caps the locations recorded per type of sensitive data in
+	// an object, as Macie does
+	maxLocations = 15
+	// maxLineLength is the longest line the local classifier reads; objects
+	// with longer lines are skipped
+	maxLineLength = 1024 * 1024
+)
+
+// errStopScan ends reading an object early once a scanner has what it needs
+var errStopScan = errors.New("stop scanning")
+
+// LocalScanOptions controls which objects the local classifier, the secret
+// scanner and the column profiler read
+type LocalScanOptions struct {
+	Prefix string
+	// MaxObjects caps how many objects are read per bucket; zero reads every
+	// object
+	MaxObjects int
+	// MaxBytes caps how much of each object is read; zero reads whole objects
+	MaxBytes int64
+	// Extract limits how far archives are unpacked
+	Extract extract.Options
+}
+
+// LocalClassifier looks for sensitive data by streaming objects through
+// regular expressions, validating checksums where the data has one. It costs
+// only the S3 requests. It reads the text of PDFs, Office documents, JSON,
+// Parquet and archives, but understands fewer formats than Macie.
+type LocalClassifier struct {
+	s3Client awsutils.S3ClientAPI
+	opts     LocalScanOptions
+}
+
+// NewLocalClassifier returns a classifier that reads objects with s3Client
+func NewLocalClassifier(s3Client awsutils.S3ClientAPI, opts LocalScanOptions) *LocalClassifier {
+	return &LocalClassifier{s3Client: s3Client, opts: opts}
+}
+
+// Patterns shared by the local classifier and the secret scanner
+var (
+	awsAccessKeyPattern = regexp.MustCompile(`\b(?:AKIA|ASIA)[0-9A-Z]{16}\b`)
+	// awsSecretKeyPattern captures the key in an assignment such as
+	// aws_secret_access_key = ...
+	awsSecretKeyPattern = regexp.MustCompile(`(?i)aws_?secret_?access_?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})\b`)
+	privateKeyPattern   = regexp.MustCompile(`-----BEGIN (?:[A-Z0-9]+ )*PRIVATE KEY( BLOCK)?-----`)
+)
+
+// detector finds one type of sensitive data
+type detector struct {
+	dataType string
+	category macie2types.SensitiveDataItemCategory
+	severity models.Severity
+	pattern  *regexp.Regexp
+	// valid rejects matches that fail a checksum or format rule; nil accepts
+	// every match
+	valid func(match string) bool
+}
+
+// detectors run in order, and a match overlapping one found earlier on the
+// same line is ignored, so more specific patterns come first
+var detectors = []detector{
+	{
+		dataType: "AWS_CREDENTIALS",
+		category: macie2types.SensitiveDataItemCategoryCredentials,
+		severity: models.SeverityHigh,
+		pattern:  awsAccessKeyPattern,
+	},
+	{
+		dataType: "AWS_CREDENTIALS",
+		category: macie2types.SensitiveDataItemCategoryCredentials,
+		severity: models.SeverityHigh,
+		pattern:  awsSecretKeyPattern,
+	},
+	{
+		dataType: "PRIVATE_KEY",
+		category: macie2types.SensitiveDataItemCategoryCredentials,
+		severity: models.SeverityHigh,
+		pattern:  privateKeyPattern,
+	},
+	{
+		dataType: "IBAN",
+		category: macie2types.SensitiveDataItemCategoryFinancialInformation,
+		severity: models.SeverityHigh,
+		pattern:  regexp.MustCompile(`\b[A-Z]{2}\d{2}(?:[A-Z0-9]{11,30}|(?: [A-Z0-9]{4}){2,7}(?: [A-Z0-9]{1,4})?)\b`),
+		valid:    validIBAN,
+	},
+	{
+		dataType: "CREDIT_CARD_NUMBER",
+		category: macie2types.SensitiveDataItemCategoryFinancialInformation,
+		severity: models.SeverityHigh,
+		pattern:  regexp.MustCompile(`\b\d(?:[ -]?\d){12,18}\b`),
+		valid:    validCardNumber,
+	},
+	{
+		dataType: "USA_SOCIAL_SECURITY_NUMBER",
+		category: macie2types.SensitiveDataItemCategoryPersonalInformation,
+		severity: models.SeverityHigh,
+		pattern:  regexp.MustCompile(`\b\d{3}-\d{2}-\d{4}\b`),
+		valid:    validSSN,
+	},
+	{
+		dataType: "EMAIL_ADDRESS",
+		category: macie2types.SensitiveDataItemCategoryPersonalInformation,
+		severity: models.SeverityMedium,
+		pattern:  regexp.MustCompile(`\b[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}\b`),
+	},
+	{
+		dataType: "PHONE_NUMBER",
+		category: macie2types.SensitiveDataItemCategoryPersonalInformation,
+		severity: models.SeverityMedium,
+		pattern:  regexp.MustCompile(`(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\)|\b\d{3})[ .-]?\d{3}[ .-]?\d{4}\b`),
+	},
+}
+
+// Classify reads the objects of the bucket and reports those that contain
+// sensitive data
+func (c *LocalClassifier) Classify(ctx context.Context, bucketName string) (*models.SensitiveDataSummary, []models.Finding, error) {
+	keys, err := listObjectsToScan(ctx, c.s3Client, bucketName, c.opts, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("unable to list objects: %w", err)
+	}
+	color.Yellow("🔍 Scanning %d object(s) in %s for sensitive data\n", len(keys), bucketName)
+	log.Printf("Scanning %d object(s) in %s for sensitive data", len(keys), bucketName)
+
+	summary := &models.SensitiveDataSummary{Classifier: ClassifierLocal}
+	var findings []models.Finding
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return nil, nil, err
+		}
+
+		var matches detectionCounter
+		scanned, skipped, err := scanObjectLines(ctx, c.s3Client, bucketName, key, c.opts, func(line objectLine) bool {
+			matches.scanLine(line.Text, line.location())
+			return true
+		})
+		if err != nil {
+			log.Printf("Warning: unable to scan object %s in bucket %s: %v", key, bucketName, err)
+			summary.Skipped = append(summary.Skipped, unreadableObject(key, err))
+			continue
+		}
+		summary.Skipped = append(summary.Skipped, skipped...)
+		if !scanned {
+			continue
+		}
+		summary.ObjectsScanned++
+		detections := matches.detections
+		if len(detections) == 0 {
+			continue
+		}
+
+		object := models.SensitiveObject{
+			Key:         key,
+			FindingType: string(localFindingType(detections)),
+			Severity:    localSeverity(detections),
+			Detections:  detections,
+		}
+		log.Printf("Local scan: %d occurrence(s) of sensitive data in %s", object.Occurrences(), key)
+		summary.Objects = append(summary.Objects, object)
+		findings = append(findings, sensitiveObjectFinding(bucketName, "Local scan detected sensitive data", "Local scan", object))
+	}
+	summary.FindingCount = len(summary.Objects)
+	summary.Sort()
+
+	if len(findings) == 0 {
+		color.Green("✅ No sensitive data found.")
+		log.Println("No sensitive data found.")
+	}
+	return summary, findings, nil
+}
+
+// listObjectsToScan returns the keys of the objects to read, leaving out
+// empty objects and archived objects that cannot be read without a restore.
+// match, when not nil, selects the keys to return.
+func listObjectsToScan(ctx context.Context, s3Client awsutils.S3ClientAPI, bucketName string, opts LocalScanOptions, match func(key string) bool) ([]string, error) {
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucketName)}
+	if opts.Prefix != "" {
+		input.Prefix = aws.String(opts.Prefix)
+	}
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s3Client, input)
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			if aws.ToInt64(object.Size) == 0 ||
+				object.StorageClass == s3types.ObjectStorageClassGlacier ||
+				object.StorageClass == s3types.ObjectStorageClassDeepArchive {
+				continue
+			}
+			if match != nil && !match(aws.ToString(object.Key)) {
+				continue
+			}
+			keys = append(keys, aws.ToString(object.Key))
+			if opts.MaxObjects > 0 && len(keys) >= opts.MaxObjects {
+				return keys, nil
+			}
+		}
+	}
+	return keys, nil
+}
+
+// objectLine is a line of text in an object, or in a file inside it such as
+// a file in an archive or a worksheet
+type objectLine struct {
+	// File is empty for the object itself
+	File   string
+	Text   string
+	Number int
+}
+
+// location describes where the line is, e.g. "line 12" or
+// "customers.csv line 12"
+func (l objectLine) location() string {
+	if l.File == "" {
+		return fmt.Sprintf("line %d", l.Number)
+	}
+	return fmt.Sprintf("%s line %d", l.File, l.Number)
+}
+
+// scanObjectLines reads an object and calls fn for each line of its text,
+// extracting the text of documents and the files in archives. fn returns
+// false to stop reading. scanned reports whether any text was read, and
+// skipped lists what no text could be read from, such as images.
+func scanObjectLines(ctx context.Context, s3Client awsutils.S3ClientAPI, bucketName, key string, opts LocalScanOptions, fn func(line objectLine) bool) (scanned bool, skipped []models.SkippedObject, err error) {
+	body, truncated, err := getObjectToScan(ctx, s3Client, bucketName, key, opts)
+	if err != nil {
+		return false, nil, err
+	}
+	defer body.Close()
+
+	extracted, err := extract.Extract(key, body, truncated, opts.Extract, func(doc extract.Document) error {
+		scanned = true
+		scanner := bufio.NewScanner(doc.Text)
+		scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+		for number := 1; scanner.Scan(); number++ {
+			if !fn(objectLine{File: doc.Name, Text: scanner.Text(), Number: number}) {
+				return errStopScan
+			}
+		}
+		return scanner.Err()
+	})
+	if err != nil && !errors.Is(err, errStopScan) {
+		return false, nil, err
+	}
+
+	for _, s := range extracted {
+		skipped = append(skipped, models.SkippedObject{Key: key, File: s.Name, Format: s.Format, Reason: s.Reason})
+		log.Printf("Skipped %s in bucket %s: %s", displayName(key, s.Name), bucketName, s.Reason)
+	}
+	return scanned, skipped, nil
+}
+
+// unreadableObject records an object that could not be read or extracted.
+// Denials are named plainly so they are counted together in reports.
+func unreadableObject(key string, err error) models.SkippedObject {
+	reason := err.Error()
+	if awsutils.IsAccessDenied(err) {
+		reason = "access denied"
+	}
+	return models.SkippedObject{Key: key, Reason: reason}
+}
+
+// getObjectToScan opens an object, reading at most opts.MaxBytes of it.
+// truncated reports that the object is larger.
+func getObjectToScan(ctx context.Context, s3Client awsutils.S3ClientAPI, bucketName, key string, opts LocalScanOptions) (body io.ReadCloser, truncated bool, err error) {
+	input := &s3.GetObjectInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	}
+	if opts.MaxBytes > 0 {
+		input.Range = aws.String(fmt.Sprintf("bytes=0-%d", opts.MaxBytes-1))
+	}
+	output, err := s3Client.GetObject(ctx, input)
+	if err != nil {
+		return nil, false, err
+	}
+	return output.Body, opts.MaxBytes > 0 && objectSize(output) > opts.MaxBytes, nil
+}
+
+// displayName names an object, or a file inside it, in messages
+func displayName(key, file string) string {
+	if file == "" {
+		return key
+	}
+	return key + "!" + file
+}
+
+// objectSize returns the full size of an object read with a Range header, or
+// -1 when S3 did not report it
+func objectSize(output *s3.GetObjectOutput) int64 {
+	contentRange := aws.ToString(output.ContentRange)
+	_, total, ok := strings.Cut(contentRange, "/")
+	if !ok {
+		return -1
+	}
+	size, err := strconv.ParseInt(total, 10, 64)
+	if err != nil {
+		return -1
+	}
+	return size
+}
+
+// detectionCounter totals the matches per type of sensitive data
+type detectionCounter struct {
+	detections []models.SensitiveDetection
+}
+
+func (c *detectionCounter) scanLine(line, location string) {
+	var claimed [][]int
+	for _, d := range detectors {
+		for _, span := range d.pattern.FindAllStringIndex(line, -1) {
+			if overlaps(claimed, span) {
+				continue
+			}
+			if d.valid != nil && !d.valid(line[span[0]:span[1]]) {
+				continue
+			}
+			claimed = append(claimed, span)
+			c.add(d, location)
+		}
+	}
+}
+
+func (c *detectionCounter) add(d detector, location string) {
+	for i := range c.detections {
+		detection := &c.detections[i]
+		if detection.Type != d.dataType {
+			continue
+		}
+		detection.Count++
+		if len(detection.Locations) < maxLocations && detection.Locations[len(detection.Locations)-1] != location {
+			detection.Locations = append(detection.Locations, location)
+		}
+		return
+	}
+	c.detections = append(c.detections, models.SensitiveDetection{
+		Category:  string(d.category),
+		Type:      d.dataType,
+		Count:     1,
+		Locations: []string{location},
+	})
+}
+
+func overlaps(spans [][]int, span []int) bool {
+	for _, s := range spans {
+		if span[0] < s[1] && s[0] < span[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// localFindingType names the finding the way Macie would for the categories
+// of sensitive data found in an object
+func localFindingType(detections []models.SensitiveDetection) macie2types.FindingType {
+	categories := map[string]bool{}
+	for _, d := range detections {
+		categories[d.Category] = true
+	}
+	if len(categories) > 1 {
+		return macie2types.FindingTypeSensitiveDataS3ObjectMultiple
+	}
+	switch macie2types.SensitiveDataItemCategory(detections[0].Category) {
+	case macie2types.SensitiveDataItemCategoryCredentials:
+		return macie2types.FindingTypeSensitiveDataS3ObjectCredentials
+	case macie2types.SensitiveDataItemCategoryFinancialInformation:
+		return macie2types.FindingTypeSensitiveDataS3ObjectFinancial
+	default:
+		return macie2types.FindingTypeSensitiveDataS3ObjectPersonal
+	}
+}
+
+// localSeverity is the severity of the most sensitive type of data found
+func localSeverity(detections []models.SensitiveDetection) models.Severity {
+	severity := models.SeverityLow
+	for _, detection := range detections {
+		for _, d := range detectors {
+			if d.dataType == detection.Type && d.severity > severity {
+				severity = d.severity
+			}
+		}
+	}
+	return severity
+}
+
+// validCardNumber checks the length and Luhn checksum of a card number
+func validCardNumber(match string) bool {
+	digits := strings.NewReplacer(" ", "", "-", "").Replace(match)
+	if len(digits) < 13 || len(digits) > 19 || strings.Trim(digits, "0") == "" {
+		return false
+	}
+
+	sum := 0
+	double := false
+	for i := len(digits) - 1; i >= 0; i-- {
+		d := int(digits[i] - '0')
+		if double {
+			d *= 2
+			if d > 9 {
+				d -= 9
+			}
+		}
+		sum += d
+		double = !double
+	}
+	return sum%10 == 0
+}
+
+// validIBAN checks the length and ISO 7064 mod 97 checksum of an IBAN
+func validIBAN(match string) bool {
+	iban := strings.ReplaceAll(match, " ", "")
+	if len(iban) < 15 || len(iban) > 34 {
+		return false
+	}
+
+	// Move the country code and check digits to the end and replace each
+	// letter with two digits, A=10 to Z=35
+	var numeric strings.Builder
+	for _, r := range iban[4:] + iban[:4] {
+		if r >= 'A' && r <= 'Z' {
+			numeric.WriteString(fmt.Sprint(r - 'A' + 10))
+		} else {
+			numeric.WriteRune(r)
+		}
+	}
+	n, ok := new(big.Int).SetString(numeric.String(), 10)
+	return ok && new(big.Int).Mod(n, big.NewInt(97)).Int64() == 1
+}
+
+// validSSN rejects numbers the Social Security Administration never issues
+func validSSN(match string) bool {
+	area, group, serial := match[:3], match[4:6], match[7:]
+	return area != "000" && area != "666" && area[0] != '9' && group != "00" && serial != "0000"
+}
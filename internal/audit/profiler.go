@@ -0,0 +1,418 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/fatih/color"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/extract"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// minColumnMatchRatio is the share of the non-empty values of a column that
+// must be of a kind of personal data for the column to be classified as it
+const minColumnMatchRatio = 0.5
+
+// tabularExtensions are the extensions of the objects the profiler reads,
+// also when followed by .gz
+var tabularExtensions = map[string]bool{".csv": true, ".tsv": true, ".json": true, ".jsonl": true, ".ndjson": true, ".parquet": true}
+
+// tableFormats names the formats of tables in profiles
+var tableFormats = map[string]string{extract.FormatCSV: "csv", extract.FormatJSON: "json", extract.FormatParquet: "parquet"}
+
+var (
+	// partitionPattern matches a Hive-style partition directory such as
+	// date=2024-01-31
+	partitionPattern = regexp.MustCompile(`^[^=]+=[^=]*$`)
+	// partFilePattern matches the files Spark, Hive and similar engines write
+	// a table as, such as part-00000-<uuid>.snappy.parquet and 000000_0
+	partFilePattern = regexp.MustCompile(`^(?:part|run)-\d|^\d{5,}(?:_\d+)?(?:\.|$)`)
+)
+
+// Patterns for the values of columns, which hold one value each
+var (
+	emailValuePattern  = regexp.MustCompile(`^[A-Za-z0-9._%+-]+@[A-Za-z0-9.-]+\.[A-Za-z]{2,}$`)
+	cardValuePattern   = regexp.MustCompile(`^\d(?:[ -]?\d){12,18}$`)
+	ssnValuePattern    = regexp.MustCompile(`^\d{3}-\d{2}-\d{4}$`)
+	ninoValuePattern   = regexp.MustCompile(`^[A-CEGHJ-PR-TW-Z]{2} ?\d{2} ?\d{2} ?\d{2} ?[A-D]$`)
+	phoneValuePattern  = regexp.MustCompile(`^(?:\+\d{1,3}[ .-]?)?(?:\(\d{3}\) ?|\d{3}[ .-])\d{3}[ .-]?\d{4}$|^\+\d{7,15}$`)
+	digitsValuePattern = regexp.MustCompile(`^\+?\d{7,15}$`)
+	nameValuePattern   = regexp.MustCompile(`^\p{Lu}[\p{L}'’.-]*(?:,? \p{L}[\p{L}'’.-]*){0,3}$`)
+)
+
+// columnClass recognizes one kind of personal data in the values of a column
+type columnClass struct {
+	name     string
+	severity models.Severity
+	// hint matches the names of columns that suggest the kind, whose values
+	// may match more loosely; nil gives no hint
+	hint *regexp.Regexp
+	// match reports whether a value is of this kind; hinted is set when the
+	// column name matches hint
+	match func(value string, hinted bool) bool
+}
+
+// columnClasses lists the kinds of personal data the profiler looks for. A
+// column is classified as the kind most of its values match.
+var columnClasses = []columnClass{
+	{
+		name:     models.ColumnCardNumber,
+		severity: models.SeverityHigh,
+		match: func(value string, _ bool) bool {
+			return cardValuePattern.MatchString(value) && validCardNumber(value)
+		},
+	},
+	{
+		name:     models.ColumnNationalID,
+		severity: models.SeverityHigh,
+		hint:     regexp.MustCompile(`ssn|social_?security|national_?id|nino|insurance_?n(?:o|um)`),
+		match: func(value string, hinted bool) bool {
+			if ssnValuePattern.MatchString(value) {
+				return validSSN(value)
+			}
+			// Bare nine digit numbers are only social security numbers in
+			// columns named for them
+			if hinted && len(value) == 9 && strings.Trim(value, "0123456789") == "" {
+				return validSSN(value[:3] + "-" + value[3:5] + "-" + value[5:])
+			}
+			return ninoValuePattern.MatchString(strings.ToUpper(value))
+		},
+	},
+	{
+		name:     models.ColumnEmail,
+		severity: models.SeverityMedium,
+		match: func(value string, _ bool) bool {
+			return emailValuePattern.MatchString(value)
+		},
+	},
+	{
+		name:     models.ColumnPhone,
+		severity: models.SeverityMedium,
+		hint:     regexp.MustCompile(`phone|mobile|cell|tel|fax`),
+		match: func(value string, hinted bool) bool {
+			// Unformatted numbers could be IDs, unless the column says
+			// otherwise
+			return phoneValuePattern.MatchString(value) || hinted && digitsValuePattern.MatchString(value)
+		},
+	},
+	{
+		name:     models.ColumnName,
+		severity: models.SeverityMedium,
+		hint:     regexp.MustCompile(`^(?:(?:first|last|full|given|family|middle|maiden|legal|display|customer|contact|employee|patient|person|card_?holder|account_?holder|holder)_?)?name$|^(?:surname|forename|fname|lname)$`),
+		match: func(value string, hinted bool) bool {
+			// Names cannot be told from other capitalized words, such as
+			// cities, without a column named for them
+			return hinted && nameValuePattern.MatchString(value)
+		},
+	},
+	{
+		name:     models.ColumnFreeText,
+		severity: models.SeverityLow,
+		match: func(value string, _ bool) bool {
+			return len(strings.Fields(value)) >= 5
+		},
+	},
+}
+
+// ProfileOptions controls which objects the column profiler reads
+type ProfileOptions struct {
+	Objects LocalScanOptions
+	// MaxRows caps the rows sampled from each object; zero reads every row
+	MaxRows int
+}
+
+// ColumnProfiler samples the rows of CSV, JSON and Parquet objects, infers
+// the type of each column and classifies the personal data it holds, such as
+// email addresses or card numbers, per dataset
+type ColumnProfiler struct {
+	s3Client awsutils.S3ClientAPI
+	opts     ProfileOptions
+}
+
+// NewColumnProfiler returns a profiler that reads objects with s3Client
+func NewColumnProfiler(s3Client awsutils.S3ClientAPI, opts ProfileOptions) *ColumnProfiler {
+	return &ColumnProfiler{s3Client: s3Client, opts: opts}
+}
+
+// datasetKey identifies a dataset; the part files of a table written in two
+// formats make two datasets
+type datasetKey struct {
+	name   string
+	format string
+}
+
+// Profile reads the tabular objects of the bucket and returns the profile of
+// each dataset, in the order of their keys
+func (p *ColumnProfiler) Profile(ctx context.Context, bucketName string) (models.BucketProfile, error) {
+	profile := models.BucketProfile{Bucket: bucketName}
+	keys, err := listObjectsToScan(ctx, p.s3Client, bucketName, p.opts.Objects, isTabularKey)
+	if err != nil {
+		return profile, fmt.Errorf("unable to list objects: %w", err)
+	}
+	color.Yellow("📊 Profiling %d tabular object(s) in %s\n", len(keys), bucketName)
+	log.Printf("Profiling %d tabular object(s) in %s", len(keys), bucketName)
+
+	datasets := map[datasetKey]*tableStats{}
+	var order []datasetKey
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return profile, err
+		}
+
+		stats, skipped, err := p.profileObject(ctx, bucketName, key)
+		if err != nil {
+			log.Printf("Warning: unable to profile object %s in bucket %s: %v", key, bucketName, err)
+			profile.Skipped = append(profile.Skipped, unreadableObject(key, err))
+			continue
+		}
+		profile.Skipped = append(profile.Skipped, skipped...)
+		if stats == nil {
+			continue
+		}
+		profile.ObjectsRead++
+
+		id := datasetKey{name: datasetName(key), format: stats.format}
+		if dataset, ok := datasets[id]; ok {
+			dataset.merge(stats)
+			continue
+		}
+		datasets[id] = stats
+		order = append(order, id)
+	}
+
+	for _, id := range order {
+		profile.Datasets = append(profile.Datasets, datasets[id].profile(id.name))
+	}
+	return profile, nil
+}
+
+// profileObject samples the rows of one object. stats is nil when no rows
+// could be read, and skipped then says why.
+func (p *ColumnProfiler) profileObject(ctx context.Context, bucketName, key string) (stats *tableStats, skipped []models.SkippedObject, err error) {
+	body, truncated, err := getObjectToScan(ctx, p.s3Client, bucketName, key, p.opts.Objects)
+	if err != nil {
+		return nil, nil, err
+	}
+	defer body.Close()
+
+	stats = newTableStats()
+	table, err := extract.Columns(key, body, truncated, p.opts.Objects.Extract, p.opts.MaxRows, stats.add)
+	for _, s := range table.Skipped {
+		skipped = append(skipped, models.SkippedObject{Key: key, File: s.Name, Format: s.Format, Reason: s.Reason})
+	}
+	reason := ""
+	switch {
+	case err != nil:
+		reason = err.Error()
+	case table.Rows == 0:
+		reason = "no rows"
+	}
+	if reason != "" {
+		skipped = append(skipped, models.SkippedObject{Key: key, Format: table.Format, Reason: reason})
+		log.Printf("Skipped %s in bucket %s: %s", key, bucketName, reason)
+		return nil, skipped, nil
+	}
+
+	stats.format = tableFormats[table.Format]
+	stats.objects = 1
+	stats.rows = table.Rows
+	return stats, skipped, nil
+}
+
+// isTabularKey reports whether a key names a CSV, JSON or Parquet file
+func isTabularKey(key string) bool {
+	ext := strings.ToLower(path.Ext(key))
+	if ext == ".gz" {
+		ext = strings.ToLower(path.Ext(strings.TrimSuffix(key, path.Ext(key))))
+	}
+	return tabularExtensions[ext]
+}
+
+// datasetName groups the objects of a partitioned table: objects under
+// Hive-style partition directories, and part files written by engines such
+// as Spark, belong to the dataset named by their table's prefix. Other
+// objects are datasets of their own.
+func datasetName(key string) string {
+	dir, file := path.Split(key)
+	var table []string
+	partitioned := false
+	for _, segment := range strings.Split(strings.TrimSuffix(dir, "/"), "/") {
+		if partitionPattern.MatchString(segment) {
+			partitioned = true
+		}
+		if !partitioned && segment != "" {
+			table = append(table, segment)
+		}
+	}
+	if !partitioned && !partFilePattern.MatchString(file) {
+		return key
+	}
+	return strings.Join(table, "/") + "/"
+}
+
+// columnStats totals the values sampled from a column
+type columnStats struct {
+	name   string
+	values int
+	empty  int
+	// types counts the non-empty values by inferred type
+	types map[string]int
+	// matches counts the non-empty values by the kind of personal data they
+	// are
+	matches map[string]int
+	// hinted lists the kinds the column name suggests
+	hinted map[string]bool
+}
+
+func newColumnStats(name string) *columnStats {
+	c := &columnStats{name: name, types: map[string]int{}, matches: map[string]int{}, hinted: map[string]bool{}}
+	// The last part of a nested name describes the value
+	hintName := strings.ToLower(name[strings.LastIndex(name, ".")+1:])
+	hintName = strings.NewReplacer("-", "_", " ", "_").Replace(hintName)
+	for _, class := range columnClasses {
+		if class.hint != nil && class.hint.MatchString(hintName) {
+			c.hinted[class.name] = true
+		}
+	}
+	return c
+}
+
+func (c *columnStats) add(value string) {
+	c.values++
+	value = strings.TrimSpace(value)
+	if value == "" {
+		c.empty++
+		return
+	}
+	c.types[valueType(value)]++
+	for _, class := range columnClasses {
+		if class.match(value, c.hinted[class.name]) {
+			c.matches[class.name]++
+		}
+	}
+}
+
+func (c *columnStats) merge(other *columnStats) {
+	c.values += other.values
+	c.empty += other.empty
+	for t, n := range other.types {
+		c.types[t] += n
+	}
+	for class, n := range other.matches {
+		c.matches[class] += n
+	}
+}
+
+func (c *columnStats) profile() models.ColumnProfile {
+	column := models.ColumnProfile{Name: c.name, Type: columnType(c.types), Values: c.values, Empty: c.empty}
+	nonEmpty := c.values - c.empty
+	if nonEmpty == 0 {
+		return column
+	}
+	for _, class := range columnClasses {
+		ratio := float64(c.matches[class.name]) / float64(nonEmpty)
+		if ratio >= minColumnMatchRatio && ratio > column.MatchRatio {
+			column.Classification = class.name
+			column.MatchRatio = math.Round(ratio*1000) / 1000
+			column.Severity = class.severity
+		}
+	}
+	return column
+}
+
+// tableStats totals the columns of a dataset, in the order they were first
+// seen
+type tableStats struct {
+	format  string
+	objects int
+	rows    int
+	columns []*columnStats
+	byName  map[string]*columnStats
+}
+
+func newTableStats() *tableStats {
+	return &tableStats{byName: map[string]*columnStats{}}
+}
+
+func (t *tableStats) column(name string) *columnStats {
+	c, ok := t.byName[name]
+	if !ok {
+		c = newColumnStats(name)
+		t.byName[name] = c
+		t.columns = append(t.columns, c)
+	}
+	return c
+}
+
+// add records a value of a column
+func (t *tableStats) add(column, value string) {
+	t.column(column).add(value)
+}
+
+// merge adds the columns of another object of the dataset
+func (t *tableStats) merge(other *tableStats) {
+	t.objects += other.objects
+	t.rows += other.rows
+	for _, c := range other.columns {
+		t.column(c.name).merge(c)
+	}
+}
+
+func (t *tableStats) profile(name string) models.DatasetProfile {
+	dataset := models.DatasetProfile{Name: name, Format: t.format, Objects: t.objects, Rows: t.rows}
+	for _, c := range t.columns {
+		dataset.Columns = append(dataset.Columns, c.profile())
+	}
+	return dataset
+}
+
+// valueType infers the type of a value
+func valueType(value string) string {
+	if _, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return "integer"
+	}
+	// ParseFloat also accepts words such as Inf and NaN
+	if strings.ContainsAny(value, "0123456789") {
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return "number"
+		}
+	}
+	if strings.EqualFold(value, "true") || strings.EqualFold(value, "false") {
+		return "boolean"
+	}
+	if _, err := time.Parse(time.DateOnly, value); err == nil {
+		return "date"
+	}
+	for _, layout := range []string{time.RFC3339Nano, time.DateTime, "2006-01-02T15:04:05", "2006-01-02 15:04:05.999999999"} {
+		if _, err := time.Parse(layout, value); err == nil {
+			return "timestamp"
+		}
+	}
+	return "string"
+}
+
+// columnType combines the types of the values of a column, widening
+// integers to numbers and dates to timestamps
+func columnType(types map[string]int) string {
+	switch {
+	case len(types) == 0:
+		return "empty"
+	case len(types) == 1:
+		for t := range types {
+			return t
+		}
+	case len(types) == 2 && types["integer"] > 0 && types["number"] > 0:
+		return "number"
+	case len(types) == 2 && types["date"] > 0 && types["timestamp"] > 0:
+		return "timestamp"
+	}
+	return "string"
+}
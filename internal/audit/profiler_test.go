@@ -0,0 +1,213 @@
+package audit
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestDatasetName(t *testing.T) {
+	tests := []struct {
+		key      string
+		expected string
+	}{
+		{key: "exports/customers.csv", expected: "exports/customers.csv"},
+		{key: "lake/events/date=2024-01-31/hour=09/events.jsonl", expected: "lake/events/"},
+		{key: "lake/orders/part-00000-3f2a.snappy.parquet", expected: "lake/orders/"},
+		{key: "warehouse/users/000000_0.csv", expected: "warehouse/users/"},
+		{key: "year=2024/data.csv", expected: "/"},
+		{key: "reports/2024-01-31.csv", expected: "reports/2024-01-31.csv"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			assert.Equal(t, tt.expected, datasetName(tt.key))
+		})
+	}
+}
+
+func TestIsTabularKey(t *testing.T) {
+	assert.True(t, isTabularKey("a/b.CSV"))
+	assert.True(t, isTabularKey("events.jsonl.gz"))
+	assert.True(t, isTabularKey("part-0.snappy.parquet"))
+	assert.False(t, isTabularKey("logs/app.log.gz"))
+	assert.False(t, isTabularKey("report.pdf"))
+	assert.False(t, isTabularKey("000000_0"))
+}
+
+func TestColumnStats_Profile(t *testing.T) {
+	tests := []struct {
+		name     string
+		column   string
+		values   []string
+		expected models.ColumnProfile
+	}{
+		{
+			name:     "Email addresses",
+			column:   "contact",
+			values:   []string{"jane@example.com", "john@example.org", "", "n/a"},
+			expected: models.ColumnProfile{Type: "string", Values: 4, Empty: 1, Classification: models.ColumnEmail, MatchRatio: 0.667, Severity: models.SeverityMedium},
+		},
+		{
+			name:     "Card numbers",
+			column:   "pan",
+			values:   []string{"4111111111111111", "5555 5555 5555 4444"},
+			expected: models.ColumnProfile{Type: "string", Values: 2, Classification: models.ColumnCardNumber, MatchRatio: 1, Severity: models.SeverityHigh},
+		},
+		{
+			name:     "Numeric IDs that rarely pass the Luhn check",
+			column:   "order_id",
+			values:   []string{"1000000000000001", "1000000000000002", "1000000000000003", "1000000000000004"},
+			expected: models.ColumnProfile{Type: "integer", Values: 4},
+		},
+		{
+			name:     "Social security numbers in a column named for them",
+			column:   "customer.SSN",
+			values:   []string{"123456789", "123-45-6788"},
+			expected: models.ColumnProfile{Type: "string", Values: 2, Classification: models.ColumnNationalID, MatchRatio: 1, Severity: models.SeverityHigh},
+		},
+		{
+			name:     "Unformatted phone numbers need a hint",
+			column:   "mobile",
+			values:   []string{"+4915112345678", "4155550199"},
+			expected: models.ColumnProfile{Type: "integer", Values: 2, Classification: models.ColumnPhone, MatchRatio: 1, Severity: models.SeverityMedium},
+		},
+		{
+			name:     "Unformatted numbers without a hint",
+			column:   "account",
+			values:   []string{"4155550199", "4155550142"},
+			expected: models.ColumnProfile{Type: "integer", Values: 2},
+		},
+		{
+			name:     "Names",
+			column:   "First Name",
+			values:   []string{"Jane", "Mary-Ann", "José"},
+			expected: models.ColumnProfile{Type: "string", Values: 3, Classification: models.ColumnName, MatchRatio: 1, Severity: models.SeverityMedium},
+		},
+		{
+			name:     "Capitalized words in a column not named for names",
+			column:   "city",
+			values:   []string{"New York", "Berlin"},
+			expected: models.ColumnProfile{Type: "string", Values: 2},
+		},
+		{
+			name:     "Free text",
+			column:   "notes",
+			values:   []string{"Customer asked us to call back tomorrow morning", "Refund issued after the second complaint"},
+			expected: models.ColumnProfile{Type: "string", Values: 2, Classification: models.ColumnFreeText, MatchRatio: 1, Severity: models.SeverityLow},
+		},
+		{
+			name:     "Mixed numbers",
+			column:   "amount",
+			values:   []string{"10", "12.50"},
+			expected: models.ColumnProfile{Type: "number", Values: 2},
+		},
+		{
+			name:     "Timestamps",
+			column:   "created",
+			values:   []string{"2024-01-31", "2024-01-31T09:15:00Z", "2024-02-01 10:00:00"},
+			expected: models.ColumnProfile{Type: "timestamp", Values: 3},
+		},
+		{
+			name:     "Empty",
+			column:   "unused",
+			values:   []string{"", " "},
+			expected: models.ColumnProfile{Type: "empty", Values: 2, Empty: 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stats := newColumnStats(tt.column)
+			for _, value := range tt.values {
+				stats.add(value)
+			}
+			tt.expected.Name = tt.column
+
+			assert.Equal(t, tt.expected, stats.profile())
+		})
+	}
+}
+
+func TestColumnProfiler_Profile(t *testing.T) {
+	mockS3 := new(mockS3Client)
+	mockS3.On("ListObjectsV2", mock.Anything, mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []s3types.Object{
+			{Key: aws.String("exports/customers.csv"), Size: aws.Int64(100)},
+			{Key: aws.String("exports/README.txt"), Size: aws.Int64(20)},
+			{Key: aws.String("events/date=2024-01-30/events.jsonl"), Size: aws.Int64(100)},
+			{Key: aws.String("events/date=2024-01-31/events.jsonl"), Size: aws.Int64(100)},
+			{Key: aws.String("events/date=2024-02-01/events.jsonl"), Size: aws.Int64(100), StorageClass: s3types.ObjectStorageClassGlacier},
+			{Key: aws.String("big/part-00000.parquet"), Size: aws.Int64(5000)},
+			{Key: aws.String("broken.json"), Size: aws.Int64(20)},
+			{Key: aws.String("private.csv"), Size: aws.Int64(20)},
+		},
+	}, nil)
+	body := func(key, content string, size int) {
+		output := &s3.GetObjectOutput{Body: io.NopCloser(strings.NewReader(content))}
+		if size > 0 {
+			output.ContentRange = aws.String("bytes 0-1023/" + strings.Repeat("9", size))
+		}
+		mockS3.On("GetObject", mock.Anything, mock.MatchedBy(func(in *s3.GetObjectInput) bool {
+			return aws.ToString(in.Key) == key
+		})).Return(output, nil)
+	}
+	body("exports/customers.csv", "name,email,card,city\nJane Doe,jane@example.com,4111111111111111,Berlin\nJohn Roe,john@example.com,5555555555554444,Paris\n", 0)
+	body("events/date=2024-01-30/events.jsonl", `{"user":{"email":"a@example.com"},"action":"login"}`+"\n", 0)
+	body("events/date=2024-01-31/events.jsonl", `{"user":{"email":"b@example.com","phone":"+1 415-555-0199"},"action":"logout"}`+"\n", 0)
+	body("big/part-00000.parquet", "PAR1", 4)
+	body("broken.json", "not json at all", 0)
+	mockS3.On("GetObject", mock.Anything, mock.MatchedBy(func(in *s3.GetObjectInput) bool {
+		return aws.ToString(in.Key) == "private.csv"
+	})).Return((*s3.GetObjectOutput)(nil), &smithy.GenericAPIError{Code: "AccessDenied"})
+
+	profiler := NewColumnProfiler(mockS3, ProfileOptions{Objects: LocalScanOptions{MaxBytes: 1024}, MaxRows: 100})
+	profile, err := profiler.Profile(context.Background(), "bucket")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "bucket", profile.Bucket)
+	assert.Equal(t, 3, profile.ObjectsRead)
+	assert.Equal(t, []models.DatasetProfile{
+		{
+			Name: "exports/customers.csv", Format: "csv", Objects: 1, Rows: 2,
+			Columns: []models.ColumnProfile{
+				{Name: "name", Type: "string", Values: 2, Classification: models.ColumnName, MatchRatio: 1, Severity: models.SeverityMedium},
+				{Name: "email", Type: "string", Values: 2, Classification: models.ColumnEmail, MatchRatio: 1, Severity: models.SeverityMedium},
+				{Name: "card", Type: "integer", Values: 2, Classification: models.ColumnCardNumber, MatchRatio: 1, Severity: models.SeverityHigh},
+				{Name: "city", Type: "string", Values: 2},
+			},
+		},
+		{
+			Name: "events/", Format: "json", Objects: 2, Rows: 2,
+			Columns: []models.ColumnProfile{
+				{Name: "user.email", Type: "string", Values: 2, Classification: models.ColumnEmail, MatchRatio: 1, Severity: models.SeverityMedium},
+				{Name: "action", Type: "string", Values: 2},
+				{Name: "user.phone", Type: "string", Values: 1, Classification: models.ColumnPhone, MatchRatio: 1, Severity: models.SeverityMedium},
+			},
+		},
+	}, profile.Datasets)
+	assert.Equal(t, []models.SkippedObject{
+		{Key: "big/part-00000.parquet", Format: "application/vnd.apache.parquet", Reason: "only the start of the object was read, which is not enough for this format"},
+		{Key: "broken.json", Format: "application/json", Reason: "not a CSV, JSON or Parquet file"},
+		{Key: "private.csv", Reason: "access denied"},
+	}, profile.Skipped)
+	// The text file and the archived object are never read
+	mockS3.AssertNumberOfCalls(t, "GetObject", 6)
+
+	var csvOut bytes.Buffer
+	assert.NoError(t, WriteProfilesCSV(&csvOut, []models.BucketProfile{profile}))
+	lines := strings.Split(strings.TrimSpace(csvOut.String()), "\n")
+	assert.Len(t, lines, 8)
+	assert.Equal(t, "bucket,region,dataset,format,objects,rows,column,type,values,empty,classification,match_ratio,severity", lines[0])
+	assert.Equal(t, "bucket,,exports/customers.csv,csv,1,2,card,integer,2,0,card_number,1,high", lines[3])
+}
@@ -1,25 +1,454 @@
 package audit
 
 import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
 	"time"
 
 	"github.com/fatih/color"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
 )
 
 func PrintBucketReport(info models.BucketInfo) {
-	color.Cyan("\nS3 Bucket Security Audit Report:")
-	color.Cyan("=====================================================================")
-	color.Green("Bucket Name      : %s", info.Name)
-	color.Cyan("Region           : %s", info.Region)
-	color.Yellow("Public Access    : %t", info.IsPublic)
-	color.Cyan("Encryption       : %s", info.Encryption)
-	color.Cyan("Versioning       : %s", info.VersioningStatus)
-	if info.SensitiveData {
-		color.Red("Sensitive Data   : %t", info.SensitiveData)
+	WriteBucketReport(color.Output, info)
+}
+
+// WriteBucketReport writes the audit report for a bucket to w
+func WriteBucketReport(w io.Writer, info models.BucketInfo) {
+	cyan := color.New(color.FgCyan)
+	green := color.New(color.FgGreen)
+	yellow := color.New(color.FgYellow)
+	red := color.New(color.FgRed)
+
+	cyan.Fprintln(w, "\nS3 Bucket Security Audit Report:")
+	cyan.Fprintln(w, "=====================================================================")
+	green.Fprintf(w, "Bucket Name      : %s\n", info.Name)
+	cyan.Fprintf(w, "Region           : %s\n", info.Region)
+	if info.Ran(CheckPublicAccess) {
+		yellow.Fprintf(w, "Public Access    : %t\n", info.IsPublic)
+		if pab := info.PublicAccessBlock; pab != nil {
+			bucket := configuredSummary(pab.Bucket, pab.BucketConfigured)
+			if pab.BucketDenied {
+				bucket = "unknown (access denied)"
+			}
+			cyan.Fprintf(w, "Block Public Acc.: %s (account: %s, bucket: %s)\n",
+				pab.Effective.Summary(), configuredSummary(pab.Account, pab.AccountConfigured), bucket)
+		}
+	}
+	if info.Ran(CheckOwnership) && info.Ownership != nil {
+		if info.Ownership.ACLsEnabled() {
+			yellow.Fprintf(w, "Object Ownership : %s (ACLs enabled)\n", info.Ownership.ObjectOwnership)
+			for _, grant := range info.Ownership.NonOwnerGrants {
+				yellow.Fprintf(w, "  - %s to %s\n", grant.Permission, grant.Summary())
+			}
+		} else {
+			cyan.Fprintf(w, "Object Ownership : %s (ACLs disabled)\n", info.Ownership.ObjectOwnership)
+		}
+	}
+	if info.Ran(CheckBucketPolicy) {
+		if info.PolicyAccess == string(awsutils.PolicyAccessPublic) {
+			red.Fprintf(w, "Bucket Policy    : %s\n", info.PolicyAccess)
+		} else if info.PolicyAccess == string(awsutils.PolicyAccessUnknown) {
+			yellow.Fprintln(w, "Bucket Policy    : unknown (access denied)")
+		} else {
+			cyan.Fprintf(w, "Bucket Policy    : %s\n", info.PolicyAccess)
+		}
+	}
+	if info.Ran(CheckTLS) && info.TLS != nil {
+		switch {
+		case info.TLS.Denied:
+			yellow.Fprintln(w, "TLS Enforced     : unknown (access denied)")
+		case info.TLS.Enforced:
+			cyan.Fprintf(w, "TLS Enforced     : true%s\n", minTLSSuffix(info.TLS))
+		case info.TLS.Partial:
+			yellow.Fprintf(w, "TLS Enforced     : partial%s\n", minTLSSuffix(info.TLS))
+		default:
+			yellow.Fprintln(w, "TLS Enforced     : false")
+		}
+	}
+	if info.Ran(CheckWebsite) && info.Website != nil {
+		website := info.Website
+		switch {
+		case !website.Enabled:
+			cyan.Fprintln(w, "Website Hosting  : Not Enabled")
+		case website.PubliclyReadable != nil && *website.PubliclyReadable:
+			red.Fprintf(w, "Website Hosting  : %s\n", website.Summary())
+		default:
+			yellow.Fprintf(w, "Website Hosting  : %s\n", website.Summary())
+		}
+		for _, rule := range website.RoutingRules {
+			cyan.Fprintf(w, "  - %s\n", rule)
+		}
+	}
+	if info.Ran(CheckCORS) && info.CORS != nil {
+		if info.CORS.Configured {
+			cyan.Fprintf(w, "CORS             : %d rule(s)\n", len(info.CORS.Rules))
+			for _, rule := range info.CORS.Rules {
+				cyan.Fprintf(w, "  - %s\n", rule.Summary())
+			}
+		} else {
+			cyan.Fprintln(w, "CORS             : Not Configured")
+		}
+	}
+	if info.Ran(CheckEncryption) {
+		cyan.Fprintf(w, "Encryption       : %s\n", info.Encryption)
+		if details := info.EncryptionDetails; details != nil {
+			for _, rule := range details.Rules {
+				writeEncryptionRule(w, rule)
+			}
+			if details.DeniesUnencryptedUploads != nil {
+				cyan.Fprintf(w, "  Deny Unencrypted: %t\n", *details.DeniesUnencryptedUploads)
+			}
+		}
+	}
+	if info.Ran(CheckVersioning) {
+		cyan.Fprintf(w, "Versioning       : %s\n", info.VersioningStatus)
+		if details := info.VersioningDetails; details != nil {
+			cyan.Fprintf(w, "MFA Delete       : %t\n", details.MFADelete)
+			if lock := details.ObjectLock; lock != nil && lock.Enabled {
+				if lock.Mode != "" {
+					cyan.Fprintf(w, "Object Lock      : %s, default retention %s\n", lock.Mode, lock.Retention())
+				} else {
+					cyan.Fprintln(w, "Object Lock      : Enabled, no default retention")
+				}
+			} else if lock != nil {
+				yellow.Fprintln(w, "Object Lock      : Not Enabled")
+			}
+		}
+	}
+	if info.Ran(CheckLifecycle) && info.Lifecycle != nil {
+		if info.Lifecycle.Configured {
+			cyan.Fprintf(w, "Lifecycle        : %d rule(s)\n", len(info.Lifecycle.Rules))
+			for _, rule := range info.Lifecycle.Rules {
+				cyan.Fprintf(w, "  - %s\n", rule.Summary())
+			}
+		} else {
+			yellow.Fprintln(w, "Lifecycle        : Not Configured")
+		}
+	}
+	if info.Ran(CheckReplication) && info.Replication != nil {
+		if info.Replication.Configured {
+			cyan.Fprintf(w, "Replication      : %d rule(s)\n", len(info.Replication.Rules))
+			for _, rule := range info.Replication.Rules {
+				cyan.Fprintf(w, "  - %s\n", rule.Summary())
+			}
+		} else {
+			yellow.Fprintln(w, "Replication      : Not Configured")
+		}
+		if info.Replication.BackupProtected != nil {
+			cyan.Fprintf(w, "AWS Backup       : %t\n", *info.Replication.BackupProtected)
+		}
+	}
+	if info.Ran(CheckLogging) && info.Logging != nil {
+		if info.Logging.Enabled {
+			cyan.Fprintf(w, "Access Logging   : s3://%s/%s\n", info.Logging.TargetBucket, info.Logging.TargetPrefix)
+		} else if info.Logging.Denied {
+			yellow.Fprintln(w, "Access Logging   : Unknown (access denied)")
+		} else {
+			yellow.Fprintln(w, "Access Logging   : Not Enabled")
+		}
+		if info.Logging.DataEventsChecked {
+			if len(info.Logging.DataEventTrails) > 0 {
+				cyan.Fprintf(w, "CloudTrail Data  : %s\n", strings.Join(info.Logging.DataEventTrails, ", "))
+			} else if len(info.Logging.ReadOnlyDataEventTrails) > 0 {
+				yellow.Fprintf(w, "CloudTrail Data  : Reads only (%s)\n", strings.Join(info.Logging.ReadOnlyDataEventTrails, ", "))
+			} else {
+				yellow.Fprintln(w, "CloudTrail Data  : Not Recorded")
+			}
+		}
+	}
+	if sample := info.ObjectSample; sample != nil {
+		writeObjectSample(w, sample)
+	}
+	if secrets := info.Secrets; secrets != nil {
+		writeSecrets(w, secrets)
+	}
+	if info.Ran(CheckSensitiveData) {
+		details := info.SensitiveDataDetails
+		if details != nil && len(details.Objects) > 0 {
+			writeSensitiveData(w, details)
+		} else if info.SensitiveData {
+			red.Fprintf(w, "Sensitive Data   : %t\n", info.SensitiveData)
+		} else if note := scanNote(details); note != "" {
+			green.Fprintf(w, "Sensitive Data   : %t (%s)\n", info.SensitiveData, note)
+		} else {
+			green.Fprintf(w, "Sensitive Data   : %t\n", info.SensitiveData)
+		}
+		if details != nil {
+			writeSkipped(w, details.Skipped)
+		}
+	}
+	writeFindings(w, info.Findings)
+	cyan.Fprintf(w, "Audit Duration   : %s\n", info.AuditDuration.Round(time.Second))
+	cyan.Fprintln(w, "---------------------------------------------------------------------")
+}
+
+func minTLSSuffix(status *models.TLSStatus) string {
+	if status.MinTLSVersion == "" {
+		return ""
+	}
+	return fmt.Sprintf(" (minimum TLS %s)", status.MinTLSVersion)
+}
+
+func writeEncryptionRule(w io.Writer, rule models.EncryptionRule) {
+	if rule.KeyManager == "" {
+		color.New(color.FgCyan).Fprintf(w, "  Rule           : %s\n", rule.Algorithm)
+		return
+	}
+
+	key := rule.KMSKeyID
+	if key == "" {
+		key = "default aws/s3 key"
+	}
+	if rule.DualLayer {
+		color.New(color.FgCyan).Fprintf(w, "  Rule           : %s with %s (%s managed), dual-layer (DSSE-KMS), bucket keys not supported\n",
+			rule.Algorithm, key, strings.ToLower(rule.KeyManager))
+		return
+	}
+	color.New(color.FgCyan).Fprintf(w, "  Rule           : %s with %s (%s managed), bucket key %t\n",
+		rule.Algorithm, key, strings.ToLower(rule.KeyManager), rule.BucketKeyEnabled)
+}
+
+func writeObjectSample(w io.Writer, sample *models.ObjectSample) {
+	cyan := color.New(color.FgCyan)
+	yellow := color.New(color.FgYellow)
+
+	scope := ""
+	if sample.Prefix != "" {
+		scope = fmt.Sprintf(" under %s", sample.Prefix)
+	}
+	summary := fmt.Sprintf("%d object(s)%s, %d public, %d unencrypted", sample.Sampled, scope, len(sample.PublicObjects), len(sample.UnencryptedObjects))
+	if len(sample.PublicObjects) > 0 || len(sample.UnencryptedObjects) > 0 {
+		yellow.Fprintf(w, "Object Sample    : %s\n", summary)
+	} else {
+		cyan.Fprintf(w, "Object Sample    : %s\n", summary)
+	}
+	for _, algorithm := range sortedKeys(sample.Encryption) {
+		cyan.Fprintf(w, "  Encryption     : %s x%d\n", algorithm, sample.Encryption[algorithm])
+	}
+	for _, key := range sortedKeys(sample.KMSKeys) {
+		cyan.Fprintf(w, "  KMS Key        : %s x%d\n", key, sample.KMSKeys[key])
+	}
+	if len(sample.Errors) > 0 {
+		yellow.Fprintf(w, "  Not Inspected  : %d object(s)\n", len(sample.Errors))
+	}
+}
+
+func writeSecrets(w io.Writer, scan *models.SecretScan) {
+	if len(scan.Secrets) == 0 {
+		color.New(color.FgGreen).Fprintf(w, "Secrets          : none in %d object(s)\n", scan.ObjectsScanned)
 	} else {
-		color.Green("Sensitive Data   : %t", info.SensitiveData)
+		color.New(color.FgRed).Fprintf(w, "Secrets          : %d in %d of %d object(s)\n", len(scan.Secrets), scan.Objects(), scan.ObjectsScanned)
+		for _, secret := range scan.Secrets {
+			fmt.Fprintf(w, "  [%s] %s in %s line %d: %s\n", strings.ToUpper(secret.Confidence), secret.Rule, displayName(secret.Key, secret.File), secret.Line, secret.Snippet)
+		}
+	}
+	writeSkipped(w, scan.Skipped)
+}
+
+// writeSkipped totals what content scanning could not read text from by
+// reason; the JSON report lists each object
+func writeSkipped(w io.Writer, skipped []models.SkippedObject) {
+	reasons := map[string]int64{}
+	for _, s := range skipped {
+		reasons[s.Reason]++
+	}
+	for _, reason := range sortedByCount(reasons) {
+		color.New(color.FgYellow).Fprintf(w, "  Skipped        : %s x%d\n", reason, reasons[reason])
+	}
+}
+
+// WriteBucketProfile writes the column profiles of the datasets in a bucket
+// to w, most sensitive columns first within each dataset
+func WriteBucketProfile(w io.Writer, profile models.BucketProfile) {
+	cyan := color.New(color.FgCyan)
+
+	cyan.Fprintln(w, "\nS3 Bucket Column Profile:")
+	cyan.Fprintln(w, "=====================================================================")
+	color.New(color.FgGreen).Fprintf(w, "Bucket Name      : %s\n", profile.Bucket)
+	cyan.Fprintf(w, "Region           : %s\n", profile.Region)
+	cyan.Fprintf(w, "Datasets         : %d from %d object(s)\n", len(profile.Datasets), profile.ObjectsRead)
+	writeSkipped(w, profile.Skipped)
+
+	for _, dataset := range profile.Datasets {
+		cyan.Fprintf(w, "\nDataset          : %s (%s, %d object(s), %d row(s) sampled)\n", dataset.Name, dataset.Format, dataset.Objects, dataset.Rows)
+		sensitivity := dataset.Sensitivity()
+		severityColor(sensitivity).Fprintf(w, "Sensitivity      : %s, %d of %d column(s) hold personal data\n",
+			strings.ToUpper(sensitivity.String()), dataset.SensitiveColumns(), len(dataset.Columns))
+
+		columns := append([]models.ColumnProfile(nil), dataset.Columns...)
+		sort.SliceStable(columns, func(i, j int) bool { return columns[i].Severity > columns[j].Severity })
+		tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "  COLUMN\tTYPE\tEMPTY\tCLASSIFICATION\tMATCH\tSEVERITY")
+		for _, column := range columns {
+			classification, match := "-", "-"
+			if column.Classification != "" {
+				classification = column.Classification
+				match = fmt.Sprintf("%.0f%%", column.MatchRatio*100)
+			}
+			empty := 0.0
+			if column.Values > 0 {
+				empty = float64(column.Empty) / float64(column.Values) * 100
+			}
+			fmt.Fprintf(tw, "  %s\t%s\t%.0f%%\t%s\t%s\t%s\n", column.Name, column.Type, empty, classification, match, strings.ToUpper(column.Severity.String()))
+		}
+		tw.Flush()
+	}
+}
+
+// WriteProfilesCSV writes one row per profiled column, for spreadsheets and
+// data catalogs
+func WriteProfilesCSV(w io.Writer, profiles []models.BucketProfile) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"bucket", "region", "dataset", "format", "objects", "rows", "column", "type", "values", "empty", "classification", "match_ratio", "severity"})
+	for _, profile := range profiles {
+		for _, dataset := range profile.Datasets {
+			for _, column := range dataset.Columns {
+				cw.Write([]string{
+					profile.Bucket, profile.Region, dataset.Name, dataset.Format,
+					strconv.Itoa(dataset.Objects), strconv.Itoa(dataset.Rows),
+					column.Name, column.Type, strconv.Itoa(column.Values), strconv.Itoa(column.Empty),
+					column.Classification, strconv.FormatFloat(column.MatchRatio, 'f', -1, 64), column.Severity.String(),
+				})
+			}
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeSensitiveData writes the Macie results as totals per category and
+// type followed by a table of the affected objects, most serious first
+func writeSensitiveData(w io.Writer, details *models.SensitiveDataSummary) {
+	cyan := color.New(color.FgCyan)
+
+	summary := fmt.Sprintf("%d finding(s), %d occurrence(s) in %d object(s)", details.FindingCount, details.Occurrences(), len(details.Objects))
+	if note := scanNote(details); note != "" {
+		summary += ", " + note
+	}
+	color.New(color.FgRed).Fprintf(w, "Sensitive Data   : true (%s)\n", summary)
+	categories := details.CategoryCounts()
+	for _, category := range sortedByCount(categories) {
+		cyan.Fprintf(w, "  Category       : %s x%d\n", category, categories[category])
+	}
+	dataTypes := details.TypeCounts()
+	for _, dataType := range sortedByCount(dataTypes) {
+		cyan.Fprintf(w, "  Type           : %s x%d\n", dataType, dataTypes[dataType])
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "  SEVERITY\tOCCURRENCES\tCATEGORIES\tOBJECT")
+	for _, object := range details.Objects {
+		fmt.Fprintf(tw, "  %s\t%d\t%s\t%s\n", strings.ToUpper(object.Severity.String()), object.Occurrences(), strings.Join(object.Categories(), ", "), object.Key)
+	}
+	tw.Flush()
+}
+
+// scanNote says how many objects the local or LLM classifier read, as their
+// results only cover those
+func scanNote(details *models.SensitiveDataSummary) string {
+	if details == nil {
+		return ""
+	}
+	switch details.Classifier {
+	case ClassifierLocal:
+		return fmt.Sprintf("local scan of %d object(s)", details.ObjectsScanned)
+	case ClassifierLLM:
+		return fmt.Sprintf("model review of %d object(s)", details.ObjectsScanned)
+	default:
+		return ""
+	}
+}
+
+// WriteSensitiveObject writes what Macie found in a single object and where
+func WriteSensitiveObject(w io.Writer, object models.SensitiveObject) {
+	cyan := color.New(color.FgCyan)
+
+	cyan.Fprintf(w, "\nObject           : %s\n", object.Key)
+	severityColor(object.Severity).Fprintf(w, "Severity         : %s\n", strings.ToUpper(object.Severity.String()))
+	if object.FindingID != "" {
+		cyan.Fprintf(w, "Macie Finding    : %s (%s)\n", object.FindingID, object.FindingType)
+	} else {
+		cyan.Fprintf(w, "Finding Type     : %s\n", object.FindingType)
+	}
+	cyan.Fprintf(w, "Occurrences      : %d\n", object.Occurrences())
+	for _, detection := range object.Detections {
+		cyan.Fprintf(w, "  - %s (%s) x%d\n", detection.Type, detection.Category, detection.Count)
+		for _, location := range detection.Locations {
+			fmt.Fprintf(w, "      %s\n", location)
+		}
+	}
+}
+
+// sortedByCount returns the keys of counts, largest count first
+func sortedByCount(counts map[string]int64) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Slice(keys, func(i, j int) bool {
+		if counts[keys[i]] != counts[keys[j]] {
+			return counts[keys[i]] > counts[keys[j]]
+		}
+		return keys[i] < keys[j]
+	})
+	return keys
+}
+
+func sortedKeys(counts map[string]int) []string {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func configuredSummary(pab models.PublicAccessBlock, configured bool) string {
+	if !configured {
+		return "not configured"
+	}
+	return pab.Summary()
+}
+
+// HighestSeverity returns the severity of the most serious finding for a bucket
+func HighestSeverity(info models.BucketInfo) models.Severity {
+	return info.MaxSeverity()
+}
+
+// severityColor picks the color a finding is printed in
+func severityColor(severity models.Severity) *color.Color {
+	switch {
+	case severity >= models.SeverityHigh:
+		return color.New(color.FgRed)
+	case severity == models.SeverityMedium:
+		return color.New(color.FgYellow)
+	default:
+		return color.New(color.FgCyan)
+	}
+}
+
+func writeFindings(w io.Writer, findings []models.Finding) {
+	if len(findings) == 0 {
+		color.New(color.FgGreen).Fprintln(w, "Findings         : none")
+		return
+	}
+
+	color.New(color.FgCyan).Fprintf(w, "Findings         : %d\n", len(findings))
+	for _, f := range findings {
+		severityColor(f.Severity).Fprintf(w, "  [%s] %s (%s)\n", strings.ToUpper(f.Severity.String()), f.Title, f.CheckID)
+		fmt.Fprintf(w, "      Resource   : %s\n", f.Resource)
+		for _, e := range f.Evidence {
+			fmt.Fprintf(w, "      Evidence   : %s\n", e)
+		}
+		if f.Remediation != "" {
+			fmt.Fprintf(w, "      Remediation: %s\n", f.Remediation)
+		}
 	}
-	color.Cyan("Audit Duration   : %s", info.AuditDuration.Round(time.Second))
-	color.Cyan("---------------------------------------------------------------------")
 }
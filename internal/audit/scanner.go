@@ -2,8 +2,15 @@ package audit
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
+	"io"
 	"log"
+	"os"
+	"slices"
+	"strings"
 	"sync"
 	"time"
 
@@ -18,11 +25,93 @@ import (
 	"github.com/schollz/progressbar/v3"
 )
 
+// Names of the checks a Scanner can run
+const (
+	CheckPublicAccess  = "public"
+	CheckOwnership     = "ownership"
+	CheckBucketPolicy  = "policy"
+	CheckTLS           = "tls"
+	CheckWebsite       = "website"
+	CheckCORS          = "cors"
+	CheckEncryption    = "encryption"
+	CheckVersioning    = "versioning"
+	CheckLifecycle     = "lifecycle"
+	CheckReplication   = "replication"
+	CheckLogging       = "logging"
+	CheckSensitiveData = "macie"
+)
+
+// CheckIDSensitiveData identifies findings raised from Macie results
+const CheckIDSensitiveData = "macie.sensitive-data"
+
+// macieReattachMaxAge is how old a recorded Macie job may be for a later run
+// to report its results as its own
+const macieReattachMaxAge = 24 * time.Hour
+
+// maciePollInterval is how often the status of a Macie job is checked
+const maciePollInterval = 30 * time.Second
+
+// AllChecks lists every check in the order the scanner runs them
+var AllChecks = []string{CheckPublicAccess, CheckOwnership, CheckBucketPolicy, CheckTLS, CheckWebsite, CheckCORS, CheckEncryption, CheckVersioning, CheckLifecycle, CheckReplication, CheckLogging, CheckSensitiveData}
+
 type Scanner struct {
 	cfg         aws.Config
 	s3Client    awsutils.S3ClientAPI
 	macieClient awsutils.MacieClientAPI
 	stsClient   awsutils.STSClientAPI
+	checks      []string
+	progressOut io.Writer
+	concurrency int
+
+	s3ControlClient  awsutils.S3ControlClientAPI
+	cloudTrailClient awsutils.CloudTrailClientAPI
+	kmsClient        awsutils.KMSClientAPI
+	// newBackupClient returns an AWS Backup client for a region; nil skips
+	// the AWS Backup lookup
+	newBackupClient func(region string) awsutils.BackupClientAPI
+
+	// classifier looks for sensitive data; nil runs Macie jobs
+	classifier Classifier
+	// secretScanner is nil unless object contents should be scanned for
+	// secrets
+	secretScanner *SecretScanner
+	// objectSampling is nil unless individual objects should be inspected
+	objectSampling *awsutils.ObjectSampleOptions
+	// macieScope limits the objects Macie classifies; the zero value covers
+	// the whole bucket
+	macieScope awsutils.MacieJobScope
+	// macieIdentifiers is nil unless the job should not use Macie's default
+	// data identifiers
+	macieIdentifiers *awsutils.MacieIdentifiers
+	// jobStore records Macie jobs across runs; nil disables reattaching
+	jobStore *JobStore
+	// reattach decides whether to reattach to a job an earlier run started;
+	// nil always reattaches
+	reattach func(models.MacieJobRecord) bool
+
+	accountMu            sync.Mutex
+	account              string
+	accountPAB           *models.PublicAccessBlock
+	accountPABConfigured bool
+
+	trailsOnce sync.Once
+	trails     []awsutils.TrailDataEvents
+
+	backupMu sync.Mutex
+	// backupProtected holds the protected bucket ARNs of each region looked
+	// up; a nil entry means AWS Backup could not be consulted there
+	backupProtected map[string]map[string]bool
+
+	macieIDsOnce       sync.Once
+	macieCustomIDs     []string
+	macieAllowListIDs  []string
+	macieIdentifierErr error
+}
+
+// BucketResult is the outcome of auditing a single bucket
+type BucketResult struct {
+	Info models.BucketInfo
+	Err  error
 }
 
 func NewScanner(cfg aws.Config, s3Client awsutils.S3ClientAPI, macieClient awsutils.MacieClientAPI, stsClient awsutils.STSClientAPI) *Scanner {
@@ -31,79 +120,503 @@ func NewScanner(cfg aws.Config, s3Client awsutils.S3ClientAPI, macieClient awsut
 		s3Client:    s3Client,
 		macieClient: macieClient,
 		stsClient:   stsClient,
+		checks:      AllChecks,
+		progressOut: os.Stdout,
+		concurrency: config.GetAuditConcurrency(),
+	}
+}
+
+// SetChecks restricts the scanner to the named checks
+func (s *Scanner) SetChecks(checks []string) error {
+	if len(checks) == 0 {
+		s.checks = AllChecks
+		return nil
+	}
+	for _, check := range checks {
+		if !slices.Contains(AllChecks, check) {
+			return fmt.Errorf("unknown check %q (valid checks: %s)", check, strings.Join(AllChecks, ", "))
+		}
+	}
+	s.checks = checks
+	return nil
+}
+
+// SetProgressOutput sets where the Macie progress bar is drawn
+func (s *Scanner) SetProgressOutput(w io.Writer) {
+	s.progressOut = w
+}
+
+// SetS3ControlClient enables account-level Block Public Access evaluation
+func (s *Scanner) SetS3ControlClient(client awsutils.S3ControlClientAPI) {
+	s.s3ControlClient = client
+}
+
+// SetCloudTrailClient enables cross-referencing CloudTrail data events in
+// the logging check
+func (s *Scanner) SetCloudTrailClient(client awsutils.CloudTrailClientAPI) {
+	s.cloudTrailClient = client
+}
+
+// SetKMSClient enables resolving whether encryption keys are AWS or
+// customer managed
+func (s *Scanner) SetKMSClient(client awsutils.KMSClientAPI) {
+	s.kmsClient = client
+}
+
+// SetBackupClients enables checking whether AWS Backup protects buckets that
+// are not replicated. AWS Backup only lists the resources of its own region,
+// so newClient is asked for a client in each bucket's region.
+func (s *Scanner) SetBackupClients(newClient func(region string) awsutils.BackupClientAPI) {
+	s.newBackupClient = newClient
+}
+
+// SetObjectSampling enables inspecting the ACL and encryption of individual
+// objects in every audited bucket
+func (s *Scanner) SetObjectSampling(opts awsutils.ObjectSampleOptions) {
+	s.objectSampling = &opts
+}
+
+// SetMacieScope limits the objects Macie classification jobs analyze
+func (s *Scanner) SetMacieScope(scope awsutils.MacieJobScope) {
+	s.macieScope = scope
+}
+
+// SetMacieIdentifiers selects the data identifiers and allow lists Macie
+// classification jobs use
+func (s *Scanner) SetMacieIdentifiers(identifiers awsutils.MacieIdentifiers) {
+	s.macieIdentifiers = &identifiers
+}
+
+// SetClassifier replaces Macie with another backend for the sensitive data
+// check
+func (s *Scanner) SetClassifier(classifier Classifier) {
+	s.classifier = classifier
+}
+
+// SetSecretScanner enables scanning object contents for secrets
+func (s *Scanner) SetSecretScanner(scanner *SecretScanner) {
+	s.secretScanner = scanner
+}
+
+// SetJobStore records the Macie jobs the scanner creates so that later runs
+// can reattach to them
+func (s *Scanner) SetJobStore(store *JobStore) {
+	s.jobStore = store
+}
+
+// SetMacieReattach sets the function that decides whether to reattach to a
+// Macie job an earlier run started for the same bucket
+func (s *Scanner) SetMacieReattach(reattach func(models.MacieJobRecord) bool) {
+	s.reattach = reattach
+}
+
+// SetConcurrency sets how many buckets AuditBuckets audits at once
+func (s *Scanner) SetConcurrency(n int) {
+	if n < 1 {
+		n = 1
 	}
+	s.concurrency = n
+}
+
+func (s *Scanner) enabled(check string) bool {
+	return slices.Contains(s.checks, check)
 }
 
 func (s *Scanner) AuditBucket(bucketName string) error {
 	wg := sync.WaitGroup{}
 	wg.Add(1)
-	startTime := time.Now()
 	go func(bucketName string) {
 		defer wg.Done()
-		bucketInfo := models.BucketInfo{Name: bucketName}
-
-		color.Cyan("Auditing bucket: %s", bucketName)
-		log.Printf("Auditing bucket: %s", bucketName)
 
-		// Get bucket region
-		region, err := awsutils.GetBucketRegion(s.s3Client, bucketName)
+		bucketInfo, err := s.ScanBucket(context.Background(), bucketName)
 		if err != nil {
-			color.Red("Error: Unable to get region for bucket %s: %v", bucketName, err)
-			log.Printf("Error: Unable to get region for bucket %s: %v", bucketName, err)
+			color.Red("Error: %v", err)
+			log.Printf("Error: %v", err)
 			return
 		}
-		bucketInfo.Region = region
 
-		// Check if bucket is public
-		public, err := awsutils.IsBucketPublic(s.s3Client, bucketName)
+		// Print the report for this bucket
+		PrintBucketReport(bucketInfo)
+	}(bucketName)
+
+	wg.Wait()
+	return nil
+}
+
+// AuditBuckets audits the named buckets on a bounded pool of workers. A failing
+// bucket does not stop the others; results are returned in the order of names.
+func (s *Scanner) AuditBuckets(ctx context.Context, names []string) []BucketResult {
+	results := make([]BucketResult, len(names))
+	jobs := make(chan int)
+
+	workers := min(s.concurrency, len(names))
+	wg := sync.WaitGroup{}
+	wg.Add(workers)
+	for w := 0; w < workers; w++ {
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				info, err := s.ScanBucket(ctx, names[i])
+				if err != nil {
+					log.Printf("Audit error: %v", err)
+				}
+				results[i] = BucketResult{Info: info, Err: err}
+			}
+		}()
+	}
+
+	for i := range names {
+		if ctx.Err() != nil {
+			results[i] = BucketResult{Info: models.BucketInfo{Name: names[i]}, Err: ctx.Err()}
+			continue
+		}
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// ScanBucket runs the enabled checks against a bucket and returns the results
+func (s *Scanner) ScanBucket(ctx context.Context, bucketName string) (models.BucketInfo, error) {
+	if err := ctx.Err(); err != nil {
+		return models.BucketInfo{Name: bucketName}, err
+	}
+
+	startTime := time.Now()
+	bucketInfo := models.BucketInfo{Name: bucketName}
+	if len(s.checks) != len(AllChecks) {
+		bucketInfo.Checks = s.checks
+	}
+
+	color.Cyan("Auditing bucket: %s", bucketName)
+	log.Printf("Auditing bucket: %s", bucketName)
+
+	// Get bucket region
+	region, err := awsutils.GetBucketRegion(s.s3Client, bucketName)
+	if err != nil {
+		return bucketInfo, fmt.Errorf("unable to get region for bucket %s: %w", bucketName, err)
+	}
+	bucketInfo.Region = region
+
+	// Check if bucket is public
+	if s.enabled(CheckPublicAccess) {
+		accountPAB, accountConfigured := s.accountPublicAccessBlock(ctx)
+		status, err := awsutils.GetPublicAccessBlockStatus(s.s3Client, bucketName, accountPAB, accountConfigured)
+		if awsutils.IsAccessDenied(err) {
+			status.BucketDenied = true
+		} else if err != nil {
+			return bucketInfo, fmt.Errorf("unable to get Block Public Access settings for bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.PublicAccessBlock = &status
+
+		public, findings, err := awsutils.CheckBucketPublicAccess(s.s3Client, bucketName, status)
 		if err != nil {
-			color.Red("Error: Unable to check public access for bucket %s: %v", bucketName, err)
-			log.Printf("Error: Unable to check public access for bucket %s: %v", bucketName, err)
-			return
+			return bucketInfo, fmt.Errorf("unable to check public access for bucket %s: %w", bucketName, err)
 		}
 		bucketInfo.IsPublic = public
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
 
-		// Check encryption status
-		encryption, err := awsutils.GetBucketEncryption(s.s3Client, bucketName)
+	// Check whether ACLs are disabled through Object Ownership
+	if s.enabled(CheckOwnership) {
+		ownership, findings, err := awsutils.CheckBucketOwnership(s.s3Client, bucketName)
 		if err != nil {
-			color.Red("Error: Unable to get encryption for bucket %s: %v", bucketName, err)
-			log.Printf("Error: Unable to get encryption for bucket %s: %v", bucketName, err)
-			return
+			return bucketInfo, fmt.Errorf("unable to get ownership controls for bucket %s: %w", bucketName, err)
 		}
-		bucketInfo.Encryption = encryption
+		bucketInfo.Ownership = &ownership
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
 
-		// Check versioning status
-		versioningStatus, err := awsutils.GetBucketVersioning(s.s3Client, bucketName)
+	// The bucket policy is read once for the policy, TLS and encryption checks
+	var policy awsutils.PolicyDocument
+	var policyErr error
+	if s.enabled(CheckBucketPolicy) || s.enabled(CheckTLS) || s.enabled(CheckEncryption) {
+		policy, policyErr = awsutils.ReadBucketPolicy(s.s3Client, bucketName)
+	}
+
+	// Check what the bucket policy grants
+	if s.enabled(CheckBucketPolicy) {
+		if policyErr != nil {
+			return bucketInfo, fmt.Errorf("unable to check bucket policy for bucket %s: %w", bucketName, policyErr)
+		}
+		accountID, err := s.accountID(ctx)
 		if err != nil {
-			color.Red("Error: Unable to get versioning status for bucket %s: %v", bucketName, err)
-			log.Printf("Error: Unable to get versioning status for bucket %s: %v", bucketName, err)
-			return
+			return bucketInfo, err
+		}
+		access, findings := awsutils.CheckBucketPolicy(s.s3Client, bucketName, accountID, policy)
+		// RestrictPublicBuckets limits a public policy to AWS services and
+		// principals of the bucket owner account
+		if access == awsutils.PolicyAccessPublic && bucketInfo.PublicAccessBlock != nil && bucketInfo.PublicAccessBlock.Effective.RestrictPublicBuckets {
+			for i := range findings {
+				if findings[i].CheckID == awsutils.CheckIDPolicyPublic {
+					findings[i].Severity = models.SeverityLow
+					findings[i].Evidence = append(findings[i].Evidence, "Access is restricted by the RestrictPublicBuckets setting")
+				}
+			}
+		} else if access == awsutils.PolicyAccessPublic {
+			bucketInfo.IsPublic = true
+		}
+		bucketInfo.PolicyAccess = string(access)
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Check that the bucket policy refuses plain-HTTP requests
+	if s.enabled(CheckTLS) {
+		if policyErr != nil {
+			return bucketInfo, fmt.Errorf("unable to check TLS enforcement for bucket %s: %w", bucketName, policyErr)
+		}
+		tls, findings := awsutils.CheckBucketTLS(bucketName, policy)
+		bucketInfo.TLS = &tls
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Check static website hosting against the public access result
+	if s.enabled(CheckWebsite) {
+		var public *bool
+		if s.enabled(CheckPublicAccess) || s.enabled(CheckBucketPolicy) {
+			isPublic := bucketInfo.IsPublic
+			public = &isPublic
 		}
-		bucketInfo.VersioningStatus = versioningStatus
-		// Check for sensitive data using Macie
-		sensitiveData, err := s.checkSensitiveData(bucketName)
+		website, findings, err := awsutils.CheckBucketWebsite(s.s3Client, bucketName, public)
 		if err != nil {
-			color.Red("Error: Unable to check sensitive data for bucket %s: %v", bucketName, err)
-			log.Printf("Error: Unable to check sensitive data for bucket %s: %v", bucketName, err)
-			return
+			return bucketInfo, fmt.Errorf("unable to get website configuration for bucket %s: %w", bucketName, err)
 		}
-		bucketInfo.SensitiveData = sensitiveData
+		bucketInfo.Website = &website
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
 
-		bucketInfo.AuditDuration = time.Since(startTime)
-		// Print the report for this bucket
-		PrintBucketReport(bucketInfo)
-	}(bucketName)
+	// Check which origins CORS lets browsers call the bucket from
+	if s.enabled(CheckCORS) {
+		cors, findings, err := awsutils.CheckBucketCORS(s.s3Client, bucketName)
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to get CORS configuration for bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.CORS = &cors
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
 
-	wg.Wait()
-	return nil
+	// Check encryption status
+	if s.enabled(CheckEncryption) {
+		// A policy that failed to load only leaves the upload deny unknown
+		var document *awsutils.PolicyDocument
+		if policyErr == nil {
+			document = &policy
+		}
+		encryption, findings, err := awsutils.CheckBucketEncryption(s.s3Client, s.kmsClient, bucketName, document)
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to get encryption for bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.Encryption = encryption.Summary()
+		bucketInfo.EncryptionDetails = &encryption
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Check versioning status
+	if s.enabled(CheckVersioning) {
+		versioningStatus, findings, err := awsutils.CheckBucketVersioning(s.s3Client, bucketName)
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to get versioning status for bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.VersioningStatus = versioningStatus.Status
+		bucketInfo.VersioningDetails = &versioningStatus
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Check lifecycle rules, which matter most for versioned buckets
+	if s.enabled(CheckLifecycle) {
+		versioning := bucketInfo.VersioningStatus
+		if versioning == "" {
+			var err error
+			if versioning, err = awsutils.GetBucketVersioning(s.s3Client, bucketName); err != nil {
+				return bucketInfo, fmt.Errorf("unable to get versioning status for bucket %s: %w", bucketName, err)
+			}
+		}
+		versioned := versioning == models.VersioningEnabled || versioning == models.VersioningSuspended
+		lifecycle, findings, err := awsutils.CheckBucketLifecycle(s.s3Client, bucketName, versioned)
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to get lifecycle configuration for bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.Lifecycle = &lifecycle
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Check replication and, for critical buckets, AWS Backup coverage
+	if s.enabled(CheckReplication) {
+		tagKey, tagValue := config.GetCriticalBucketTag()
+		replication, findings, err := awsutils.CheckBucketReplication(s.s3Client, bucketName, tagKey, tagValue, s.backupProtectedBuckets(bucketInfo.Region))
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to get replication configuration for bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.Replication = &replication
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Check server access logging and CloudTrail data events
+	if s.enabled(CheckLogging) {
+		logging, findings, err := awsutils.CheckBucketLogging(s.s3Client, bucketName, s.dataEventTrails())
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to get logging status for bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.Logging = &logging
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Sample individual objects for public ACLs and missing encryption
+	if s.objectSampling != nil {
+		// ACLs have no effect when S3 ignores public ones or they are disabled
+		ignoreACLs := bucketInfo.PublicAccessBlock != nil && bucketInfo.PublicAccessBlock.Effective.IgnorePublicAcls
+		if bucketInfo.Ownership != nil && !bucketInfo.Ownership.ACLsEnabled() {
+			ignoreACLs = true
+		}
+		sample, findings, err := awsutils.CheckObjectSample(ctx, s.s3Client, bucketName, *s.objectSampling, ignoreACLs)
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to sample objects in bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.ObjectSample = &sample
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Scan object contents for secrets
+	if s.secretScanner != nil {
+		scan, findings, err := s.secretScanner.Scan(ctx, bucketName)
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to scan objects for secrets in bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.Secrets = &scan
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	// Check for sensitive data using Macie or the selected classifier
+	if s.enabled(CheckSensitiveData) {
+		classifier := s.classifier
+		if classifier == nil {
+			classifier = macieClassifier{scanner: s}
+		}
+		summary, findings, err := classifier.Classify(ctx, bucketName)
+		if err != nil {
+			return bucketInfo, fmt.Errorf("unable to check sensitive data for bucket %s: %w", bucketName, err)
+		}
+		bucketInfo.SensitiveData = len(findings) > 0
+		bucketInfo.SensitiveDataDetails = summary
+		bucketInfo.Findings = append(bucketInfo.Findings, findings...)
+	}
+
+	bucketInfo.AuditDuration = time.Since(startTime)
+	return bucketInfo, nil
 }
 
-func (s *Scanner) checkSensitiveData(bucketName string) (bool, error) {
-	// Retrieve AWS Account ID
-	identity, err := s.stsClient.GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+// accountID returns the caller's AWS account ID, looking it up once per scanner
+func (s *Scanner) accountID(ctx context.Context) (string, error) {
+	s.accountMu.Lock()
+	defer s.accountMu.Unlock()
+
+	if s.account != "" {
+		return s.account, nil
+	}
+
+	identity, err := s.stsClient.GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
 	if err != nil {
 		log.Printf("Error: failed to retrieve account ID: %v", err)
-		return false, fmt.Errorf("Error: failed to retrieve account ID: %w", err)
+		return "", fmt.Errorf("Error: failed to retrieve account ID: %w", err)
+	}
+	s.account = aws.ToString(identity.Account)
+	return s.account, nil
+}
+
+// accountPublicAccessBlock returns the account-level Block Public Access
+// settings, fetching them once per scanner. Without an S3 Control client, or
+// when the settings cannot be read, the account is treated as unconfigured.
+func (s *Scanner) accountPublicAccessBlock(ctx context.Context) (models.PublicAccessBlock, bool) {
+	if s.s3ControlClient == nil {
+		return models.PublicAccessBlock{}, false
+	}
+
+	accountID, err := s.accountID(ctx)
+	if err != nil {
+		return models.PublicAccessBlock{}, false
+	}
+
+	s.accountMu.Lock()
+	defer s.accountMu.Unlock()
+
+	if s.accountPAB == nil {
+		pab, configured, err := awsutils.GetAccountPublicAccessBlock(s.s3ControlClient, accountID)
+		if err != nil {
+			color.Yellow("Warning: unable to read account-level Block Public Access settings: %v", err)
+			log.Printf("Warning: unable to read account-level Block Public Access settings: %v", err)
+		}
+		s.accountPAB = &pab
+		s.accountPABConfigured = configured
+	}
+	return *s.accountPAB, s.accountPABConfigured
+}
+
+// dataEventTrails returns the trails recording S3 data events, fetching them
+// once per scanner. It returns nil when CloudTrail cannot be consulted.
+func (s *Scanner) dataEventTrails() []awsutils.TrailDataEvents {
+	if s.cloudTrailClient == nil {
+		return nil
+	}
+
+	s.trailsOnce.Do(func() {
+		trails, err := awsutils.GetS3DataEventTrails(s.cloudTrailClient)
+		if err != nil {
+			color.Yellow("Warning: unable to read CloudTrail event selectors: %v", err)
+			log.Printf("Warning: unable to read CloudTrail event selectors: %v", err)
+			if len(trails) == 0 {
+				return
+			}
+		}
+		s.trails = trails
+	})
+	return s.trails
+}
+
+// backupProtectedBuckets returns the ARNs of the buckets in region with AWS
+// Backup recovery points, fetching them once per region. It returns nil when
+// AWS Backup cannot be consulted in that region.
+func (s *Scanner) backupProtectedBuckets(region string) map[string]bool {
+	if s.newBackupClient == nil {
+		return nil
+	}
+
+	s.backupMu.Lock()
+	defer s.backupMu.Unlock()
+	if protected, ok := s.backupProtected[region]; ok {
+		return protected
+	}
+	protected, err := awsutils.GetBackupProtectedBuckets(s.newBackupClient(region))
+	if err != nil {
+		color.Yellow("Warning: unable to list AWS Backup protected resources in %s: %v", region, err)
+		log.Printf("Warning: unable to list AWS Backup protected resources in %s: %v", region, err)
+	}
+	if s.backupProtected == nil {
+		s.backupProtected = map[string]map[string]bool{}
+	}
+	s.backupProtected[region] = protected
+	return protected
+}
+
+// macieDataIdentifiers returns the IDs of the custom data identifiers and
+// allow lists to attach to Macie jobs, creating missing ones once per scanner
+func (s *Scanner) macieDataIdentifiers() ([]string, []string, error) {
+	s.macieIDsOnce.Do(func() {
+		s.macieCustomIDs, s.macieAllowListIDs, s.macieIdentifierErr = awsutils.ResolveMacieIdentifiers(s.macieClient, *s.macieIdentifiers)
+		if s.macieIdentifierErr != nil {
+			log.Printf("Error: %v", s.macieIdentifierErr)
+		}
+	})
+	return s.macieCustomIDs, s.macieAllowListIDs, s.macieIdentifierErr
+}
+
+// startMacieJob returns the ID of the classification job to wait for: a job
+// an earlier run started for the bucket, if the scanner reattaches to it, or
+// a newly created one
+func (s *Scanner) startMacieJob(ctx context.Context, accountID, bucketName string) (string, error) {
+	if jobID := s.reattachableJob(ctx, accountID, bucketName); jobID != "" {
+		return jobID, nil
 	}
 
 	// Define a unique job ID for the Macie classification job
@@ -116,115 +629,274 @@ func (s *Scanner) checkSensitiveData(bucketName string) (bool, error) {
 		S3JobDefinition: &types.S3JobDefinition{
 			BucketDefinitions: []types.S3BucketDefinitionForJob{
 				{
-					AccountId: aws.String(*identity.Account),
+					AccountId: aws.String(accountID),
 					Buckets:   []string{bucketName},
 				},
 			},
+			Scoping: s.macieScope.Scoping(),
 		},
 	}
+	if s.macieScope.SamplingPercentage > 0 {
+		input.SamplingPercentage = aws.Int32(s.macieScope.SamplingPercentage)
+	}
+	if s.macieIdentifiers != nil {
+		customIDs, allowListIDs, err := s.macieDataIdentifiers()
+		if err != nil {
+			return "", err
+		}
+		input.ManagedDataIdentifierSelector = types.ManagedDataIdentifierSelector(s.macieIdentifiers.ManagedDataIdentifierSelector)
+		input.ManagedDataIdentifierIds = s.macieIdentifiers.ManagedDataIdentifierIds
+		input.CustomDataIdentifierIds = customIDs
+		input.AllowListIds = allowListIDs
+	}
 
 	// Create the Macie classification job
-	createJobOutput, err := s.macieClient.CreateClassificationJob(context.Background(), input)
+	createJobOutput, err := s.macieClient.CreateClassificationJob(ctx, input)
 	if err != nil {
 		log.Printf("Error: failed to create Macie classification job: %v", err)
-		return false, fmt.Errorf("Error: failed to create Macie classification job: %w", err)
+		return "", fmt.Errorf("Error: failed to create Macie classification job: %w", err)
 	}
 
 	jobID = *createJobOutput.JobId
 	color.Yellow("🔍 Macie classification job created with Job ID: %s\n", jobID)
 	log.Printf("Macie classification job created with Job ID: %s", jobID)
 
+	// Record the job so a later run can reattach to it or clean it up
+	if s.jobStore != nil {
+		job := models.MacieJobRecord{
+			JobID:      jobID,
+			Bucket:     bucketName,
+			AccountID:  accountID,
+			Region:     s.cfg.Region,
+			Definition: s.macieJobDefinition(),
+			CreatedAt:  time.Now().UTC(),
+		}
+		if err := s.jobStore.Add(job); err != nil {
+			color.Yellow("Warning: unable to record Macie job %s: %v", jobID, err)
+			log.Printf("Warning: unable to record Macie job %s: %v", jobID, err)
+		}
+	}
+	return jobID, nil
+}
+
+// reattachableJob returns the recorded job for the bucket when it is still
+// running or its results were never collected, and the scanner should
+// reattach to it rather than start a new one. Only jobs in the same account
+// and region, created within macieReattachMaxAge with the scope, sampling and
+// data identifiers of this run, are reattached to.
+func (s *Scanner) reattachableJob(ctx context.Context, accountID, bucketName string) string {
+	if s.jobStore == nil {
+		return ""
+	}
+
+	job, err := s.jobStore.LatestForBucket(accountID, s.cfg.Region, bucketName)
+	if err != nil {
+		color.Yellow("Warning: %v", err)
+		log.Printf("Warning: %v", err)
+		return ""
+	}
+	if job == nil {
+		return ""
+	}
+	if job.Definition != s.macieJobDefinition() {
+		log.Printf("Not reattaching to Macie classification job %s: it was created with a different scope or data identifiers", job.JobID)
+		return ""
+	}
+	if age := time.Since(job.CreatedAt); age > macieReattachMaxAge {
+		log.Printf("Not reattaching to Macie classification job %s: it was started %s ago", job.JobID, age.Round(time.Minute))
+		return ""
+	}
+
+	status, err := awsutils.GetClassificationJobStatus(ctx, s.macieClient, job.JobID)
+	if awsutils.IsNotFound(err) {
+		s.forgetMacieJob(job.JobID)
+		return ""
+	}
+	if err != nil {
+		log.Printf("Warning: unable to get status of Macie job %s: %v", job.JobID, err)
+		return ""
+	}
+	if status == types.JobStatusPaused || status == types.JobStatusUserPaused {
+		// Paused jobs cannot be reattached to but would resume if left alone;
+		// the record is kept if cancelling fails so jobs can clean it up
+		s.cancelMacieJob(job.JobID)
+		return ""
+	}
+	if status != types.JobStatusRunning && status != types.JobStatusComplete {
+		// Cancelled jobs cannot be reattached to
+		s.forgetMacieJob(job.JobID)
+		return ""
+	}
+	if s.reattach != nil && !s.reattach(*job) {
+		return ""
+	}
+
+	color.Yellow("🔁 Reattaching to Macie classification job %s started %s\n", job.JobID, job.CreatedAt.Local().Format(time.DateTime))
+	log.Printf("Reattaching to Macie classification job %s for bucket %s", job.JobID, bucketName)
+	return job.JobID
+}
+
+// macieJobDefinition fingerprints the scope, sampling and data identifiers
+// the scanner creates Macie jobs with
+func (s *Scanner) macieJobDefinition() string {
+	definition, err := json.Marshal(struct {
+		Scope       awsutils.MacieJobScope     `json:"scope"`
+		Identifiers *awsutils.MacieIdentifiers `json:"identifiers,omitempty"`
+	}{s.macieScope, s.macieIdentifiers})
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(definition)
+	return hex.EncodeToString(sum[:8])
+}
+
+// cancelMacieJob cancels a job the scanner stopped waiting for
+func (s *Scanner) cancelMacieJob(jobID string) {
+	// The audit context may already be cancelled
+	if err := awsutils.CancelClassificationJob(context.Background(), s.macieClient, jobID); err != nil {
+		color.Yellow("Warning: unable to cancel Macie classification job %s: %v", jobID, err)
+		log.Printf("Warning: unable to cancel Macie classification job %s: %v", jobID, err)
+		return
+	}
+	color.Yellow("Cancelled Macie classification job %s", jobID)
+	log.Printf("Cancelled Macie classification job %s", jobID)
+	s.forgetMacieJob(jobID)
+}
+
+// forgetMacieJob removes a job that needs no cleanup from the job store
+func (s *Scanner) forgetMacieJob(jobID string) {
+	if s.jobStore == nil {
+		return
+	}
+	if err := s.jobStore.Remove(jobID); err != nil {
+		log.Printf("Warning: unable to update Macie job records: %v", err)
+	}
+}
+
+// checkSensitiveData runs a Macie classification job on the bucket and
+// summarizes the sensitive data it finds per object
+func (s *Scanner) checkSensitiveData(ctx context.Context, bucketName string) (*models.SensitiveDataSummary, []models.Finding, error) {
+	// Retrieve AWS Account ID
+	accountID, err := s.accountID(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	jobID, err := s.startMacieJob(ctx, accountID, bucketName)
+	if err != nil {
+		return nil, nil, err
+	}
+
 	// Set a timeout for the polling loop
 	timeout := time.After(config.GetMacieTimeout())
-	ticker := time.NewTicker(30 * time.Second)
+	ticker := time.NewTicker(maciePollInterval)
 	defer ticker.Stop()
 
 	// Start the progress bar
 	bar := progressbar.NewOptions(100,
-		progressbar.OptionSetDescription("Performing Macie Classification..."),
+		progressbar.OptionSetDescription(fmt.Sprintf("Performing Macie Classification of %s...", bucketName)),
 		progressbar.OptionSetWidth(30),
 		progressbar.OptionThrottle(65*time.Millisecond),
 		progressbar.OptionShowCount(),
 		progressbar.OptionClearOnFinish(),
 		progressbar.OptionShowDescriptionAtLineEnd(),
+		progressbar.OptionSetWriter(s.progressOut),
 	)
 
-	// Poll for job completion
-	jobDone := false
-	for !jobDone {
-		select {
-		case <-timeout:
-			return false, fmt.Errorf("timeout waiting for Macie classification job completion")
-		case <-ticker.C:
-			// Get job status
-			describeJobInput := &macie2.DescribeClassificationJobInput{
-				JobId: aws.String(jobID),
-			}
+	// Poll for job completion, checking right away in case a reattached job
+	// is already complete
+	for {
+		describeJobInput := &macie2.DescribeClassificationJobInput{
+			JobId: aws.String(jobID),
+		}
 
-			describeJobOutput, err := s.macieClient.DescribeClassificationJob(context.Background(), describeJobInput)
-			if err != nil {
-				log.Printf("Error: failed to get job status: %v", err)
-				return false, fmt.Errorf("Error: failed to get job status: %w", err)
-			}
+		describeJobOutput, err := s.macieClient.DescribeClassificationJob(ctx, describeJobInput)
+		if err != nil && ctx.Err() != nil {
+			s.cancelMacieJob(jobID)
+			return nil, nil, ctx.Err()
+		}
+		if err != nil {
+			log.Printf("Error: failed to get job status: %v", err)
+			return nil, nil, fmt.Errorf("Error: failed to get job status: %w", err)
+		}
+
+		// Update progress bar
+		_ = bar.Add(1)
 
-			// Update progress bar
-			_ = bar.Add(1)
-
-			// Check if job is complete
-			if describeJobOutput.JobStatus == types.JobStatusComplete {
-				_ = bar.Finish()
-				jobDone = true
-			} else if describeJobOutput.JobStatus == types.JobStatusUserPaused ||
-				describeJobOutput.JobStatus == types.JobStatusCancelled ||
-				describeJobOutput.JobStatus == types.JobStatusPaused {
-				return false, fmt.Errorf("Macie classification job failed")
+		// Check if job is complete
+		if describeJobOutput.JobStatus == types.JobStatusComplete {
+			_ = bar.Finish()
+			break
+		} else if describeJobOutput.JobStatus == types.JobStatusUserPaused ||
+			describeJobOutput.JobStatus == types.JobStatusCancelled ||
+			describeJobOutput.JobStatus == types.JobStatusPaused {
+			if describeJobOutput.JobStatus == types.JobStatusCancelled {
+				s.forgetMacieJob(jobID)
 			}
+			return nil, nil, fmt.Errorf("Macie classification job failed")
 		}
-	}
 
-	// List findings after the job completes using FindingCriteria
-	findingsInput := &macie2.ListFindingsInput{
-		FindingCriteria: &types.FindingCriteria{
-			Criterion: map[string]types.CriterionAdditionalProperties{
-				"classificationDetails.jobId": {
-					Eq: []string{jobID},
-				},
-			},
-		},
+		select {
+		case <-ctx.Done():
+			s.cancelMacieJob(jobID)
+			return nil, nil, ctx.Err()
+		case <-timeout:
+			s.cancelMacieJob(jobID)
+			return nil, nil, fmt.Errorf("timeout waiting for Macie classification job completion")
+		case <-ticker.C:
+		}
 	}
 
-	findingsOutput, err := s.macieClient.ListFindings(context.Background(), findingsInput)
+	// List every finding of the job, then fetch their details in batches
+	findingIDs, err := awsutils.ListJobFindingIDs(ctx, s.macieClient, jobID)
 	if err != nil {
 		log.Printf("Error: failed to list Macie findings: %v", err)
-		return false, fmt.Errorf("Error: failed to list Macie findings: %w", err)
+		return nil, nil, fmt.Errorf("Error: failed to list Macie findings: %w", err)
 	}
 
-	if len(findingsOutput.FindingIds) == 0 {
+	summary := &models.SensitiveDataSummary{Classifier: ClassifierMacie, JobID: jobID, FindingCount: len(findingIDs)}
+	if len(findingIDs) == 0 {
+		s.forgetMacieJob(jobID)
 		color.Green("✅ No sensitive data found.")
 		log.Println("No sensitive data found.")
-		return false, nil
-	}
-
-	// Get detailed information about the findings using GetFindings
-	getFindingsInput := &macie2.GetFindingsInput{
-		FindingIds: findingsOutput.FindingIds,
+		return summary, nil, nil
 	}
 
-	getFindingsOutput, err := s.macieClient.GetFindings(context.Background(), getFindingsInput)
+	macieFindings, err := awsutils.GetFindingsInBatches(ctx, s.macieClient, findingIDs)
 	if err != nil {
 		log.Printf("Error: failed to get findings details: %v", err)
-		return false, fmt.Errorf("Error: failed to get findings details: %w", err)
+		return nil, nil, fmt.Errorf("Error: failed to get findings details: %w", err)
 	}
+	s.forgetMacieJob(jobID)
 
-	// Output details of each finding
-	for _, finding := range getFindingsOutput.Findings {
-		color.Magenta("🛑 Finding ID: %s\nDetails: %v\n", *finding.Id, finding)
-		log.Printf("Finding ID: %s, Details: %v", *finding.Id, finding)
+	// Summarize each finding per object
+	findings := make([]models.Finding, 0, len(macieFindings))
+	for _, finding := range macieFindings {
+		object := awsutils.SensitiveObjectFromFinding(finding)
+		log.Printf("Macie finding %s: %d occurrence(s) of sensitive data in %s", object.FindingID, object.Occurrences(), object.Key)
+		summary.Objects = append(summary.Objects, object)
+		findings = append(findings, macieFinding(bucketName, finding, object))
+	}
+	summary.Sort()
+	if len(findings) == 0 {
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDSensitiveData,
+			Severity:    models.SeverityHigh,
+			Title:       "Macie detected sensitive data",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{fmt.Sprintf("Macie finding IDs: %s", strings.Join(findingIDs, ", "))},
+			Remediation: "Review the affected objects and remove or protect the sensitive data",
+		})
 	}
 
-	// Return to the main menu after the report is complete
-	color.Cyan("\nReturning to the main menu...\n")
-	log.Println("Returning to the main menu...")
+	return summary, findings, nil
+}
 
-	return true, nil
+// macieFinding converts a Macie finding into an audit finding
+func macieFinding(bucketName string, finding types.Finding, object models.SensitiveObject) models.Finding {
+	title := "Macie detected sensitive data"
+	if finding.Title != nil {
+		title = *finding.Title
+	}
+	return sensitiveObjectFinding(bucketName, title, "Macie finding "+object.FindingID, object)
 }
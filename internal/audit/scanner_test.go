@@ -5,11 +5,15 @@ import (
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/backup"
+	backuptypes "github.com/aws/aws-sdk-go-v2/service/backup/types"
 	"github.com/aws/aws-sdk-go-v2/service/macie2"
 	macie2types "github.com/aws/aws-sdk-go-v2/service/macie2/types"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	s3types "github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/aws/smithy-go"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -29,6 +33,11 @@ func (m *MockMacieClient) DescribeClassificationJob(ctx context.Context, params
 	return args.Get(0).(*macie2.DescribeClassificationJobOutput), args.Error(1)
 }
 
+func (m *MockMacieClient) UpdateClassificationJob(ctx context.Context, params *macie2.UpdateClassificationJobInput, optFns ...func(*macie2.Options)) (*macie2.UpdateClassificationJobOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.UpdateClassificationJobOutput), args.Error(1)
+}
+
 func (m *MockMacieClient) ListFindings(ctx context.Context, params *macie2.ListFindingsInput, optFns ...func(*macie2.Options)) (*macie2.ListFindingsOutput, error) {
 	args := m.Called(ctx, params)
 	return args.Get(0).(*macie2.ListFindingsOutput), args.Error(1)
@@ -39,6 +48,26 @@ func (m *MockMacieClient) GetFindings(ctx context.Context, params *macie2.GetFin
 	return args.Get(0).(*macie2.GetFindingsOutput), args.Error(1)
 }
 
+func (m *MockMacieClient) ListCustomDataIdentifiers(ctx context.Context, params *macie2.ListCustomDataIdentifiersInput, optFns ...func(*macie2.Options)) (*macie2.ListCustomDataIdentifiersOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.ListCustomDataIdentifiersOutput), args.Error(1)
+}
+
+func (m *MockMacieClient) CreateCustomDataIdentifier(ctx context.Context, params *macie2.CreateCustomDataIdentifierInput, optFns ...func(*macie2.Options)) (*macie2.CreateCustomDataIdentifierOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.CreateCustomDataIdentifierOutput), args.Error(1)
+}
+
+func (m *MockMacieClient) ListAllowLists(ctx context.Context, params *macie2.ListAllowListsInput, optFns ...func(*macie2.Options)) (*macie2.ListAllowListsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.ListAllowListsOutput), args.Error(1)
+}
+
+func (m *MockMacieClient) CreateAllowList(ctx context.Context, params *macie2.CreateAllowListInput, optFns ...func(*macie2.Options)) (*macie2.CreateAllowListOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.CreateAllowListOutput), args.Error(1)
+}
+
 type mockS3Client struct {
 	mock.Mock
 }
@@ -64,6 +93,16 @@ func (m *mockS3Client) GetBucketVersioning(ctx context.Context, params *s3.GetBu
 	return args.Get(0).(*s3.GetBucketVersioningOutput), args.Error(1)
 }
 
+func (m *mockS3Client) GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetObjectLockConfigurationOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketLifecycleConfigurationOutput), args.Error(1)
+}
+
 func (m *mockS3Client) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
 	args := m.Called(ctx, params)
 	return args.Get(0).(*s3.GetPublicAccessBlockOutput), args.Error(1)
@@ -74,6 +113,66 @@ func (m *mockS3Client) GetBucketAcl(ctx context.Context, params *s3.GetBucketAcl
 	return args.Get(0).(*s3.GetBucketAclOutput), args.Error(1)
 }
 
+func (m *mockS3Client) GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketOwnershipControlsOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketPolicyOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketPolicyStatus(ctx context.Context, params *s3.GetBucketPolicyStatusInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyStatusOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketPolicyStatusOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketCors(ctx context.Context, params *s3.GetBucketCorsInput, optFns ...func(*s3.Options)) (*s3.GetBucketCorsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketCorsOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketWebsite(ctx context.Context, params *s3.GetBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.GetBucketWebsiteOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketWebsiteOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketLogging(ctx context.Context, params *s3.GetBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketLoggingOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketLoggingOutput), args.Error(1)
+}
+
+func (m *mockS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.ListObjectsV2Output), args.Error(1)
+}
+
+func (m *mockS3Client) GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetObjectAclOutput), args.Error(1)
+}
+
+func (m *mockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.HeadObjectOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetObjectOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketReplication(ctx context.Context, params *s3.GetBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketReplicationOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketTagging(ctx context.Context, params *s3.GetBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketTaggingOutput), args.Error(1)
+}
+
 // Add mock STS client
 type mockSTSClient struct {
 	mock.Mock
@@ -120,6 +219,10 @@ func TestScanner_AuditBucket(t *testing.T) {
 					&s3.GetBucketVersioningOutput{
 						Status: "Enabled",
 					}, nil)
+				s.On("GetObjectLockConfiguration", mock.Anything, mock.Anything).Return(
+					&s3.GetObjectLockConfigurationOutput{}, &smithy.GenericAPIError{Code: "ObjectLockConfigurationNotFoundError"})
+				s.On("GetBucketLifecycleConfiguration", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketLifecycleConfigurationOutput{}, &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration"})
 				s.On("GetPublicAccessBlock", mock.Anything, mock.Anything).Return(
 					&s3.GetPublicAccessBlockOutput{
 						PublicAccessBlockConfiguration: &s3types.PublicAccessBlockConfiguration{
@@ -129,6 +232,27 @@ func TestScanner_AuditBucket(t *testing.T) {
 							RestrictPublicBuckets: aws.Bool(true),
 						},
 					}, nil)
+				s.On("GetBucketOwnershipControls", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketOwnershipControlsOutput{
+						OwnershipControls: &s3types.OwnershipControls{
+							Rules: []s3types.OwnershipControlsRule{{ObjectOwnership: s3types.ObjectOwnershipBucketOwnerEnforced}},
+						},
+					}, nil)
+
+				s.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{}, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy"})
+
+				s.On("GetBucketWebsite", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketWebsiteOutput{}, &smithy.GenericAPIError{Code: "NoSuchWebsiteConfiguration"})
+				s.On("GetBucketCors", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketCorsOutput{}, &smithy.GenericAPIError{Code: "NoSuchCORSConfiguration"})
+				s.On("GetBucketReplication", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketReplicationOutput{}, &smithy.GenericAPIError{Code: "ReplicationConfigurationNotFoundError"})
+				s.On("GetBucketTagging", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketTaggingOutput{}, &smithy.GenericAPIError{Code: "NoSuchTagSet"})
+
+				s.On("GetBucketLogging", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketLoggingOutput{}, nil)
 
 				// Mock STS response - remove the return value since it's hardcoded in the mock
 				sts.On("GetCallerIdentity", mock.Anything, mock.Anything).Return(nil, nil)
@@ -171,7 +295,102 @@ func TestScanner_AuditBucket(t *testing.T) {
 				assert.Error(t, err)
 			} else {
 				assert.NoError(t, err)
+				// The policy, TLS and encryption checks share one policy read
+				mockS3.AssertNumberOfCalls(t, "GetBucketPolicy", 1)
 			}
 		})
 	}
 }
+
+func TestScanner_AuditBuckets(t *testing.T) {
+	mockMacie := new(MockMacieClient)
+	mockS3 := new(mockS3Client)
+	mockSTS := new(mockSTSClient)
+
+	forBucket := func(name string) interface{} {
+		return mock.MatchedBy(func(in *s3.GetBucketLocationInput) bool {
+			return aws.ToString(in.Bucket) == name
+		})
+	}
+	mockS3.On("GetBucketLocation", mock.Anything, forBucket("bucket-a")).Return(
+		&s3.GetBucketLocationOutput{LocationConstraint: "eu-west-1"}, nil)
+	mockS3.On("GetBucketLocation", mock.Anything, forBucket("bucket-b")).Return(
+		&s3.GetBucketLocationOutput{}, &s3types.NoSuchBucket{})
+	mockS3.On("GetBucketLocation", mock.Anything, forBucket("bucket-c")).Return(
+		&s3.GetBucketLocationOutput{}, nil)
+	mockS3.On("GetBucketVersioning", mock.Anything, mock.Anything).Return(
+		&s3.GetBucketVersioningOutput{Status: "Enabled"}, nil)
+	mockS3.On("GetObjectLockConfiguration", mock.Anything, mock.Anything).Return(
+		&s3.GetObjectLockConfigurationOutput{}, &smithy.GenericAPIError{Code: "ObjectLockConfigurationNotFoundError"})
+
+	scanner := NewScanner(aws.Config{Region: "us-east-1"}, mockS3, mockMacie, mockSTS)
+	assert.NoError(t, scanner.SetChecks([]string{CheckVersioning}))
+	scanner.SetConcurrency(2)
+
+	results := scanner.AuditBuckets(context.Background(), []string{"bucket-a", "bucket-b", "bucket-c"})
+
+	assert.Len(t, results, 3)
+	assert.NoError(t, results[0].Err)
+	assert.Equal(t, "eu-west-1", results[0].Info.Region)
+	assert.Equal(t, "Enabled", results[0].Info.VersioningStatus)
+	assert.Error(t, results[1].Err)
+	assert.Equal(t, "bucket-b", results[1].Info.Name)
+	assert.NoError(t, results[2].Err)
+	assert.Equal(t, "us-east-1", results[2].Info.Region)
+	mockMacie.AssertNotCalled(t, "CreateClassificationJob", mock.Anything, mock.Anything)
+}
+
+func TestScanner_AuditBucketsCancelled(t *testing.T) {
+	scanner := NewScanner(aws.Config{Region: "us-east-1"}, new(mockS3Client), new(MockMacieClient), new(mockSTSClient))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	results := scanner.AuditBuckets(ctx, []string{"bucket-a", "bucket-b"})
+
+	assert.Len(t, results, 2)
+	for _, result := range results {
+		assert.ErrorIs(t, result.Err, context.Canceled)
+	}
+}
+
+// mockBackupClient mocks the AWS Backup client interface
+type mockBackupClient struct {
+	mock.Mock
+}
+
+func (m *mockBackupClient) ListProtectedResources(ctx context.Context, params *backup.ListProtectedResourcesInput, optFns ...func(*backup.Options)) (*backup.ListProtectedResourcesOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*backup.ListProtectedResourcesOutput), args.Error(1)
+}
+
+func TestScanner_BackupProtectedBuckets(t *testing.T) {
+	euBackup := new(mockBackupClient)
+	euBackup.On("ListProtectedResources", mock.Anything, mock.Anything).Return(&backup.ListProtectedResourcesOutput{
+		Results: []backuptypes.ProtectedResource{
+			{ResourceType: aws.String("S3"), ResourceArn: aws.String("arn:aws:s3:::eu-bucket")},
+		},
+	}, nil).Once()
+	usBackup := new(mockBackupClient)
+	usBackup.On("ListProtectedResources", mock.Anything, mock.Anything).Return(
+		(*backup.ListProtectedResourcesOutput)(nil), &smithy.GenericAPIError{Code: "AccessDeniedException"}).Once()
+
+	scanner := NewScanner(aws.Config{Region: "us-east-1"}, new(mockS3Client), new(MockMacieClient), new(mockSTSClient))
+	var regions []string
+	scanner.SetBackupClients(func(region string) awsutils.BackupClientAPI {
+		regions = append(regions, region)
+		if region == "eu-west-1" {
+			return euBackup
+		}
+		return usBackup
+	})
+
+	// Each region is listed once, with a client for that region
+	assert.Equal(t, map[string]bool{"arn:aws:s3:::eu-bucket": true}, scanner.backupProtectedBuckets("eu-west-1"))
+	assert.Equal(t, map[string]bool{"arn:aws:s3:::eu-bucket": true}, scanner.backupProtectedBuckets("eu-west-1"))
+	assert.Nil(t, scanner.backupProtectedBuckets("us-east-1"))
+	assert.Nil(t, scanner.backupProtectedBuckets("us-east-1"))
+	assert.Equal(t, []string{"eu-west-1", "us-east-1"}, regions)
+	euBackup.AssertExpectations(t)
+	usBackup.AssertExpectations(t)
+}
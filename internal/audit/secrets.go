@@ -0,0 +1,298 @@
+package audit
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"path"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/fatih/color"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// CheckIDSecrets identifies findings raised for secrets in object contents
+const CheckIDSecrets = "objects.secrets"
+
+const (
+	// maxSecretsPerObject caps the secrets recorded for a single object
+	maxSecretsPerObject = 20
+	// snippetContext is how many characters around a secret a snippet shows
+	snippetContext = 30
+	// minSecretEntropy is the Shannon entropy, in bits per character, above
+	// which a long token is reported as a possible secret. Hex strings such
+	// as hashes stay at or below 4.
+	minSecretEntropy = 4.0
+)
+
+// secretRule finds one kind of secret
+type secretRule struct {
+	name       string
+	confidence string
+	pattern    *regexp.Regexp
+	// redact hides the match in snippets; private key headers are shown as
+	// they are, as the key itself follows on the next lines
+	redact bool
+	// dotEnvOnly applies the rule only to .env files
+	dotEnvOnly bool
+	// valid rejects matches such as placeholders; nil accepts every match
+	valid func(secret string) bool
+}
+
+// secretRules run in order, and a match overlapping one found earlier on the
+// same line is ignored, so the generic high-entropy rule comes last
+var secretRules = []secretRule{
+	{name: "aws-access-key-id", confidence: models.ConfidenceHigh, pattern: awsAccessKeyPattern, redact: true},
+	{name: "aws-secret-access-key", confidence: models.ConfidenceHigh, pattern: awsSecretKeyPattern, redact: true},
+	{name: "github-token", confidence: models.ConfidenceHigh, pattern: regexp.MustCompile(`\b(?:ghp|gho|ghu|ghs|ghr)_[A-Za-z0-9]{36}\b|\bgithub_pat_[A-Za-z0-9_]{82}\b`), redact: true},
+	{name: "gitlab-token", confidence: models.ConfidenceHigh, pattern: regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20}\b`), redact: true},
+	{name: "slack-token", confidence: models.ConfidenceHigh, pattern: regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`), redact: true},
+	{name: "slack-webhook", confidence: models.ConfidenceHigh, pattern: regexp.MustCompile(`https://hooks\.slack\.com/services/T[A-Za-z0-9]+/B[A-Za-z0-9]+/[A-Za-z0-9]+`), redact: true},
+	{name: "private-key", confidence: models.ConfidenceHigh, pattern: privateKeyPattern},
+	// A JWT may have expired, but often grants access while it has not
+	{name: "jwt", confidence: models.ConfidenceMedium, pattern: regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{10,}\.eyJ[A-Za-z0-9_-]{10,}\.[A-Za-z0-9_-]{10,}`), redact: true},
+	{
+		name:       "dotenv-secret",
+		confidence: models.ConfidenceMedium,
+		pattern:    regexp.MustCompile(`(?i)^\s*(?:export\s+)?[A-Z0-9_]*(?:SECRET|PASSWORD|PASSWD|TOKEN|API_?KEY|PRIVATE_?KEY|CREDENTIAL)[A-Z0-9_]*\s*=\s*["']?([^\s"'#]+)`),
+		redact:     true,
+		dotEnvOnly: true,
+		valid: func(secret string) bool {
+			return len(secret) >= 6 && !strings.HasPrefix(secret, "$")
+		},
+	},
+	{
+		name:       "high-entropy-string",
+		confidence: models.ConfidenceLow,
+		pattern:    regexp.MustCompile(`[A-Za-z0-9+/_=-]{24,}`),
+		redact:     true,
+		valid: func(secret string) bool {
+			return strings.ContainsAny(secret, "0123456789") &&
+				strings.IndexFunc(secret, func(r rune) bool { return r >= 'A' && r <= 'Z' || r >= 'a' && r <= 'z' }) >= 0 &&
+				shannonEntropy(secret) > minSecretEntropy
+		},
+	},
+}
+
+// SecretScanner looks for developer secrets such as access keys, tokens and
+// private keys in the contents of objects
+type SecretScanner struct {
+	s3Client awsutils.S3ClientAPI
+	opts     LocalScanOptions
+}
+
+// NewSecretScanner returns a scanner that reads objects with s3Client
+func NewSecretScanner(s3Client awsutils.S3ClientAPI, opts LocalScanOptions) *SecretScanner {
+	return &SecretScanner{s3Client: s3Client, opts: opts}
+}
+
+// Scan reads the objects of the bucket and returns the secrets found, with one
+// finding per affected object
+func (s *SecretScanner) Scan(ctx context.Context, bucketName string) (models.SecretScan, []models.Finding, error) {
+	var scan models.SecretScan
+	keys, err := listObjectsToScan(ctx, s.s3Client, bucketName, s.opts, nil)
+	if err != nil {
+		return scan, nil, fmt.Errorf("unable to list objects: %w", err)
+	}
+	color.Yellow("🔑 Scanning %d object(s) in %s for secrets\n", len(keys), bucketName)
+	log.Printf("Scanning %d object(s) in %s for secrets", len(keys), bucketName)
+
+	var findings []models.Finding
+	for _, key := range keys {
+		if err := ctx.Err(); err != nil {
+			return scan, nil, err
+		}
+
+		var secrets []models.SecretMatch
+		scanned, skipped, err := scanObjectLines(ctx, s.s3Client, bucketName, key, s.opts, func(line objectLine) bool {
+			// Files inside archives are named by their own extension
+			name := key
+			if line.File != "" {
+				name = line.File[strings.LastIndex(line.File, "!")+1:]
+			}
+			for _, secret := range findSecrets(key, line.Text, line.Number, isDotEnv(name)) {
+				secret.File = line.File
+				secrets = append(secrets, secret)
+			}
+			return len(secrets) < maxSecretsPerObject
+		})
+		if err != nil {
+			log.Printf("Warning: unable to scan object %s in bucket %s for secrets: %v", key, bucketName, err)
+			scan.Skipped = append(scan.Skipped, unreadableObject(key, err))
+			continue
+		}
+		scan.Skipped = append(scan.Skipped, skipped...)
+		if !scanned {
+			continue
+		}
+		scan.ObjectsScanned++
+		if len(secrets) == 0 {
+			continue
+		}
+
+		if len(secrets) > maxSecretsPerObject {
+			secrets = secrets[:maxSecretsPerObject]
+		}
+		log.Printf("Found %d possible secret(s) in %s", len(secrets), key)
+		scan.Secrets = append(scan.Secrets, secrets...)
+		findings = append(findings, secretsFinding(bucketName, key, secrets))
+	}
+
+	// Most confident first, so the report leads with what certainly needs rotating
+	sort.SliceStable(scan.Secrets, func(i, j int) bool {
+		return confidenceRank(scan.Secrets[i].Confidence) > confidenceRank(scan.Secrets[j].Confidence)
+	})
+	return scan, findings, nil
+}
+
+// findSecrets applies the secret rules to a line of an object
+func findSecrets(key, line string, lineNumber int, dotEnv bool) []models.SecretMatch {
+	type match struct {
+		rule secretRule
+		span []int
+	}
+	var matches []match
+	var claimed [][]int
+	for _, rule := range secretRules {
+		if rule.dotEnvOnly && !dotEnv {
+			continue
+		}
+		for _, indexes := range rule.pattern.FindAllStringSubmatchIndex(line, -1) {
+			// Rules with a capture group match context around the secret
+			span := indexes[:2]
+			if len(indexes) >= 4 && indexes[2] >= 0 {
+				span = indexes[2:4]
+			}
+			if overlaps(claimed, span) || rule.valid != nil && !rule.valid(line[span[0]:span[1]]) {
+				continue
+			}
+			claimed = append(claimed, span)
+			matches = append(matches, match{rule: rule, span: span})
+		}
+	}
+
+	// Every secret on the line is redacted, not just the one a snippet is for
+	var redacted [][]int
+	for _, m := range matches {
+		if m.rule.redact {
+			redacted = append(redacted, m.span)
+		}
+	}
+	secrets := make([]models.SecretMatch, 0, len(matches))
+	for _, m := range matches {
+		secrets = append(secrets, models.SecretMatch{
+			Key:        key,
+			Rule:       m.rule.name,
+			Confidence: m.rule.confidence,
+			Line:       lineNumber,
+			Snippet:    snippet(line, m.span, redacted),
+		})
+	}
+	return secrets
+}
+
+// snippet returns the text around span with the redacted spans masked
+func snippet(line string, span []int, redacted [][]int) string {
+	start := max(span[0]-snippetContext, 0)
+	end := min(span[1]+snippetContext, len(line))
+
+	sort.Slice(redacted, func(i, j int) bool { return redacted[i][0] < redacted[j][0] })
+	var b strings.Builder
+	if start > 0 {
+		b.WriteString("…")
+	}
+	pos := start
+	for _, r := range redacted {
+		if r[1] <= pos || r[0] >= end {
+			continue
+		}
+		b.WriteString(line[pos:max(r[0], pos)])
+		b.WriteString(redact(line[r[0]:r[1]]))
+		pos = r[1]
+	}
+	if pos < end {
+		b.WriteString(line[pos:end])
+	}
+	if end < len(line) {
+		b.WriteString("…")
+	}
+	return strings.TrimSpace(b.String())
+}
+
+// redact keeps enough of a secret to recognize it, but not to use it
+func redact(secret string) string {
+	if len(secret) <= 8 {
+		return "********"
+	}
+	return secret[:4] + "********"
+}
+
+// shannonEntropy returns the entropy of s in bits per character
+func shannonEntropy(s string) float64 {
+	counts := map[rune]int{}
+	for _, r := range s {
+		counts[r]++
+	}
+	var entropy float64
+	n := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / n
+		entropy -= p * math.Log2(p)
+	}
+	return entropy
+}
+
+// isDotEnv reports whether the object is a dotenv file such as .env or
+// .env.production
+func isDotEnv(key string) bool {
+	name := path.Base(key)
+	return name == ".env" || strings.HasPrefix(name, ".env.") || strings.HasSuffix(name, ".env")
+}
+
+func confidenceRank(confidence string) int {
+	switch confidence {
+	case models.ConfidenceHigh:
+		return 3
+	case models.ConfidenceMedium:
+		return 2
+	default:
+		return 1
+	}
+}
+
+// secretsFinding reports the secrets found in one object
+func secretsFinding(bucketName, key string, secrets []models.SecretMatch) models.Finding {
+	highest := models.ConfidenceLow
+	for _, secret := range secrets {
+		if confidenceRank(secret.Confidence) > confidenceRank(highest) {
+			highest = secret.Confidence
+		}
+	}
+	severity := models.SeverityLow
+	switch highest {
+	case models.ConfidenceHigh:
+		severity = models.SeverityHigh
+	case models.ConfidenceMedium:
+		severity = models.SeverityMedium
+	}
+
+	f := models.Finding{
+		CheckID:     CheckIDSecrets,
+		Severity:    severity,
+		Title:       "Object contains secrets",
+		Resource:    models.ObjectARN(bucketName, key),
+		Remediation: "Rotate the exposed credentials, then delete the object or move the secrets to a secrets manager",
+	}
+	if highest == models.ConfidenceLow {
+		f.Title = "Object contains strings that look like secrets"
+	}
+	for _, secret := range secrets {
+		location := objectLine{File: secret.File, Number: secret.Line}.location()
+		f.Evidence = append(f.Evidence, fmt.Sprintf("%s (%s confidence) at %s: %s", secret.Rule, secret.Confidence, location, secret.Snippet))
+	}
+	return f
+}
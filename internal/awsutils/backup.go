@@ -0,0 +1,36 @@
+package awsutils
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/backup"
+)
+
+// BackupClientAPI defines the interface for AWS Backup operations we use
+type BackupClientAPI interface {
+	ListProtectedResources(ctx context.Context, params *backup.ListProtectedResourcesInput, optFns ...func(*backup.Options)) (*backup.ListProtectedResourcesOutput, error)
+}
+
+// backupS3ResourceType is the AWS Backup resource type of S3 buckets
+const backupS3ResourceType = "S3"
+
+// GetBackupProtectedBuckets returns the ARNs of the S3 buckets AWS Backup
+// holds at least one recovery point for
+func GetBackupProtectedBuckets(client BackupClientAPI) (map[string]bool, error) {
+	protected := map[string]bool{}
+
+	paginator := backup.NewListProtectedResourcesPaginator(client, &backup.ListProtectedResourcesInput{})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return nil, err
+		}
+		for _, resource := range page.Results {
+			if aws.ToString(resource.ResourceType) == backupS3ResourceType {
+				protected[aws.ToString(resource.ResourceArn)] = true
+			}
+		}
+	}
+	return protected, nil
+}
@@ -5,14 +5,20 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/macie2"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
 )
 
 type AWSClients struct {
-	Config      aws.Config
-	S3Client    *s3.Client
-	MacieClient *macie2.Client
+	Config           aws.Config
+	S3Client         *s3.Client
+	MacieClient      *macie2.Client
+	S3ControlClient  *s3control.Client
+	CloudTrailClient *cloudtrail.Client
+	KMSClient        *kms.Client
 }
 
 func NewAWSClients(ctx context.Context) (*AWSClients, error) {
@@ -22,8 +28,11 @@ func NewAWSClients(ctx context.Context) (*AWSClients, error) {
 	}
 
 	return &AWSClients{
-		Config:      cfg,
-		S3Client:    s3.NewFromConfig(cfg),
-		MacieClient: macie2.NewFromConfig(cfg),
+		Config:           cfg,
+		S3Client:         s3.NewFromConfig(cfg),
+		MacieClient:      macie2.NewFromConfig(cfg),
+		S3ControlClient:  s3control.NewFromConfig(cfg),
+		CloudTrailClient: cloudtrail.NewFromConfig(cfg),
+		KMSClient:        kms.NewFromConfig(cfg),
 	}, nil
 }
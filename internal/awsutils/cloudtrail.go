@@ -0,0 +1,192 @@
+package awsutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+)
+
+// CloudTrailClientAPI defines the interface for CloudTrail operations we use
+type CloudTrailClientAPI interface {
+	DescribeTrails(ctx context.Context, params *cloudtrail.DescribeTrailsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.DescribeTrailsOutput, error)
+	GetEventSelectors(ctx context.Context, params *cloudtrail.GetEventSelectorsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventSelectorsOutput, error)
+	GetTrailStatus(ctx context.Context, params *cloudtrail.GetTrailStatusInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetTrailStatusOutput, error)
+}
+
+const s3ObjectResourceType = "AWS::S3::Object"
+
+// TrailDataEvents describes which S3 objects a trail records data events for
+type TrailDataEvents struct {
+	Name      string
+	selectors []s3DataSelector
+}
+
+// s3DataSelector matches object ARNs by prefix, or exactly when listed in
+// objects. No prefixes or objects means every bucket; excludes remove matches
+// again. reads and writes tell which data events it records.
+type s3DataSelector struct {
+	prefixes []string
+	objects  []string
+	excludes []string
+	reads    bool
+	writes   bool
+}
+
+// Covers reports whether the trail records write data events, such as
+// PutObject and DeleteObject, for every object in the bucket. Selectors
+// limited to a key prefix only cover part of it.
+func (t TrailDataEvents) Covers(bucketName string) bool {
+	_, writes := t.Coverage(bucketName)
+	return writes
+}
+
+// Coverage reports whether the trail records read and write data events for
+// every object in the bucket. Selectors naming single objects, or excluding
+// any part of the bucket, do not cover it.
+func (t TrailDataEvents) Coverage(bucketName string) (reads, writes bool) {
+	objects := fmt.Sprintf("arn:aws:s3:::%s/", bucketName)
+	for _, sel := range t.selectors {
+		if matchesPrefix(sel.excludes, objects) || insideBucket(sel.excludes, objects) {
+			continue
+		}
+		all := len(sel.prefixes) == 0 && len(sel.objects) == 0
+		if all || matchesPrefix(sel.prefixes, objects) {
+			reads = reads || sel.reads
+			writes = writes || sel.writes
+		}
+	}
+	return reads, writes
+}
+
+// insideBucket reports whether any prefix only matches some of the objects
+// whose ARNs start with objects
+func insideBucket(prefixes []string, objects string) bool {
+	for _, p := range prefixes {
+		if len(p) > len(objects) && strings.HasPrefix(p, objects) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchesPrefix(prefixes []string, arn string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(arn, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// GetS3DataEventTrails returns the trails that are logging and record S3 data
+// events. Trails whose status or selectors cannot be read are skipped and
+// reported in the error.
+func GetS3DataEventTrails(client CloudTrailClientAPI) ([]TrailDataEvents, error) {
+	trailsOutput, err := client.DescribeTrails(context.Background(), &cloudtrail.DescribeTrailsInput{
+		IncludeShadowTrails: aws.Bool(true),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	trails := []TrailDataEvents{}
+	var errs []error
+	for _, trail := range trailsOutput.TrailList {
+		// Shadow trails, replicated from another region, are named by ARN
+		statusOutput, err := client.GetTrailStatus(context.Background(), &cloudtrail.GetTrailStatusInput{
+			Name: trail.TrailARN,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("trail %s: %w", aws.ToString(trail.Name), err))
+			continue
+		}
+		if !aws.ToBool(statusOutput.IsLogging) {
+			continue
+		}
+
+		selectorsOutput, err := client.GetEventSelectors(context.Background(), &cloudtrail.GetEventSelectorsInput{
+			TrailName: trail.TrailARN,
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("trail %s: %w", aws.ToString(trail.Name), err))
+			continue
+		}
+
+		selectors := basicS3Selectors(selectorsOutput.EventSelectors)
+		selectors = append(selectors, advancedS3Selectors(selectorsOutput.AdvancedEventSelectors)...)
+		if len(selectors) > 0 {
+			trails = append(trails, TrailDataEvents{Name: aws.ToString(trail.Name), selectors: selectors})
+		}
+	}
+
+	return trails, errors.Join(errs...)
+}
+
+func basicS3Selectors(eventSelectors []types.EventSelector) []s3DataSelector {
+	var selectors []s3DataSelector
+	for _, es := range eventSelectors {
+		for _, dr := range es.DataResources {
+			if aws.ToString(dr.Type) != s3ObjectResourceType {
+				continue
+			}
+			// An unset ReadWriteType records both
+			sel := s3DataSelector{
+				reads:  es.ReadWriteType != types.ReadWriteTypeWriteOnly,
+				writes: es.ReadWriteType != types.ReadWriteTypeReadOnly,
+			}
+			for _, value := range dr.Values {
+				// "arn:aws:s3" and "arn:aws:s3:::" select every bucket
+				if value == "arn:aws:s3" || value == "arn:aws:s3:::" {
+					sel.prefixes = nil
+					break
+				}
+				sel.prefixes = append(sel.prefixes, value)
+			}
+			selectors = append(selectors, sel)
+		}
+	}
+	return selectors
+}
+
+func advancedS3Selectors(advanced []types.AdvancedEventSelector) []s3DataSelector {
+	var selectors []s3DataSelector
+	for _, aes := range advanced {
+		isData, isS3Object := false, false
+		// Without a readOnly field, both reads and writes are recorded
+		sel := s3DataSelector{reads: true, writes: true}
+		for _, field := range aes.FieldSelectors {
+			switch aws.ToString(field.Field) {
+			case "readOnly":
+				readOnly := containsString(field.Equals, "true") || containsString(field.NotEquals, "false")
+				writeOnly := containsString(field.Equals, "false") || containsString(field.NotEquals, "true")
+				sel.reads, sel.writes = !writeOnly, !readOnly
+			case "eventCategory":
+				isData = containsString(field.Equals, "Data")
+			case "resources.type":
+				isS3Object = containsString(field.Equals, s3ObjectResourceType)
+			case "resources.ARN":
+				sel.prefixes = append(sel.prefixes, field.StartsWith...)
+				sel.objects = append(sel.objects, field.Equals...)
+				sel.excludes = append(sel.excludes, field.NotStartsWith...)
+			}
+		}
+		if isData && isS3Object {
+			selectors = append(selectors, sel)
+		}
+	}
+	return selectors
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,85 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Check IDs of the findings raised by the CORS check
+const (
+	CheckIDCORSWildcardOrigin = "cors.wildcard-origin"
+	CheckIDCORSWildcardWrite  = "cors.wildcard-write"
+)
+
+// GetBucketCORS reads the CORS rules of the bucket. A bucket without a CORS
+// configuration is reported as not configured.
+func GetBucketCORS(s3Client S3ClientAPI, bucketName string) (models.CORSStatus, error) {
+	var status models.CORSStatus
+
+	corsOutput, err := s3Client.GetBucketCors(context.Background(), &s3.GetBucketCorsInput{
+		Bucket: aws.String(bucketName),
+	})
+	if isAPIError(err, "NoSuchCORSConfiguration") {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	for _, rule := range corsOutput.CORSRules {
+		status.Rules = append(status.Rules, models.CORSRule{
+			ID:             aws.ToString(rule.ID),
+			AllowedOrigins: rule.AllowedOrigins,
+			AllowedMethods: rule.AllowedMethods,
+			AllowedHeaders: rule.AllowedHeaders,
+			ExposeHeaders:  rule.ExposeHeaders,
+			MaxAgeSeconds:  aws.ToInt32(rule.MaxAgeSeconds),
+		})
+	}
+	status.Configured = len(status.Rules) > 0
+	return status, nil
+}
+
+// CheckBucketCORS reads the CORS rules and returns a finding for every rule
+// that allows any origin, raised in severity when the rule also allows
+// methods that modify objects
+func CheckBucketCORS(s3Client S3ClientAPI, bucketName string) (models.CORSStatus, []models.Finding, error) {
+	status, err := GetBucketCORS(s3Client, bucketName)
+	if err != nil {
+		return status, nil, err
+	}
+
+	var findings []models.Finding
+	for _, rule := range status.Rules {
+		if !rule.AllowsAnyOrigin() {
+			continue
+		}
+		evidence := []string{fmt.Sprintf("Rule %s", rule.Summary())}
+		if writes := rule.WriteMethods(); len(writes) > 0 {
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDCORSWildcardWrite,
+				Severity:    models.SeverityMedium,
+				Title:       fmt.Sprintf("CORS allows %s from any origin", strings.Join(writes, ", ")),
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    evidence,
+				Remediation: "List the trusted origins explicitly in AllowedOrigins, or drop the write methods from the rule",
+			})
+			continue
+		}
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDCORSWildcardOrigin,
+			Severity:    models.SeverityLow,
+			Title:       "CORS allows requests from any origin",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    evidence,
+			Remediation: "List the trusted origins explicitly in AllowedOrigins",
+		})
+	}
+
+	return status, findings, nil
+}
@@ -0,0 +1,70 @@
+package awsutils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckBucketCORS(t *testing.T) {
+	tests := []struct {
+		name           string
+		rules          []types.CORSRule
+		expectedChecks []string
+	}{
+		{
+			name: "No CORS configuration",
+		},
+		{
+			name: "Specific origin",
+			rules: []types.CORSRule{{
+				AllowedOrigins: []string{"https://app.example.com"},
+				AllowedMethods: []string{"GET", "PUT"},
+			}},
+		},
+		{
+			name: "Wildcard origin for reads",
+			rules: []types.CORSRule{{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{"GET", "HEAD"},
+			}},
+			expectedChecks: []string{CheckIDCORSWildcardOrigin},
+		},
+		{
+			name: "Wildcard origin with writes",
+			rules: []types.CORSRule{{
+				AllowedOrigins: []string{"*"},
+				AllowedMethods: []string{"GET", "PUT", "DELETE"},
+				ExposeHeaders:  []string{"ETag"},
+			}},
+			expectedChecks: []string{CheckIDCORSWildcardWrite},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			if tt.rules == nil {
+				mockClient.On("GetBucketCors", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketCorsOutput{}, &smithy.GenericAPIError{Code: "NoSuchCORSConfiguration"})
+			} else {
+				mockClient.On("GetBucketCors", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketCorsOutput{CORSRules: tt.rules}, nil)
+			}
+
+			status, findings, err := CheckBucketCORS(mockClient, "bucket")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.rules != nil, status.Configured)
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
@@ -0,0 +1,32 @@
+package awsutils
+
+import (
+	"errors"
+
+	"github.com/aws/smithy-go"
+)
+
+// isAPIError reports whether err is an AWS API error with one of the codes
+func isAPIError(err error, codes ...string) bool {
+	var apiErr smithy.APIError
+	if !errors.As(err, &apiErr) {
+		return false
+	}
+	for _, code := range codes {
+		if apiErr.ErrorCode() == code {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAccessDenied reports whether err is an AWS access denied error
+func IsAccessDenied(err error) bool {
+	return isAPIError(err, "AccessDenied", "AccessDeniedException")
+}
+
+// IsNotFound reports whether err says the requested Macie resource, such as a
+// classification job, does not exist
+func IsNotFound(err error) bool {
+	return isAPIError(err, "ResourceNotFoundException")
+}
@@ -0,0 +1,224 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// CloudWatchClientAPI defines the interface for CloudWatch operations we use
+type CloudWatchClientAPI interface {
+	ListMetrics(ctx context.Context, params *cloudwatch.ListMetricsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.ListMetricsOutput, error)
+	GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error)
+}
+
+// largestObjectCount is how many of the largest objects an inventory keeps
+const largestObjectCount = 5
+
+// s3StorageTypes maps CloudWatch StorageType dimensions to storage classes.
+// Intelligent-Tiering reports one storage type per access tier; types not
+// listed, such as per-object overheads, keep their CloudWatch name.
+var s3StorageTypes = map[string]string{
+	"StandardStorage":                "STANDARD",
+	"StandardIAStorage":              "STANDARD_IA",
+	"OneZoneIAStorage":               "ONEZONE_IA",
+	"ReducedRedundancyStorage":       "REDUCED_REDUNDANCY",
+	"GlacierInstantRetrievalStorage": "GLACIER_IR",
+	"GlacierStorage":                 "GLACIER",
+	"DeepArchiveStorage":             "DEEP_ARCHIVE",
+	"ExpressOneZone":                 "EXPRESS_ONEZONE",
+}
+
+// ListBucketInventory paginates the objects of the bucket and totals their
+// size per storage class. Listing stops after maxObjects objects when
+// maxObjects is positive.
+func ListBucketInventory(s3Client S3ClientAPI, bucketName string, maxObjects int) (models.BucketInventory, error) {
+	inventory := models.BucketInventory{
+		Bucket:         bucketName,
+		Source:         models.InventorySourceListing,
+		StorageClasses: map[string]models.StorageClassUsage{},
+	}
+
+	paginator := s3.NewListObjectsV2Paginator(s3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(bucketName),
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return inventory, err
+		}
+		for _, object := range page.Contents {
+			if maxObjects > 0 && inventory.ObjectCount >= int64(maxObjects) {
+				inventory.Truncated = true
+				return inventory, nil
+			}
+
+			size := aws.ToInt64(object.Size)
+			class := string(object.StorageClass)
+			if class == "" {
+				class = "STANDARD"
+			}
+			inventory.ObjectCount++
+			inventory.TotalSize += size
+			usage := inventory.StorageClasses[class]
+			usage.Objects++
+			usage.Size += size
+			inventory.StorageClasses[class] = usage
+
+			if object.LastModified != nil {
+				modified := *object.LastModified
+				if inventory.Oldest == nil || modified.Before(*inventory.Oldest) {
+					inventory.Oldest = &modified
+				}
+				if inventory.Newest == nil || modified.After(*inventory.Newest) {
+					inventory.Newest = &modified
+				}
+			}
+			inventory.LargestObjects = keepLargest(inventory.LargestObjects, models.ObjectSummary{
+				Key:          aws.ToString(object.Key),
+				Size:         size,
+				StorageClass: class,
+				LastModified: aws.ToTime(object.LastModified),
+			})
+		}
+	}
+
+	return inventory, nil
+}
+
+// keepLargest inserts the object into the list of largest objects
+func keepLargest(largest []models.ObjectSummary, object models.ObjectSummary) []models.ObjectSummary {
+	if len(largest) == largestObjectCount && object.Size <= largest[len(largest)-1].Size {
+		return largest
+	}
+	i := sort.Search(len(largest), func(i int) bool { return largest[i].Size < object.Size })
+	largest = append(largest, models.ObjectSummary{})
+	copy(largest[i+1:], largest[i:])
+	largest[i] = object
+	if len(largest) > largestObjectCount {
+		largest = largest[:largestObjectCount]
+	}
+	return largest
+}
+
+// GetBucketMetrics reads the daily BucketSizeBytes and NumberOfObjects
+// storage metrics S3 publishes to CloudWatch. The client must be in the
+// bucket's region. It returns false when the bucket has no metrics yet.
+func GetBucketMetrics(client CloudWatchClientAPI, bucketName string) (models.BucketInventory, bool, error) {
+	inventory := models.BucketInventory{
+		Bucket:         bucketName,
+		Source:         models.InventorySourceCloudWatch,
+		StorageClasses: map[string]models.StorageClassUsage{},
+	}
+
+	var storageTypes []string
+	paginator := cloudwatch.NewListMetricsPaginator(client, &cloudwatch.ListMetricsInput{
+		Namespace:  aws.String("AWS/S3"),
+		MetricName: aws.String("BucketSizeBytes"),
+		Dimensions: []cwtypes.DimensionFilter{{Name: aws.String("BucketName"), Value: aws.String(bucketName)}},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(context.Background())
+		if err != nil {
+			return inventory, false, err
+		}
+		for _, metric := range page.Metrics {
+			for _, dim := range metric.Dimensions {
+				if aws.ToString(dim.Name) == "StorageType" {
+					storageTypes = append(storageTypes, aws.ToString(dim.Value))
+				}
+			}
+		}
+	}
+	if len(storageTypes) == 0 {
+		return inventory, false, nil
+	}
+
+	queries := []cwtypes.MetricDataQuery{storageMetricQuery("objects", bucketName, "NumberOfObjects", "AllStorageTypes")}
+	for i, storageType := range storageTypes {
+		queries = append(queries, storageMetricQuery(fmt.Sprintf("size%d", i), bucketName, "BucketSizeBytes", storageType))
+	}
+
+	// Storage metrics are published once a day, so look back a few days
+	end := time.Now()
+	dataOutput, err := client.GetMetricData(context.Background(), &cloudwatch.GetMetricDataInput{
+		MetricDataQueries: queries,
+		StartTime:         aws.Time(end.Add(-72 * time.Hour)),
+		EndTime:           aws.Time(end),
+	})
+	if err != nil {
+		return inventory, false, err
+	}
+
+	found := false
+	for _, result := range dataOutput.MetricDataResults {
+		if len(result.Values) == 0 {
+			continue
+		}
+		found = true
+		// Results are ordered newest first
+		value := int64(result.Values[0])
+		id := aws.ToString(result.Id)
+		if id == "objects" {
+			inventory.ObjectCount = value
+			continue
+		}
+
+		var index int
+		if _, err := fmt.Sscanf(id, "size%d", &index); err != nil || index >= len(storageTypes) {
+			continue
+		}
+		class, ok := s3StorageTypes[storageTypes[index]]
+		if !ok && strings.HasPrefix(storageTypes[index], "IntelligentTiering") {
+			class, ok = "INTELLIGENT_TIERING", true
+		}
+		if !ok {
+			class = storageTypes[index]
+		}
+		usage := inventory.StorageClasses[class]
+		usage.Size += value
+		inventory.StorageClasses[class] = usage
+		inventory.TotalSize += value
+	}
+
+	return inventory, found, nil
+}
+
+func storageMetricQuery(id, bucketName, metricName, storageType string) cwtypes.MetricDataQuery {
+	return cwtypes.MetricDataQuery{
+		Id: aws.String(id),
+		MetricStat: &cwtypes.MetricStat{
+			Metric: &cwtypes.Metric{
+				Namespace:  aws.String("AWS/S3"),
+				MetricName: aws.String(metricName),
+				Dimensions: []cwtypes.Dimension{
+					{Name: aws.String("BucketName"), Value: aws.String(bucketName)},
+					{Name: aws.String("StorageType"), Value: aws.String(storageType)},
+				},
+			},
+			Period: aws.Int32(86400),
+			Stat:   aws.String("Average"),
+		},
+	}
+}
+
+// GetBucketInventory reports the contents of the bucket from CloudWatch
+// storage metrics when cwClient is set and the bucket has them, and from a
+// listing of up to maxObjects objects otherwise
+func GetBucketInventory(s3Client S3ClientAPI, cwClient CloudWatchClientAPI, bucketName string, maxObjects int) (models.BucketInventory, error) {
+	if cwClient != nil {
+		inventory, ok, err := GetBucketMetrics(cwClient, bucketName)
+		if err == nil && ok {
+			return inventory, nil
+		}
+	}
+	return ListBucketInventory(s3Client, bucketName, maxObjects)
+}
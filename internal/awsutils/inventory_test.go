@@ -0,0 +1,174 @@
+package awsutils
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	cwtypes "github.com/aws/aws-sdk-go-v2/service/cloudwatch/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockCloudWatchClient struct {
+	mock.Mock
+}
+
+func (m *mockCloudWatchClient) ListMetrics(ctx context.Context, params *cloudwatch.ListMetricsInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.ListMetricsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatch.ListMetricsOutput), args.Error(1)
+}
+
+func (m *mockCloudWatchClient) GetMetricData(ctx context.Context, params *cloudwatch.GetMetricDataInput, optFns ...func(*cloudwatch.Options)) (*cloudwatch.GetMetricDataOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudwatch.GetMetricDataOutput), args.Error(1)
+}
+
+func TestListBucketInventory(t *testing.T) {
+	day := func(d int) *time.Time {
+		modified := time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+		return &modified
+	}
+	objects := []types.Object{
+		{Key: aws.String("a"), Size: aws.Int64(10), LastModified: day(5)},
+		{Key: aws.String("b"), Size: aws.Int64(300), LastModified: day(2), StorageClass: types.ObjectStorageClassGlacier},
+		{Key: aws.String("c"), Size: aws.Int64(20), LastModified: day(9)},
+		{Key: aws.String("d"), Size: aws.Int64(50), LastModified: day(3)},
+		{Key: aws.String("e"), Size: aws.Int64(40), LastModified: day(7), StorageClass: types.ObjectStorageClassStandardIa},
+		{Key: aws.String("f"), Size: aws.Int64(30), LastModified: day(4)},
+		{Key: aws.String("g"), Size: aws.Int64(5), LastModified: day(6)},
+	}
+
+	tests := []struct {
+		name            string
+		maxObjects      int
+		expectedCount   int64
+		expectedSize    int64
+		expectedClasses map[string]models.StorageClassUsage
+		expectedLargest []string
+		expectedOldest  *time.Time
+		expectedNewest  *time.Time
+		expectTruncated bool
+	}{
+		{
+			name:          "All objects",
+			expectedCount: 7,
+			expectedSize:  455,
+			expectedClasses: map[string]models.StorageClassUsage{
+				"STANDARD":    {Objects: 5, Size: 115},
+				"GLACIER":     {Objects: 1, Size: 300},
+				"STANDARD_IA": {Objects: 1, Size: 40},
+			},
+			expectedLargest: []string{"b", "d", "e", "f", "c"},
+			expectedOldest:  day(2),
+			expectedNewest:  day(9),
+		},
+		{
+			name:          "Listing capped",
+			maxObjects:    2,
+			expectedCount: 2,
+			expectedSize:  310,
+			expectedClasses: map[string]models.StorageClassUsage{
+				"STANDARD": {Objects: 1, Size: 10},
+				"GLACIER":  {Objects: 1, Size: 300},
+			},
+			expectedLargest: []string{"b", "a"},
+			expectedOldest:  day(2),
+			expectedNewest:  day(5),
+			expectTruncated: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			mockClient.On("ListObjectsV2", mock.Anything, mock.MatchedBy(func(in *s3.ListObjectsV2Input) bool {
+				return in.ContinuationToken == nil
+			})).Return(&s3.ListObjectsV2Output{
+				Contents:              objects[:4],
+				IsTruncated:           aws.Bool(true),
+				NextContinuationToken: aws.String("page-2"),
+			}, nil)
+			mockClient.On("ListObjectsV2", mock.Anything, mock.MatchedBy(func(in *s3.ListObjectsV2Input) bool {
+				return aws.ToString(in.ContinuationToken) == "page-2"
+			})).Return(&s3.ListObjectsV2Output{Contents: objects[4:]}, nil)
+
+			inventory, err := ListBucketInventory(mockClient, "bucket", tt.maxObjects)
+
+			assert.NoError(t, err)
+			assert.Equal(t, models.InventorySourceListing, inventory.Source)
+			assert.Equal(t, tt.expectedCount, inventory.ObjectCount)
+			assert.Equal(t, tt.expectedSize, inventory.TotalSize)
+			assert.Equal(t, tt.expectedClasses, inventory.StorageClasses)
+			var largest []string
+			for _, object := range inventory.LargestObjects {
+				largest = append(largest, object.Key)
+			}
+			assert.Equal(t, tt.expectedLargest, largest)
+			assert.Equal(t, tt.expectedOldest, inventory.Oldest)
+			assert.Equal(t, tt.expectedNewest, inventory.Newest)
+			assert.Equal(t, tt.expectTruncated, inventory.Truncated)
+		})
+	}
+}
+
+func TestGetBucketInventory_CloudWatch(t *testing.T) {
+	storageMetric := func(storageType string) cwtypes.Metric {
+		return cwtypes.Metric{Dimensions: []cwtypes.Dimension{
+			{Name: aws.String("BucketName"), Value: aws.String("bucket")},
+			{Name: aws.String("StorageType"), Value: aws.String(storageType)},
+		}}
+	}
+
+	cwClient := new(mockCloudWatchClient)
+	cwClient.On("ListMetrics", mock.Anything, mock.Anything).Return(&cloudwatch.ListMetricsOutput{
+		Metrics: []cwtypes.Metric{
+			storageMetric("StandardStorage"),
+			storageMetric("IntelligentTieringFAStorage"),
+			storageMetric("IntelligentTieringIAStorage"),
+		},
+	}, nil)
+	cwClient.On("GetMetricData", mock.Anything, mock.Anything).Return(&cloudwatch.GetMetricDataOutput{
+		MetricDataResults: []cwtypes.MetricDataResult{
+			{Id: aws.String("objects"), Values: []float64{42, 40}},
+			{Id: aws.String("size0"), Values: []float64{1000}},
+			{Id: aws.String("size1"), Values: []float64{200}},
+			{Id: aws.String("size2"), Values: []float64{300}},
+		},
+	}, nil)
+	s3Client := new(mockS3Client)
+
+	inventory, err := GetBucketInventory(s3Client, cwClient, "bucket", 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.InventorySourceCloudWatch, inventory.Source)
+	assert.Equal(t, int64(42), inventory.ObjectCount)
+	assert.Equal(t, int64(1500), inventory.TotalSize)
+	assert.Equal(t, map[string]models.StorageClassUsage{
+		"STANDARD":            {Size: 1000},
+		"INTELLIGENT_TIERING": {Size: 500},
+	}, inventory.StorageClasses)
+	s3Client.AssertNotCalled(t, "ListObjectsV2", mock.Anything, mock.Anything)
+}
+
+func TestGetBucketInventory_NoMetrics(t *testing.T) {
+	cwClient := new(mockCloudWatchClient)
+	cwClient.On("ListMetrics", mock.Anything, mock.Anything).Return(&cloudwatch.ListMetricsOutput{}, nil)
+	s3Client := new(mockS3Client)
+	s3Client.On("ListObjectsV2", mock.Anything, mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String("a"), Size: aws.Int64(7)}},
+	}, nil)
+
+	inventory, err := GetBucketInventory(s3Client, cwClient, "bucket", 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, models.InventorySourceListing, inventory.Source)
+	assert.Equal(t, int64(1), inventory.ObjectCount)
+	assert.Equal(t, int64(7), inventory.TotalSize)
+	cwClient.AssertNotCalled(t, "GetMetricData", mock.Anything, mock.Anything)
+}
@@ -0,0 +1,39 @@
+package awsutils
+
+import (
+	"context"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// KMSClientAPI defines the interface for KMS operations we use
+type KMSClientAPI interface {
+	DescribeKey(ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error)
+}
+
+// awsManagedS3KeyAlias is the alias of the AWS managed key for S3
+const awsManagedS3KeyAlias = "alias/aws/s3"
+
+// GetKMSKeyManager reports whether a key is AWS managed or customer managed.
+// keyID may be a key ID, key ARN, alias name or alias ARN; an empty keyID is
+// the AWS managed key S3 uses by default. Without a client, or when the key
+// cannot be described, only the AWS managed alias can be recognised.
+func GetKMSKeyManager(client KMSClientAPI, keyID string) (string, string) {
+	if keyID == "" || strings.HasSuffix(keyID, awsManagedS3KeyAlias) {
+		return models.KeyManagerAWS, keyID
+	}
+	if client == nil {
+		return models.KeyManagerUnknown, keyID
+	}
+
+	output, err := client.DescribeKey(context.Background(), &kms.DescribeKeyInput{
+		KeyId: aws.String(keyID),
+	})
+	if err != nil || output.KeyMetadata == nil {
+		return models.KeyManagerUnknown, keyID
+	}
+	return string(output.KeyMetadata.KeyManager), aws.ToString(output.KeyMetadata.Arn)
+}
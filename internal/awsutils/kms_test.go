@@ -0,0 +1,140 @@
+package awsutils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
+	kmstypes "github.com/aws/aws-sdk-go-v2/service/kms/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockKMSClient struct {
+	mock.Mock
+}
+
+func (m *mockKMSClient) DescribeKey(ctx context.Context, params *kms.DescribeKeyInput, optFns ...func(*kms.Options)) (*kms.DescribeKeyOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*kms.DescribeKeyOutput), args.Error(1)
+}
+
+const customerKeyARN = "arn:aws:kms:us-east-1:111111111111:key/1234abcd-12ab-34cd-56ef-1234567890ab"
+
+func TestCheckBucketEncryption(t *testing.T) {
+	tests := []struct {
+		name            string
+		rules           []types.ServerSideEncryptionRule
+		policy          string
+		expectedManager string
+		expectedDenies  bool
+		expectedChecks  []string
+	}{
+		{
+			name: "Customer managed key with bucket key",
+			rules: []types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+					SSEAlgorithm:   types.ServerSideEncryptionAwsKms,
+					KMSMasterKeyID: aws.String(customerKeyARN),
+				},
+				BucketKeyEnabled: aws.Bool(true),
+			}},
+			expectedManager: models.KeyManagerCustomer,
+		},
+		{
+			name: "AWS managed key without bucket key",
+			rules: []types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+					SSEAlgorithm: types.ServerSideEncryptionAwsKms,
+				},
+			}},
+			expectedManager: models.KeyManagerAWS,
+			expectedChecks:  []string{CheckIDEncryptionAWSKey, CheckIDEncryptionBucketKey},
+		},
+		{
+			name: "DSSE-KMS with policy denying unencrypted uploads",
+			rules: []types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+					SSEAlgorithm:   types.ServerSideEncryptionAwsKmsDsse,
+					KMSMasterKeyID: aws.String(customerKeyARN),
+				},
+				BucketKeyEnabled: aws.Bool(true),
+			}},
+			policy: `{"Statement": {"Effect": "Deny", "Principal": "*", "Action": "s3:PutObject",
+				"Resource": "arn:aws:s3:::data/*",
+				"Condition": {"Null": {"s3:x-amz-server-side-encryption": "true"}}}}`,
+			expectedManager: models.KeyManagerCustomer,
+			expectedDenies:  true,
+		},
+		{
+			name: "DSSE-KMS without bucket key",
+			rules: []types.ServerSideEncryptionRule{{
+				ApplyServerSideEncryptionByDefault: &types.ServerSideEncryptionByDefault{
+					SSEAlgorithm:   types.ServerSideEncryptionAwsKmsDsse,
+					KMSMasterKeyID: aws.String(customerKeyARN),
+				},
+			}},
+			expectedManager: models.KeyManagerCustomer,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s3Client := new(mockS3Client)
+			s3Client.On("GetBucketEncryption", mock.Anything, mock.Anything).Return(&s3.GetBucketEncryptionOutput{
+				ServerSideEncryptionConfiguration: &types.ServerSideEncryptionConfiguration{Rules: tt.rules},
+			}, nil)
+			if tt.policy == "" {
+				s3Client.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{}, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy"})
+			} else {
+				s3Client.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{Policy: aws.String(tt.policy)}, nil)
+			}
+			kmsClient := new(mockKMSClient)
+			kmsClient.On("DescribeKey", mock.Anything, mock.Anything).Return(&kms.DescribeKeyOutput{
+				KeyMetadata: &kmstypes.KeyMetadata{Arn: aws.String(customerKeyARN), KeyManager: kmstypes.KeyManagerTypeCustomer},
+			}, nil)
+
+			document, err := ReadBucketPolicy(s3Client, "data")
+			assert.NoError(t, err)
+			status, findings, err := CheckBucketEncryption(s3Client, kmsClient, "data", &document)
+
+			assert.NoError(t, err)
+			assert.True(t, status.Configured)
+			if assert.Len(t, status.Rules, 1) {
+				assert.Equal(t, tt.expectedManager, status.Rules[0].KeyManager)
+				assert.Equal(t, tt.rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm == types.ServerSideEncryptionAwsKmsDsse, status.Rules[0].DualLayer)
+			}
+			if assert.NotNil(t, status.DeniesUnencryptedUploads) {
+				assert.Equal(t, tt.expectedDenies, *status.DeniesUnencryptedUploads)
+			}
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
+
+func TestGetKMSKeyManager(t *testing.T) {
+	kmsClient := new(mockKMSClient)
+	kmsClient.On("DescribeKey", mock.Anything, mock.Anything).Return(
+		&kms.DescribeKeyOutput{}, &smithy.GenericAPIError{Code: "AccessDeniedException"})
+
+	manager, _ := GetKMSKeyManager(kmsClient, "alias/aws/s3")
+	assert.Equal(t, models.KeyManagerAWS, manager)
+
+	manager, key := GetKMSKeyManager(kmsClient, customerKeyARN)
+	assert.Equal(t, models.KeyManagerUnknown, manager)
+	assert.Equal(t, customerKeyARN, key)
+
+	manager, _ = GetKMSKeyManager(nil, customerKeyARN)
+	assert.Equal(t, models.KeyManagerUnknown, manager)
+}
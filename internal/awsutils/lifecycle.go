@@ -0,0 +1,154 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Check IDs of the findings raised by the lifecycle check
+const (
+	CheckIDLifecycleNoncurrent = "lifecycle.noncurrent-expiration"
+	CheckIDLifecycleUploads    = "lifecycle.incomplete-uploads"
+)
+
+// GetBucketLifecycle reads the lifecycle rules of the bucket. A bucket
+// without a lifecycle configuration is reported as not configured.
+func GetBucketLifecycle(s3Client S3ClientAPI, bucketName string) (models.LifecycleStatus, error) {
+	var status models.LifecycleStatus
+
+	lifecycleOutput, err := s3Client.GetBucketLifecycleConfiguration(context.Background(), &s3.GetBucketLifecycleConfigurationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if isAPIError(err, "NoSuchLifecycleConfiguration") {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	for _, rule := range lifecycleOutput.Rules {
+		r := models.LifecycleRule{
+			ID:      aws.ToString(rule.ID),
+			Enabled: rule.Status == types.ExpirationStatusEnabled,
+			Scope:   lifecycleScope(rule),
+		}
+		if rule.Expiration != nil {
+			r.ExpirationDays = aws.ToInt32(rule.Expiration.Days)
+			if rule.Expiration.Date != nil {
+				r.ExpirationDate = rule.Expiration.Date.UTC().Format(time.DateOnly)
+			}
+			r.ExpiresDeleteMarkers = aws.ToBool(rule.Expiration.ExpiredObjectDeleteMarker)
+		}
+		if rule.NoncurrentVersionExpiration != nil {
+			r.NoncurrentExpirationDays = aws.ToInt32(rule.NoncurrentVersionExpiration.NoncurrentDays)
+		}
+		if rule.AbortIncompleteMultipartUpload != nil {
+			r.AbortIncompleteUploadDays = aws.ToInt32(rule.AbortIncompleteMultipartUpload.DaysAfterInitiation)
+		}
+		for _, t := range rule.Transitions {
+			transition := models.LifecycleTransition{
+				StorageClass: string(t.StorageClass),
+				Days:         aws.ToInt32(t.Days),
+			}
+			if t.Date != nil {
+				transition.Date = t.Date.UTC().Format(time.DateOnly)
+			}
+			r.Transitions = append(r.Transitions, transition)
+		}
+		for _, t := range rule.NoncurrentVersionTransitions {
+			r.NoncurrentTransitions = append(r.NoncurrentTransitions, models.LifecycleTransition{
+				StorageClass: string(t.StorageClass),
+				Days:         aws.ToInt32(t.NoncurrentDays),
+			})
+		}
+		status.Rules = append(status.Rules, r)
+	}
+	status.Configured = len(status.Rules) > 0
+	return status, nil
+}
+
+// CheckBucketLifecycle reads the lifecycle rules and returns findings for
+// incomplete multipart uploads that are never aborted and, when the bucket
+// is versioned, noncurrent versions that are never expired
+func CheckBucketLifecycle(s3Client S3ClientAPI, bucketName string, versioned bool) (models.LifecycleStatus, []models.Finding, error) {
+	status, err := GetBucketLifecycle(s3Client, bucketName)
+	if err != nil {
+		return status, nil, err
+	}
+
+	evidence := "No lifecycle configuration"
+	if status.Configured {
+		evidence = fmt.Sprintf("None of the %d lifecycle rule(s) set the action", len(status.Rules))
+	}
+
+	var findings []models.Finding
+	if versioned && !status.ExpiresNoncurrentVersions() {
+		title, noncurrentEvidence := "Noncurrent object versions are never expired", evidence
+		if scopes := status.ScopedNoncurrentExpirations(); len(scopes) > 0 {
+			title = "Noncurrent object versions are only expired in part of the bucket"
+			noncurrentEvidence = "NoncurrentVersionExpiration only applies to " + strings.Join(scopes, "; ")
+		}
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDLifecycleNoncurrent,
+			Severity:    models.SeverityLow,
+			Title:       title,
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{"Versioning is enabled or suspended", noncurrentEvidence},
+			Remediation: "Add a NoncurrentVersionExpiration rule so overwritten and deleted versions do not accumulate storage costs",
+		})
+	}
+	if !status.AbortsIncompleteUploads() {
+		title, uploadEvidence := "Incomplete multipart uploads are never aborted", []string{evidence}
+		if scopes := status.ScopedUploadAborts(); len(scopes) > 0 {
+			title = "Incomplete multipart uploads are only aborted in part of the bucket"
+			uploadEvidence = []string{"AbortIncompleteMultipartUpload only applies to " + strings.Join(scopes, "; ")}
+		}
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDLifecycleUploads,
+			Severity:    models.SeverityLow,
+			Title:       title,
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    uploadEvidence,
+			Remediation: "Add an AbortIncompleteMultipartUpload rule, e.g. after 7 days, to remove orphaned upload parts",
+		})
+	}
+
+	return status, findings, nil
+}
+
+// lifecycleScope describes which objects a rule applies to
+func lifecycleScope(rule types.LifecycleRule) string {
+	var parts []string
+	switch filter := rule.Filter.(type) {
+	case *types.LifecycleRuleFilterMemberPrefix:
+		if filter.Value != "" {
+			parts = append(parts, "prefix "+filter.Value)
+		}
+	case *types.LifecycleRuleFilterMemberTag:
+		parts = append(parts, fmt.Sprintf("tag %s=%s", aws.ToString(filter.Value.Key), aws.ToString(filter.Value.Value)))
+	case *types.LifecycleRuleFilterMemberAnd:
+		if prefix := aws.ToString(filter.Value.Prefix); prefix != "" {
+			parts = append(parts, "prefix "+prefix)
+		}
+		for _, tag := range filter.Value.Tags {
+			parts = append(parts, fmt.Sprintf("tag %s=%s", aws.ToString(tag.Key), aws.ToString(tag.Value)))
+		}
+	case *types.LifecycleRuleFilterMemberObjectSizeGreaterThan:
+		parts = append(parts, fmt.Sprintf("larger than %d bytes", filter.Value))
+	case *types.LifecycleRuleFilterMemberObjectSizeLessThan:
+		parts = append(parts, fmt.Sprintf("smaller than %d bytes", filter.Value))
+	case nil:
+		// Rules created with the legacy API carry the prefix on the rule
+		if prefix := aws.ToString(rule.Prefix); prefix != "" {
+			parts = append(parts, "prefix "+prefix)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
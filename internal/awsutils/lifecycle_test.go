@@ -0,0 +1,167 @@
+package awsutils
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckBucketLifecycle(t *testing.T) {
+	archiveRule := types.LifecycleRule{
+		ID:     aws.String("archive"),
+		Status: types.ExpirationStatusEnabled,
+		Filter: &types.LifecycleRuleFilterMemberPrefix{Value: "logs/"},
+		Transitions: []types.Transition{
+			{StorageClass: types.TransitionStorageClassGlacier, Days: aws.Int32(30)},
+			{StorageClass: types.TransitionStorageClassDeepArchive, Days: aws.Int32(180)},
+		},
+	}
+	cleanupRule := types.LifecycleRule{
+		ID:                             aws.String("cleanup"),
+		Status:                         types.ExpirationStatusEnabled,
+		NoncurrentVersionExpiration:    &types.NoncurrentVersionExpiration{NoncurrentDays: aws.Int32(30)},
+		AbortIncompleteMultipartUpload: &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int32(7)},
+	}
+	disabledCleanup := cleanupRule
+	disabledCleanup.Status = types.ExpirationStatusDisabled
+	scopedCleanup := cleanupRule
+	scopedCleanup.Filter = &types.LifecycleRuleFilterMemberPrefix{Value: "uploads/"}
+	scopedExpiry := types.LifecycleRule{
+		ID:                          aws.String("expiry"),
+		Status:                      types.ExpirationStatusEnabled,
+		Filter:                      &types.LifecycleRuleFilterMemberPrefix{Value: "tmp/"},
+		NoncurrentVersionExpiration: &types.NoncurrentVersionExpiration{NoncurrentDays: aws.Int32(30)},
+	}
+	abortRule := types.LifecycleRule{
+		ID:                             aws.String("abort"),
+		Status:                         types.ExpirationStatusEnabled,
+		AbortIncompleteMultipartUpload: &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int32(7)},
+	}
+
+	tests := []struct {
+		name                string
+		rules               []types.LifecycleRule
+		versioned           bool
+		expectedTransitions []string
+		expectedChecks      []string
+		// expectedEvidence is checked for the last finding when set
+		expectedEvidence []string
+	}{
+		{
+			name:           "No configuration on a versioned bucket",
+			versioned:      true,
+			expectedChecks: []string{CheckIDLifecycleNoncurrent, CheckIDLifecycleUploads},
+		},
+		{
+			name:           "No configuration on an unversioned bucket",
+			expectedChecks: []string{CheckIDLifecycleUploads},
+		},
+		{
+			name:                "Versioned bucket with archive and cleanup rules",
+			rules:               []types.LifecycleRule{archiveRule, cleanupRule},
+			versioned:           true,
+			expectedTransitions: []string{"GLACIER after 30 day(s)", "DEEP_ARCHIVE after 180 day(s)"},
+		},
+		{
+			name:             "Rules scoped to a prefix do not cover the bucket",
+			rules:            []types.LifecycleRule{scopedCleanup},
+			expectedChecks:   []string{CheckIDLifecycleUploads},
+			expectedEvidence: []string{"AbortIncompleteMultipartUpload only applies to prefix uploads/"},
+		},
+		{
+			name:             "Noncurrent expiry scoped to a prefix does not cover a versioned bucket",
+			rules:            []types.LifecycleRule{scopedExpiry, abortRule},
+			versioned:        true,
+			expectedChecks:   []string{CheckIDLifecycleNoncurrent},
+			expectedEvidence: []string{"Versioning is enabled or suspended", "NoncurrentVersionExpiration only applies to prefix tmp/"},
+		},
+		{
+			name:           "Disabled rules do not count",
+			rules:          []types.LifecycleRule{disabledCleanup},
+			versioned:      true,
+			expectedChecks: []string{CheckIDLifecycleNoncurrent, CheckIDLifecycleUploads},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			if tt.rules == nil {
+				mockClient.On("GetBucketLifecycleConfiguration", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketLifecycleConfigurationOutput{}, &smithy.GenericAPIError{Code: "NoSuchLifecycleConfiguration"})
+			} else {
+				mockClient.On("GetBucketLifecycleConfiguration", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketLifecycleConfigurationOutput{Rules: tt.rules}, nil)
+			}
+
+			status, findings, err := CheckBucketLifecycle(mockClient, "bucket", tt.versioned)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.rules != nil, status.Configured)
+			assert.Equal(t, tt.expectedTransitions, status.ArchiveTransitions())
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+			if tt.expectedEvidence != nil {
+				assert.Equal(t, tt.expectedEvidence, findings[len(findings)-1].Evidence)
+			}
+		})
+	}
+}
+
+func TestLifecycleRuleSummary(t *testing.T) {
+	mockClient := new(mockS3Client)
+	mockClient.On("GetBucketLifecycleConfiguration", mock.Anything, mock.Anything).Return(
+		&s3.GetBucketLifecycleConfigurationOutput{Rules: []types.LifecycleRule{{
+			ID:     aws.String("logs"),
+			Status: types.ExpirationStatusEnabled,
+			Filter: &types.LifecycleRuleFilterMemberAnd{Value: types.LifecycleRuleAndOperator{
+				Prefix: aws.String("logs/"),
+				Tags:   []types.Tag{{Key: aws.String("tier"), Value: aws.String("cold")}},
+			}},
+			Expiration:                     &types.LifecycleExpiration{Days: aws.Int32(365)},
+			AbortIncompleteMultipartUpload: &types.AbortIncompleteMultipartUpload{DaysAfterInitiation: aws.Int32(7)},
+		}}}, nil)
+
+	status, err := GetBucketLifecycle(mockClient, "bucket")
+
+	assert.NoError(t, err)
+	assert.Len(t, status.Rules, 1)
+	assert.Equal(t, "logs (prefix logs/, tag tier=cold): expire after 365d, abort uploads after 7d", status.Rules[0].Summary())
+}
+
+func TestLifecycleRuleSummary_Dates(t *testing.T) {
+	date := time.Date(2030, time.January, 1, 0, 0, 0, 0, time.UTC)
+	mockClient := new(mockS3Client)
+	mockClient.On("GetBucketLifecycleConfiguration", mock.Anything, mock.Anything).Return(
+		&s3.GetBucketLifecycleConfigurationOutput{Rules: []types.LifecycleRule{
+			{
+				ID:          aws.String("retire"),
+				Status:      types.ExpirationStatusEnabled,
+				Filter:      &types.LifecycleRuleFilterMemberPrefix{},
+				Expiration:  &types.LifecycleExpiration{Date: aws.Time(date)},
+				Transitions: []types.Transition{{StorageClass: types.TransitionStorageClassGlacier, Date: aws.Time(date.AddDate(-1, 0, 0))}},
+			},
+			{
+				ID:         aws.String("markers"),
+				Status:     types.ExpirationStatusEnabled,
+				Expiration: &types.LifecycleExpiration{ExpiredObjectDeleteMarker: aws.Bool(true)},
+			},
+		}}, nil)
+
+	status, err := GetBucketLifecycle(mockClient, "bucket")
+
+	assert.NoError(t, err)
+	assert.Len(t, status.Rules, 2)
+	assert.Equal(t, "retire: expire on 2030-01-01, GLACIER on 2029-01-01", status.Rules[0].Summary())
+	assert.Equal(t, "markers: remove expired delete markers", status.Rules[1].Summary())
+	assert.Equal(t, []string{"GLACIER on 2029-01-01"}, status.ArchiveTransitions())
+}
@@ -0,0 +1,122 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Check IDs of the findings raised by the logging check
+const (
+	CheckIDLoggingDisabled   = "logging.disabled"
+	CheckIDLoggingSelfTarget = "logging.self-target"
+	CheckIDLoggingNoTarget   = "logging.missing-target"
+	CheckIDLoggingDataEvents = "logging.cloudtrail-data-events"
+	CheckIDLoggingDenied     = "logging.denied"
+)
+
+// CheckBucketLogging reads the server access logging configuration and
+// returns findings for disabled logging, self-logging loops and missing
+// target buckets. A denied read of the configuration is reported through a
+// finding rather than an error. When trails is not nil, it is also checked
+// for CloudTrail data events that cover the bucket.
+func CheckBucketLogging(s3Client S3ClientAPI, bucketName string, trails []TrailDataEvents) (models.LoggingStatus, []models.Finding, error) {
+	var status models.LoggingStatus
+	var findings []models.Finding
+
+	loggingOutput, err := s3Client.GetBucketLogging(context.Background(), &s3.GetBucketLoggingInput{
+		Bucket: aws.String(bucketName),
+	})
+	if IsAccessDenied(err) {
+		status.Denied = true
+	} else if err != nil {
+		return status, nil, err
+	}
+
+	if err == nil {
+		if le := loggingOutput.LoggingEnabled; le != nil && aws.ToString(le.TargetBucket) != "" {
+			status.Enabled = true
+			status.TargetBucket = aws.ToString(le.TargetBucket)
+			status.TargetPrefix = aws.ToString(le.TargetPrefix)
+		}
+	}
+
+	target := fmt.Sprintf("Target: s3://%s/%s", status.TargetBucket, status.TargetPrefix)
+	switch {
+	case status.Denied:
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDLoggingDenied,
+			Severity:    models.SeverityLow,
+			Title:       "Server access logging could not be checked",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{"GetBucketLogging was denied"},
+			Remediation: "Grant s3:GetBucketLogging to the auditing principal",
+		})
+	case !status.Enabled:
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDLoggingDisabled,
+			Severity:    models.SeverityMedium,
+			Title:       "Server access logging is not enabled",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{"GetBucketLogging returned no LoggingEnabled configuration"},
+			Remediation: "Enable server access logging to a dedicated log bucket",
+		})
+	case status.TargetBucket == bucketName:
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDLoggingSelfTarget,
+			Severity:    models.SeverityMedium,
+			Title:       "Bucket delivers access logs to itself",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{target, "Every log delivery is itself logged, growing the bucket indefinitely"},
+			Remediation: "Send access logs to a separate bucket",
+		})
+	default:
+		_, err := s3Client.GetBucketLocation(context.Background(), &s3.GetBucketLocationInput{
+			Bucket: aws.String(status.TargetBucket),
+		})
+		// A target owned by another account may deny the lookup, which says
+		// nothing about whether it exists
+		if isAPIError(err, "NoSuchBucket") {
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDLoggingNoTarget,
+				Severity:    models.SeverityHigh,
+				Title:       "Access log target bucket does not exist",
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    []string{target},
+				Remediation: "Point logging at an existing bucket; logs are currently being dropped",
+			})
+		}
+	}
+
+	if trails != nil {
+		status.DataEventsChecked = true
+		for _, trail := range trails {
+			switch reads, writes := trail.Coverage(bucketName); {
+			case writes:
+				status.DataEventTrails = append(status.DataEventTrails, trail.Name)
+			case reads:
+				status.ReadOnlyDataEventTrails = append(status.ReadOnlyDataEventTrails, trail.Name)
+			}
+		}
+		if len(status.DataEventTrails) == 0 {
+			evidence := []string{fmt.Sprintf("%d trail(s) record S3 data events, none cover this bucket", len(trails))}
+			if len(status.ReadOnlyDataEventTrails) > 0 {
+				evidence = []string{fmt.Sprintf("Trail(s) %s only record ReadOnly data events for this bucket", strings.Join(status.ReadOnlyDataEventTrails, ", "))}
+			}
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDLoggingDataEvents,
+				Severity:    models.SeverityLow,
+				Title:       "No CloudTrail trail records S3 write data events for the bucket",
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    evidence,
+				Remediation: "Add an S3 data event selector recording write events, or all events, for the bucket to a CloudTrail trail",
+			})
+		}
+	}
+
+	return status, findings, nil
+}
@@ -0,0 +1,240 @@
+package awsutils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	cloudtrailtypes "github.com/aws/aws-sdk-go-v2/service/cloudtrail/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockCloudTrailClient struct {
+	mock.Mock
+}
+
+func (m *mockCloudTrailClient) DescribeTrails(ctx context.Context, params *cloudtrail.DescribeTrailsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.DescribeTrailsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudtrail.DescribeTrailsOutput), args.Error(1)
+}
+
+func (m *mockCloudTrailClient) GetEventSelectors(ctx context.Context, params *cloudtrail.GetEventSelectorsInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetEventSelectorsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudtrail.GetEventSelectorsOutput), args.Error(1)
+}
+
+func (m *mockCloudTrailClient) GetTrailStatus(ctx context.Context, params *cloudtrail.GetTrailStatusInput, optFns ...func(*cloudtrail.Options)) (*cloudtrail.GetTrailStatusOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*cloudtrail.GetTrailStatusOutput), args.Error(1)
+}
+
+func TestCheckBucketLogging(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockSetup      func(*mockS3Client)
+		expectedTarget string
+		expectedChecks []string
+	}{
+		{
+			name: "Logging disabled",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketLogging", mock.Anything, mock.Anything).Return(&s3.GetBucketLoggingOutput{}, nil)
+			},
+			expectedChecks: []string{CheckIDLoggingDisabled},
+		},
+		{
+			name: "Bucket logs to itself",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketLogging", mock.Anything, mock.Anything).Return(&s3.GetBucketLoggingOutput{
+					LoggingEnabled: &types.LoggingEnabled{TargetBucket: aws.String("data"), TargetPrefix: aws.String("logs/")},
+				}, nil)
+			},
+			expectedTarget: "data",
+			expectedChecks: []string{CheckIDLoggingSelfTarget},
+		},
+		{
+			name: "Target bucket is missing",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketLogging", mock.Anything, mock.Anything).Return(&s3.GetBucketLoggingOutput{
+					LoggingEnabled: &types.LoggingEnabled{TargetBucket: aws.String("deleted-logs"), TargetPrefix: aws.String("")},
+				}, nil)
+				m.On("GetBucketLocation", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketLocationOutput{}, &types.NoSuchBucket{})
+			},
+			expectedTarget: "deleted-logs",
+			expectedChecks: []string{CheckIDLoggingNoTarget},
+		},
+		{
+			name: "Logging to an existing bucket",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketLogging", mock.Anything, mock.Anything).Return(&s3.GetBucketLoggingOutput{
+					LoggingEnabled: &types.LoggingEnabled{TargetBucket: aws.String("central-logs"), TargetPrefix: aws.String("data/")},
+				}, nil)
+				m.On("GetBucketLocation", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketLocationOutput{LocationConstraint: "eu-west-1"}, nil)
+			},
+			expectedTarget: "central-logs",
+		},
+		{
+			name: "Access denied",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketLogging", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketLoggingOutput{}, &smithy.GenericAPIError{Code: "AccessDenied"})
+			},
+			expectedChecks: []string{CheckIDLoggingDenied},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			tt.mockSetup(mockClient)
+
+			status, findings, err := CheckBucketLogging(mockClient, "data", nil)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedTarget, status.TargetBucket)
+			assert.False(t, status.DataEventsChecked)
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
+
+func TestGetS3DataEventTrails(t *testing.T) {
+	mockClient := new(mockCloudTrailClient)
+	mockClient.On("DescribeTrails", mock.Anything, mock.Anything).Return(&cloudtrail.DescribeTrailsOutput{
+		TrailList: []cloudtrailtypes.Trail{
+			{Name: aws.String("all-buckets"), TrailARN: aws.String("arn:trail/all-buckets")},
+			{Name: aws.String("one-bucket"), TrailARN: aws.String("arn:trail/one-bucket")},
+			{Name: aws.String("advanced"), TrailARN: aws.String("arn:trail/advanced")},
+			{Name: aws.String("management-only"), TrailARN: aws.String("arn:trail/management-only")},
+			{Name: aws.String("reads-only"), TrailARN: aws.String("arn:trail/reads-only")},
+			{Name: aws.String("advanced-writes"), TrailARN: aws.String("arn:trail/advanced-writes")},
+			{Name: aws.String("single-objects"), TrailARN: aws.String("arn:trail/single-objects")},
+			{Name: aws.String("part-excluded"), TrailARN: aws.String("arn:trail/part-excluded")},
+			{Name: aws.String("stopped"), TrailARN: aws.String("arn:trail/stopped")},
+		},
+	}, nil)
+	mockClient.On("GetTrailStatus", mock.Anything, mock.MatchedBy(func(in *cloudtrail.GetTrailStatusInput) bool {
+		return aws.ToString(in.Name) == "arn:trail/stopped"
+	})).Return(&cloudtrail.GetTrailStatusOutput{IsLogging: aws.Bool(false)}, nil)
+	mockClient.On("GetTrailStatus", mock.Anything, mock.Anything).Return(&cloudtrail.GetTrailStatusOutput{IsLogging: aws.Bool(true)}, nil)
+	forTrail := func(arn string) interface{} {
+		return mock.MatchedBy(func(in *cloudtrail.GetEventSelectorsInput) bool {
+			return aws.ToString(in.TrailName) == arn
+		})
+	}
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/all-buckets")).Return(&cloudtrail.GetEventSelectorsOutput{
+		EventSelectors: []cloudtrailtypes.EventSelector{{
+			DataResources: []cloudtrailtypes.DataResource{{Type: aws.String("AWS::S3::Object"), Values: []string{"arn:aws:s3"}}},
+		}},
+	}, nil)
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/one-bucket")).Return(&cloudtrail.GetEventSelectorsOutput{
+		EventSelectors: []cloudtrailtypes.EventSelector{{
+			DataResources: []cloudtrailtypes.DataResource{{Type: aws.String("AWS::S3::Object"), Values: []string{"arn:aws:s3:::audited/"}}},
+		}},
+	}, nil)
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/advanced")).Return(&cloudtrail.GetEventSelectorsOutput{
+		AdvancedEventSelectors: []cloudtrailtypes.AdvancedEventSelector{{
+			FieldSelectors: []cloudtrailtypes.AdvancedFieldSelector{
+				{Field: aws.String("eventCategory"), Equals: []string{"Data"}},
+				{Field: aws.String("resources.type"), Equals: []string{"AWS::S3::Object"}},
+				{Field: aws.String("resources.ARN"), NotStartsWith: []string{"arn:aws:s3:::audited/"}},
+			},
+		}},
+	}, nil)
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/management-only")).Return(&cloudtrail.GetEventSelectorsOutput{
+		EventSelectors: []cloudtrailtypes.EventSelector{{IncludeManagementEvents: aws.Bool(true)}},
+	}, nil)
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/reads-only")).Return(&cloudtrail.GetEventSelectorsOutput{
+		EventSelectors: []cloudtrailtypes.EventSelector{{
+			ReadWriteType: cloudtrailtypes.ReadWriteTypeReadOnly,
+			DataResources: []cloudtrailtypes.DataResource{{Type: aws.String("AWS::S3::Object"), Values: []string{"arn:aws:s3:::audited/"}}},
+		}},
+	}, nil)
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/advanced-writes")).Return(&cloudtrail.GetEventSelectorsOutput{
+		AdvancedEventSelectors: []cloudtrailtypes.AdvancedEventSelector{{
+			FieldSelectors: []cloudtrailtypes.AdvancedFieldSelector{
+				{Field: aws.String("eventCategory"), Equals: []string{"Data"}},
+				{Field: aws.String("resources.type"), Equals: []string{"AWS::S3::Object"}},
+				{Field: aws.String("readOnly"), Equals: []string{"false"}},
+			},
+		}},
+	}, nil)
+	// Equals names exact objects, not every object under a prefix
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/single-objects")).Return(&cloudtrail.GetEventSelectorsOutput{
+		AdvancedEventSelectors: []cloudtrailtypes.AdvancedEventSelector{{
+			FieldSelectors: []cloudtrailtypes.AdvancedFieldSelector{
+				{Field: aws.String("eventCategory"), Equals: []string{"Data"}},
+				{Field: aws.String("resources.type"), Equals: []string{"AWS::S3::Object"}},
+				{Field: aws.String("resources.ARN"), Equals: []string{"arn:aws:s3:::audited/", "arn:aws:s3:::other/report.csv"}},
+			},
+		}},
+	}, nil)
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/part-excluded")).Return(&cloudtrail.GetEventSelectorsOutput{
+		AdvancedEventSelectors: []cloudtrailtypes.AdvancedEventSelector{{
+			FieldSelectors: []cloudtrailtypes.AdvancedFieldSelector{
+				{Field: aws.String("eventCategory"), Equals: []string{"Data"}},
+				{Field: aws.String("resources.type"), Equals: []string{"AWS::S3::Object"}},
+				{Field: aws.String("resources.ARN"), NotStartsWith: []string{"arn:aws:s3:::audited/logs/"}},
+			},
+		}},
+	}, nil)
+	mockClient.On("GetEventSelectors", mock.Anything, forTrail("arn:trail/stopped")).Return(&cloudtrail.GetEventSelectorsOutput{
+		EventSelectors: []cloudtrailtypes.EventSelector{{
+			DataResources: []cloudtrailtypes.DataResource{{Type: aws.String("AWS::S3::Object"), Values: []string{"arn:aws:s3"}}},
+		}},
+	}, nil)
+
+	trails, err := GetS3DataEventTrails(mockClient)
+	assert.NoError(t, err)
+	// The stopped trail records nothing, whatever its selectors say
+	assert.Len(t, trails, 7)
+
+	coverage := map[string][]bool{}
+	for _, trail := range trails {
+		coverage[trail.Name] = []bool{trail.Covers("audited"), trail.Covers("other")}
+	}
+	assert.Equal(t, map[string][]bool{
+		"all-buckets":     {true, true},
+		"one-bucket":      {true, false},
+		"advanced":        {false, true},
+		"reads-only":      {false, false},
+		"advanced-writes": {true, true},
+		"single-objects":  {false, false},
+		"part-excluded":   {false, true},
+	}, coverage)
+	mockClient.AssertNotCalled(t, "GetEventSelectors", mock.Anything, forTrail("arn:trail/stopped"))
+
+	reads, writes := trails[3].Coverage("audited")
+	assert.True(t, reads)
+	assert.False(t, writes)
+	reads, writes = trails[4].Coverage("audited")
+	assert.False(t, reads)
+	assert.True(t, writes)
+
+	s3Client := new(mockS3Client)
+	s3Client.On("GetBucketLogging", mock.Anything, mock.Anything).Return(&s3.GetBucketLoggingOutput{}, nil)
+	status, findings, err := CheckBucketLogging(s3Client, "unlisted", trails[1:2])
+	assert.NoError(t, err)
+	assert.True(t, status.DataEventsChecked)
+	assert.Empty(t, status.DataEventTrails)
+	assert.Equal(t, CheckIDLoggingDataEvents, findings[len(findings)-1].CheckID)
+
+	// A trail recording only reads leaves changes to the objects unrecorded
+	status, findings, err = CheckBucketLogging(s3Client, "audited", trails[3:4])
+	assert.NoError(t, err)
+	assert.Empty(t, status.DataEventTrails)
+	assert.Equal(t, []string{"reads-only"}, status.ReadOnlyDataEventTrails)
+	assert.Equal(t, CheckIDLoggingDataEvents, findings[len(findings)-1].CheckID)
+	assert.Equal(t, []string{"Trail(s) reads-only only record ReadOnly data events for this bucket"}, findings[len(findings)-1].Evidence)
+}
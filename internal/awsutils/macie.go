@@ -2,14 +2,492 @@ package awsutils
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/macie2"
+	"github.com/aws/aws-sdk-go-v2/service/macie2/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
 )
 
 // MacieClientAPI defines the interface for Macie operations we use
 type MacieClientAPI interface {
 	CreateClassificationJob(ctx context.Context, params *macie2.CreateClassificationJobInput, optFns ...func(*macie2.Options)) (*macie2.CreateClassificationJobOutput, error)
 	DescribeClassificationJob(ctx context.Context, params *macie2.DescribeClassificationJobInput, optFns ...func(*macie2.Options)) (*macie2.DescribeClassificationJobOutput, error)
+	UpdateClassificationJob(ctx context.Context, params *macie2.UpdateClassificationJobInput, optFns ...func(*macie2.Options)) (*macie2.UpdateClassificationJobOutput, error)
 	ListFindings(ctx context.Context, params *macie2.ListFindingsInput, optFns ...func(*macie2.Options)) (*macie2.ListFindingsOutput, error)
 	GetFindings(ctx context.Context, params *macie2.GetFindingsInput, optFns ...func(*macie2.Options)) (*macie2.GetFindingsOutput, error)
+	ListCustomDataIdentifiers(ctx context.Context, params *macie2.ListCustomDataIdentifiersInput, optFns ...func(*macie2.Options)) (*macie2.ListCustomDataIdentifiersOutput, error)
+	CreateCustomDataIdentifier(ctx context.Context, params *macie2.CreateCustomDataIdentifierInput, optFns ...func(*macie2.Options)) (*macie2.CreateCustomDataIdentifierOutput, error)
+	ListAllowLists(ctx context.Context, params *macie2.ListAllowListsInput, optFns ...func(*macie2.Options)) (*macie2.ListAllowListsOutput, error)
+	CreateAllowList(ctx context.Context, params *macie2.CreateAllowListInput, optFns ...func(*macie2.Options)) (*macie2.CreateAllowListOutput, error)
+}
+
+// MacieJobScope limits which objects a Macie classification job analyzes.
+// The zero value analyzes every object in the bucket.
+//
+// Macie joins the include conditions with AND, so an object must match each
+// of them, and likewise only excludes objects that match every exclude
+// condition. Multiple prefixes, extensions or tags within one condition
+// match any of the values.
+type MacieJobScope struct {
+	IncludePrefixes   []string
+	ExcludePrefixes   []string
+	IncludeExtensions []string
+	ExcludeExtensions []string
+	// MinSize and MaxSize bound the object size in bytes; zero means no bound
+	MinSize int64
+	MaxSize int64
+	// IncludeTags and ExcludeTags map object tag keys to values; an empty
+	// value matches any value of the key
+	IncludeTags map[string]string
+	ExcludeTags map[string]string
+	// SamplingPercentage is the share of matching objects to analyze; zero
+	// analyzes all of them
+	SamplingPercentage int32
+}
+
+// Validate reports options that Macie would reject
+func (s MacieJobScope) Validate() error {
+	if s.SamplingPercentage < 0 || s.SamplingPercentage > 100 {
+		return fmt.Errorf("sampling percentage must be between 1 and 100, got %d", s.SamplingPercentage)
+	}
+	if s.MinSize < 0 || s.MaxSize < 0 {
+		return fmt.Errorf("object size bounds cannot be negative")
+	}
+	if s.MaxSize > 0 && s.MinSize > s.MaxSize {
+		return fmt.Errorf("minimum object size %d is larger than maximum %d", s.MinSize, s.MaxSize)
+	}
+	return nil
+}
+
+// Scoping translates the scope into the Scoping block of a job definition.
+// It returns nil when the scope does not restrict any objects.
+func (s MacieJobScope) Scoping() *types.Scoping {
+	var includes, excludes []types.JobScopeTerm
+
+	if len(s.IncludePrefixes) > 0 {
+		includes = append(includes, simpleScopeTerm(types.ScopeFilterKeyObjectKey, types.JobComparatorStartsWith, s.IncludePrefixes...))
+	}
+	if len(s.IncludeExtensions) > 0 {
+		includes = append(includes, simpleScopeTerm(types.ScopeFilterKeyObjectExtension, types.JobComparatorEq, extensions(s.IncludeExtensions)...))
+	}
+	if s.MinSize > 0 {
+		includes = append(includes, simpleScopeTerm(types.ScopeFilterKeyObjectSize, types.JobComparatorGte, strconv.FormatInt(s.MinSize, 10)))
+	}
+	if s.MaxSize > 0 {
+		includes = append(includes, simpleScopeTerm(types.ScopeFilterKeyObjectSize, types.JobComparatorLte, strconv.FormatInt(s.MaxSize, 10)))
+	}
+	if len(s.IncludeTags) > 0 {
+		includes = append(includes, tagScopeTerm(s.IncludeTags))
+	}
+
+	if len(s.ExcludePrefixes) > 0 {
+		excludes = append(excludes, simpleScopeTerm(types.ScopeFilterKeyObjectKey, types.JobComparatorStartsWith, s.ExcludePrefixes...))
+	}
+	if len(s.ExcludeExtensions) > 0 {
+		excludes = append(excludes, simpleScopeTerm(types.ScopeFilterKeyObjectExtension, types.JobComparatorEq, extensions(s.ExcludeExtensions)...))
+	}
+	if len(s.ExcludeTags) > 0 {
+		excludes = append(excludes, tagScopeTerm(s.ExcludeTags))
+	}
+
+	if len(includes) == 0 && len(excludes) == 0 {
+		return nil
+	}
+	scoping := &types.Scoping{}
+	if len(includes) > 0 {
+		scoping.Includes = &types.JobScopingBlock{And: includes}
+	}
+	if len(excludes) > 0 {
+		scoping.Excludes = &types.JobScopingBlock{And: excludes}
+	}
+	return scoping
+}
+
+func simpleScopeTerm(key types.ScopeFilterKey, comparator types.JobComparator, values ...string) types.JobScopeTerm {
+	return types.JobScopeTerm{SimpleScopeTerm: &types.SimpleScopeTerm{
+		Key:        key,
+		Comparator: comparator,
+		Values:     values,
+	}}
+}
+
+func tagScopeTerm(tags map[string]string) types.JobScopeTerm {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]types.TagValuePair, 0, len(keys))
+	for _, key := range keys {
+		pairs = append(pairs, types.TagValuePair{Key: aws.String(key), Value: aws.String(tags[key])})
+	}
+	return types.JobScopeTerm{TagScopeTerm: &types.TagScopeTerm{
+		Comparator: types.JobComparatorEq,
+		Key:        aws.String("TAG"),
+		TagValues:  pairs,
+		Target:     types.TagTargetS3Object,
+	}}
+}
+
+// extensions strips the leading dot Macie does not expect on extensions
+func extensions(values []string) []string {
+	result := make([]string, len(values))
+	for i, v := range values {
+		result[i] = strings.TrimPrefix(v, ".")
+	}
+	return result
+}
+
+// MacieIdentifiers selects the managed and custom data identifiers and the
+// allow lists a Macie classification job uses. Custom data identifiers and
+// allow lists given by definition are created on first use and reused by
+// name afterwards.
+type MacieIdentifiers struct {
+	// ManagedDataIdentifierSelector is ALL, EXCLUDE, INCLUDE, NONE or
+	// RECOMMENDED; empty leaves Macie's default (RECOMMENDED)
+	ManagedDataIdentifierSelector string `json:"managedDataIdentifierSelector,omitempty"`
+	// ManagedDataIdentifierIds are the managed identifiers to include or
+	// exclude, e.g. CREDIT_CARD_NUMBER
+	ManagedDataIdentifierIds []string               `json:"managedDataIdentifierIds,omitempty"`
+	CustomDataIdentifiers    []CustomDataIdentifier `json:"customDataIdentifiers,omitempty"`
+	CustomDataIdentifierIds  []string               `json:"customDataIdentifierIds,omitempty"`
+	AllowLists               []AllowList            `json:"allowLists,omitempty"`
+	AllowListIds             []string               `json:"allowListIds,omitempty"`
+}
+
+// CustomDataIdentifier defines a Macie custom data identifier
+type CustomDataIdentifier struct {
+	Name        string   `json:"name"`
+	Description string   `json:"description,omitempty"`
+	Regex       string   `json:"regex"`
+	Keywords    []string `json:"keywords,omitempty"`
+	IgnoreWords []string `json:"ignoreWords,omitempty"`
+	// MaximumMatchDistance is how many characters may separate a keyword
+	// from a match; zero leaves Macie's default (50)
+	MaximumMatchDistance int32 `json:"maximumMatchDistance,omitempty"`
+}
+
+// AllowList defines a Macie allow list from either a regular expression or
+// a plain text file of words stored in S3
+type AllowList struct {
+	Name           string `json:"name"`
+	Description    string `json:"description,omitempty"`
+	Regex          string `json:"regex,omitempty"`
+	WordsBucket    string `json:"wordsBucket,omitempty"`
+	WordsObjectKey string `json:"wordsObjectKey,omitempty"`
+}
+
+// LoadMacieIdentifiers reads data identifier settings from a JSON file
+func LoadMacieIdentifiers(path string) (MacieIdentifiers, error) {
+	var identifiers MacieIdentifiers
+
+	f, err := os.Open(path)
+	if err != nil {
+		return identifiers, err
+	}
+	defer f.Close()
+
+	decoder := json.NewDecoder(f)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(&identifiers); err != nil {
+		return identifiers, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	identifiers.ManagedDataIdentifierSelector = strings.ToUpper(identifiers.ManagedDataIdentifierSelector)
+	if err := identifiers.Validate(); err != nil {
+		return identifiers, fmt.Errorf("invalid %s: %w", path, err)
+	}
+	return identifiers, nil
+}
+
+// Validate reports settings that Macie would reject
+func (m MacieIdentifiers) Validate() error {
+	selector := types.ManagedDataIdentifierSelector(m.ManagedDataIdentifierSelector)
+	if selector != "" && !slices.Contains(selector.Values(), selector) {
+		return fmt.Errorf("unknown managed data identifier selector %q", m.ManagedDataIdentifierSelector)
+	}
+	listsIDs := selector == types.ManagedDataIdentifierSelectorInclude || selector == types.ManagedDataIdentifierSelectorExclude
+	if listsIDs && len(m.ManagedDataIdentifierIds) == 0 {
+		return fmt.Errorf("managed data identifier selector %s needs managed data identifier IDs", selector)
+	}
+	if !listsIDs && len(m.ManagedDataIdentifierIds) > 0 {
+		return fmt.Errorf("managed data identifier IDs need the INCLUDE or EXCLUDE selector")
+	}
+
+	for _, identifier := range m.CustomDataIdentifiers {
+		if identifier.Name == "" || identifier.Regex == "" {
+			return fmt.Errorf("custom data identifiers need a name and a regex")
+		}
+		if identifier.MaximumMatchDistance < 0 || identifier.MaximumMatchDistance > 300 {
+			return fmt.Errorf("custom data identifier %s: maximum match distance must be between 1 and 300", identifier.Name)
+		}
+	}
+	for _, list := range m.AllowLists {
+		if list.Name == "" {
+			return fmt.Errorf("allow lists need a name")
+		}
+		regexAndWords := list.Regex != "" && (list.WordsBucket != "" || list.WordsObjectKey != "")
+		incompleteWords := list.Regex == "" && (list.WordsBucket == "" || list.WordsObjectKey == "")
+		if regexAndWords || incompleteWords {
+			return fmt.Errorf("allow list %s needs either a regex or a words bucket and object key", list.Name)
+		}
+	}
+	return nil
+}
+
+// ResolveMacieIdentifiers returns the IDs of the custom data identifiers and
+// allow lists to attach to a job. Definitions are matched to existing
+// resources by name and created when missing.
+func ResolveMacieIdentifiers(client MacieClientAPI, identifiers MacieIdentifiers) ([]string, []string, error) {
+	customIDs := slices.Clone(identifiers.CustomDataIdentifierIds)
+	if len(identifiers.CustomDataIdentifiers) > 0 {
+		existing := map[string]string{}
+		paginator := macie2.NewListCustomDataIdentifiersPaginator(client, &macie2.ListCustomDataIdentifiersInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(context.Background())
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to list custom data identifiers: %w", err)
+			}
+			for _, item := range page.Items {
+				existing[aws.ToString(item.Name)] = aws.ToString(item.Id)
+			}
+		}
+
+		for _, identifier := range identifiers.CustomDataIdentifiers {
+			if id, ok := existing[identifier.Name]; ok {
+				customIDs = append(customIDs, id)
+				continue
+			}
+			input := &macie2.CreateCustomDataIdentifierInput{
+				Name:        aws.String(identifier.Name),
+				Regex:       aws.String(identifier.Regex),
+				Keywords:    identifier.Keywords,
+				IgnoreWords: identifier.IgnoreWords,
+			}
+			if identifier.Description != "" {
+				input.Description = aws.String(identifier.Description)
+			}
+			if identifier.MaximumMatchDistance > 0 {
+				input.MaximumMatchDistance = aws.Int32(identifier.MaximumMatchDistance)
+			}
+			output, err := client.CreateCustomDataIdentifier(context.Background(), input)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to create custom data identifier %s: %w", identifier.Name, err)
+			}
+			customIDs = append(customIDs, aws.ToString(output.CustomDataIdentifierId))
+		}
+	}
+
+	allowListIDs := slices.Clone(identifiers.AllowListIds)
+	if len(identifiers.AllowLists) > 0 {
+		existing := map[string]string{}
+		paginator := macie2.NewListAllowListsPaginator(client, &macie2.ListAllowListsInput{})
+		for paginator.HasMorePages() {
+			page, err := paginator.NextPage(context.Background())
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to list allow lists: %w", err)
+			}
+			for _, list := range page.AllowLists {
+				existing[aws.ToString(list.Name)] = aws.ToString(list.Id)
+			}
+		}
+
+		for _, list := range identifiers.AllowLists {
+			if id, ok := existing[list.Name]; ok {
+				allowListIDs = append(allowListIDs, id)
+				continue
+			}
+			criteria := &types.AllowListCriteria{}
+			if list.Regex != "" {
+				criteria.Regex = aws.String(list.Regex)
+			} else {
+				criteria.S3WordsList = &types.S3WordsList{
+					BucketName: aws.String(list.WordsBucket),
+					ObjectKey:  aws.String(list.WordsObjectKey),
+				}
+			}
+			input := &macie2.CreateAllowListInput{
+				Name:     aws.String(list.Name),
+				Criteria: criteria,
+			}
+			if list.Description != "" {
+				input.Description = aws.String(list.Description)
+			}
+			output, err := client.CreateAllowList(context.Background(), input)
+			if err != nil {
+				return nil, nil, fmt.Errorf("unable to create allow list %s: %w", list.Name, err)
+			}
+			allowListIDs = append(allowListIDs, aws.ToString(output.Id))
+		}
+	}
+
+	return customIDs, allowListIDs, nil
+}
+
+// GetClassificationJobStatus returns the status of a classification job
+func GetClassificationJobStatus(ctx context.Context, client MacieClientAPI, jobID string) (types.JobStatus, error) {
+	output, err := client.DescribeClassificationJob(ctx, &macie2.DescribeClassificationJobInput{
+		JobId: aws.String(jobID),
+	})
+	if err != nil {
+		return "", err
+	}
+	return output.JobStatus, nil
+}
+
+// CancelClassificationJob cancels a classification job. A cancelled job
+// cannot be resumed.
+func CancelClassificationJob(ctx context.Context, client MacieClientAPI, jobID string) error {
+	_, err := client.UpdateClassificationJob(ctx, &macie2.UpdateClassificationJobInput{
+		JobId:     aws.String(jobID),
+		JobStatus: types.JobStatusCancelled,
+	})
+	return err
+}
+
+// maxFindingsPerRequest is the most finding IDs GetFindings accepts at once
+const maxFindingsPerRequest = 50
+
+// ListJobFindingIDs returns the IDs of every finding a classification job
+// produced, following NextToken across pages
+func ListJobFindingIDs(ctx context.Context, client MacieClientAPI, jobID string) ([]string, error) {
+	var ids []string
+	paginator := macie2.NewListFindingsPaginator(client, &macie2.ListFindingsInput{
+		FindingCriteria: &types.FindingCriteria{
+			Criterion: map[string]types.CriterionAdditionalProperties{
+				"classificationDetails.jobId": {
+					Eq: []string{jobID},
+				},
+			},
+		},
+	})
+	for paginator.HasMorePages() {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return ids, err
+		}
+		ids = append(ids, page.FindingIds...)
+	}
+	return ids, nil
+}
+
+// GetFindingsInBatches returns the details of the findings, requesting them
+// in batches of the size GetFindings allows
+func GetFindingsInBatches(ctx context.Context, client MacieClientAPI, ids []string) ([]types.Finding, error) {
+	findings := make([]types.Finding, 0, len(ids))
+	for start := 0; start < len(ids); start += maxFindingsPerRequest {
+		end := min(start+maxFindingsPerRequest, len(ids))
+		output, err := client.GetFindings(ctx, &macie2.GetFindingsInput{FindingIds: ids[start:end]})
+		if err != nil {
+			return findings, err
+		}
+		findings = append(findings, output.Findings...)
+	}
+	return findings, nil
+}
+
+// MacieSeverity converts the severity of a Macie finding, treating findings
+// without one as high
+func MacieSeverity(severity *types.Severity) models.Severity {
+	if severity == nil {
+		return models.SeverityHigh
+	}
+	switch severity.Description {
+	case types.SeverityDescriptionLow:
+		return models.SeverityLow
+	case types.SeverityDescriptionMedium:
+		return models.SeverityMedium
+	default:
+		return models.SeverityHigh
+	}
+}
+
+// SensitiveObjectFromFinding summarizes the sensitive data a Macie finding
+// reports for an object
+func SensitiveObjectFromFinding(finding types.Finding) models.SensitiveObject {
+	object := models.SensitiveObject{
+		FindingID:   aws.ToString(finding.Id),
+		FindingType: string(finding.Type),
+		Severity:    MacieSeverity(finding.Severity),
+	}
+	if finding.ResourcesAffected != nil && finding.ResourcesAffected.S3Object != nil {
+		object.Key = aws.ToString(finding.ResourcesAffected.S3Object.Key)
+	}
+	if finding.ClassificationDetails == nil || finding.ClassificationDetails.Result == nil {
+		return object
+	}
+
+	result := finding.ClassificationDetails.Result
+	for _, item := range result.SensitiveData {
+		for _, detection := range item.Detections {
+			object.Detections = append(object.Detections, models.SensitiveDetection{
+				Category:  string(item.Category),
+				Type:      aws.ToString(detection.Type),
+				Count:     aws.ToInt64(detection.Count),
+				Locations: occurrenceLocations(detection.Occurrences),
+			})
+		}
+	}
+	if result.CustomDataIdentifiers != nil {
+		for _, detection := range result.CustomDataIdentifiers.Detections {
+			object.Detections = append(object.Detections, models.SensitiveDetection{
+				Category:  string(types.SensitiveDataItemCategoryCustomIdentifier),
+				Type:      aws.ToString(detection.Name),
+				Count:     aws.ToInt64(detection.Count),
+				Locations: occurrenceLocations(detection.Occurrences),
+			})
+		}
+	}
+	return object
+}
+
+// occurrenceLocations describes where in an object Macie found sensitive data
+func occurrenceLocations(occurrences *types.Occurrences) []string {
+	if occurrences == nil {
+		return nil
+	}
+
+	var locations []string
+	for _, cell := range occurrences.Cells {
+		if cell.CellReference != nil {
+			locations = append(locations, "cell "+aws.ToString(cell.CellReference))
+		} else if cell.ColumnName != nil {
+			locations = append(locations, fmt.Sprintf("row %d, column %s", aws.ToInt64(cell.Row), aws.ToString(cell.ColumnName)))
+		} else {
+			locations = append(locations, fmt.Sprintf("row %d, column %d", aws.ToInt64(cell.Row), aws.ToInt64(cell.Column)))
+		}
+	}
+	for _, r := range occurrences.LineRanges {
+		locations = append(locations, rangeLocation("line", r))
+	}
+	for _, r := range occurrences.OffsetRanges {
+		locations = append(locations, rangeLocation("offset", r))
+	}
+	for _, page := range occurrences.Pages {
+		locations = append(locations, fmt.Sprintf("page %d", aws.ToInt64(page.PageNumber)))
+	}
+	for _, record := range occurrences.Records {
+		location := fmt.Sprintf("record %d", aws.ToInt64(record.RecordIndex))
+		if record.JsonPath != nil {
+			location += " at " + aws.ToString(record.JsonPath)
+		}
+		locations = append(locations, location)
+	}
+	return locations
+}
+
+func rangeLocation(unit string, r types.Range) string {
+	start, end := aws.ToInt64(r.Start), aws.ToInt64(r.End)
+	if end <= start {
+		return fmt.Sprintf("%s %d", unit, start)
+	}
+	return fmt.Sprintf("%ss %d-%d", unit, start, end)
 }
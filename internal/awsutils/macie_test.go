@@ -0,0 +1,373 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/macie2"
+	"github.com/aws/aws-sdk-go-v2/service/macie2/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockMacieClient struct {
+	mock.Mock
+}
+
+func (m *mockMacieClient) CreateClassificationJob(ctx context.Context, params *macie2.CreateClassificationJobInput, optFns ...func(*macie2.Options)) (*macie2.CreateClassificationJobOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.CreateClassificationJobOutput), args.Error(1)
+}
+
+func (m *mockMacieClient) DescribeClassificationJob(ctx context.Context, params *macie2.DescribeClassificationJobInput, optFns ...func(*macie2.Options)) (*macie2.DescribeClassificationJobOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.DescribeClassificationJobOutput), args.Error(1)
+}
+
+func (m *mockMacieClient) UpdateClassificationJob(ctx context.Context, params *macie2.UpdateClassificationJobInput, optFns ...func(*macie2.Options)) (*macie2.UpdateClassificationJobOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.UpdateClassificationJobOutput), args.Error(1)
+}
+
+func (m *mockMacieClient) ListFindings(ctx context.Context, params *macie2.ListFindingsInput, optFns ...func(*macie2.Options)) (*macie2.ListFindingsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.ListFindingsOutput), args.Error(1)
+}
+
+func (m *mockMacieClient) GetFindings(ctx context.Context, params *macie2.GetFindingsInput, optFns ...func(*macie2.Options)) (*macie2.GetFindingsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.GetFindingsOutput), args.Error(1)
+}
+
+func (m *mockMacieClient) ListCustomDataIdentifiers(ctx context.Context, params *macie2.ListCustomDataIdentifiersInput, optFns ...func(*macie2.Options)) (*macie2.ListCustomDataIdentifiersOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.ListCustomDataIdentifiersOutput), args.Error(1)
+}
+
+func (m *mockMacieClient) CreateCustomDataIdentifier(ctx context.Context, params *macie2.CreateCustomDataIdentifierInput, optFns ...func(*macie2.Options)) (*macie2.CreateCustomDataIdentifierOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.CreateCustomDataIdentifierOutput), args.Error(1)
+}
+
+func (m *mockMacieClient) ListAllowLists(ctx context.Context, params *macie2.ListAllowListsInput, optFns ...func(*macie2.Options)) (*macie2.ListAllowListsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.ListAllowListsOutput), args.Error(1)
+}
+
+func (m *mockMacieClient) CreateAllowList(ctx context.Context, params *macie2.CreateAllowListInput, optFns ...func(*macie2.Options)) (*macie2.CreateAllowListOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*macie2.CreateAllowListOutput), args.Error(1)
+}
+
+func TestMacieJobScope_Scoping(t *testing.T) {
+	tests := []struct {
+		name             string
+		scope            MacieJobScope
+		expectedIncludes []types.JobScopeTerm
+		expectedExcludes []types.JobScopeTerm
+		expectNil        bool
+	}{
+		{
+			name:      "Whole bucket",
+			expectNil: true,
+		},
+		{
+			name: "Prefixes, extensions and size range",
+			scope: MacieJobScope{
+				IncludePrefixes:   []string{"exports/", "uploads/"},
+				IncludeExtensions: []string{".csv", "xlsx"},
+				MinSize:           1024,
+				MaxSize:           10485760,
+			},
+			expectedIncludes: []types.JobScopeTerm{
+				simpleScopeTerm(types.ScopeFilterKeyObjectKey, types.JobComparatorStartsWith, "exports/", "uploads/"),
+				simpleScopeTerm(types.ScopeFilterKeyObjectExtension, types.JobComparatorEq, "csv", "xlsx"),
+				simpleScopeTerm(types.ScopeFilterKeyObjectSize, types.JobComparatorGte, "1024"),
+				simpleScopeTerm(types.ScopeFilterKeyObjectSize, types.JobComparatorLte, "10485760"),
+			},
+		},
+		{
+			name: "Tags and exclusions",
+			scope: MacieJobScope{
+				IncludeTags:       map[string]string{"team": "payments", "pii": ""},
+				ExcludePrefixes:   []string{"tmp/"},
+				ExcludeExtensions: []string{"gz"},
+				ExcludeTags:       map[string]string{"classified": "public"},
+			},
+			expectedIncludes: []types.JobScopeTerm{
+				{TagScopeTerm: &types.TagScopeTerm{
+					Comparator: types.JobComparatorEq,
+					Key:        aws.String("TAG"),
+					TagValues: []types.TagValuePair{
+						{Key: aws.String("pii"), Value: aws.String("")},
+						{Key: aws.String("team"), Value: aws.String("payments")},
+					},
+					Target: types.TagTargetS3Object,
+				}},
+			},
+			expectedExcludes: []types.JobScopeTerm{
+				simpleScopeTerm(types.ScopeFilterKeyObjectKey, types.JobComparatorStartsWith, "tmp/"),
+				simpleScopeTerm(types.ScopeFilterKeyObjectExtension, types.JobComparatorEq, "gz"),
+				{TagScopeTerm: &types.TagScopeTerm{
+					Comparator: types.JobComparatorEq,
+					Key:        aws.String("TAG"),
+					TagValues:  []types.TagValuePair{{Key: aws.String("classified"), Value: aws.String("public")}},
+					Target:     types.TagTargetS3Object,
+				}},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			scoping := tt.scope.Scoping()
+			if tt.expectNil {
+				assert.Nil(t, scoping)
+				return
+			}
+			var includes, excludes []types.JobScopeTerm
+			if scoping.Includes != nil {
+				includes = scoping.Includes.And
+			}
+			if scoping.Excludes != nil {
+				excludes = scoping.Excludes.And
+			}
+			assert.Equal(t, tt.expectedIncludes, includes)
+			assert.Equal(t, tt.expectedExcludes, excludes)
+		})
+	}
+}
+
+func TestMacieJobScope_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		scope   MacieJobScope
+		wantErr bool
+	}{
+		{name: "Zero value", scope: MacieJobScope{}},
+		{name: "Sampling and size range", scope: MacieJobScope{SamplingPercentage: 25, MinSize: 10, MaxSize: 100}},
+		{name: "Sampling above 100", scope: MacieJobScope{SamplingPercentage: 150}, wantErr: true},
+		{name: "Minimum above maximum", scope: MacieJobScope{MinSize: 100, MaxSize: 10}, wantErr: true},
+		{name: "Negative size", scope: MacieJobScope{MinSize: -1}, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.scope.Validate()
+			if tt.wantErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+		})
+	}
+}
+
+func TestLoadMacieIdentifiers(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		expected MacieIdentifiers
+		wantErr  bool
+	}{
+		{
+			name: "Custom identifiers and allow lists",
+			content: `{
+				"managedDataIdentifierSelector": "include",
+				"managedDataIdentifierIds": ["CREDIT_CARD_NUMBER"],
+				"customDataIdentifiers": [{"name": "employee-id", "regex": "EMP-[0-9]{6}", "keywords": ["employee"], "maximumMatchDistance": 20}],
+				"allowLists": [{"name": "test-accounts", "wordsBucket": "config", "wordsObjectKey": "allow.txt"}]
+			}`,
+			expected: MacieIdentifiers{
+				ManagedDataIdentifierSelector: "INCLUDE",
+				ManagedDataIdentifierIds:      []string{"CREDIT_CARD_NUMBER"},
+				CustomDataIdentifiers:         []CustomDataIdentifier{{Name: "employee-id", Regex: "EMP-[0-9]{6}", Keywords: []string{"employee"}, MaximumMatchDistance: 20}},
+				AllowLists:                    []AllowList{{Name: "test-accounts", WordsBucket: "config", WordsObjectKey: "allow.txt"}},
+			},
+		},
+		{
+			name:    "Unknown selector",
+			content: `{"managedDataIdentifierSelector": "SOME"}`,
+			wantErr: true,
+		},
+		{
+			name:    "Managed IDs without include or exclude",
+			content: `{"managedDataIdentifierSelector": "ALL", "managedDataIdentifierIds": ["CREDIT_CARD_NUMBER"]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Custom identifier without regex",
+			content: `{"customDataIdentifiers": [{"name": "employee-id"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Allow list with regex and words",
+			content: `{"allowLists": [{"name": "both", "regex": "x", "wordsBucket": "config", "wordsObjectKey": "allow.txt"}]}`,
+			wantErr: true,
+		},
+		{
+			name:    "Unknown field",
+			content: `{"customIdentifiers": []}`,
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "macie.json")
+			assert.NoError(t, os.WriteFile(path, []byte(tt.content), 0o600))
+
+			identifiers, err := LoadMacieIdentifiers(path)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, identifiers)
+		})
+	}
+}
+
+func TestResolveMacieIdentifiers(t *testing.T) {
+	mockClient := new(mockMacieClient)
+	mockClient.On("ListCustomDataIdentifiers", mock.Anything, mock.Anything).Return(&macie2.ListCustomDataIdentifiersOutput{
+		Items: []types.CustomDataIdentifierSummary{{Name: aws.String("employee-id"), Id: aws.String("cdi-existing")}},
+	}, nil)
+	mockClient.On("CreateCustomDataIdentifier", mock.Anything, mock.MatchedBy(func(in *macie2.CreateCustomDataIdentifierInput) bool {
+		return aws.ToString(in.Name) == "account-number" && aws.ToString(in.Regex) == "ACC[0-9]{8}" && aws.ToInt32(in.MaximumMatchDistance) == 30
+	})).Return(&macie2.CreateCustomDataIdentifierOutput{CustomDataIdentifierId: aws.String("cdi-new")}, nil)
+	mockClient.On("ListAllowLists", mock.Anything, mock.Anything).Return(&macie2.ListAllowListsOutput{}, nil)
+	mockClient.On("CreateAllowList", mock.Anything, mock.MatchedBy(func(in *macie2.CreateAllowListInput) bool {
+		return aws.ToString(in.Name) == "test-accounts" && aws.ToString(in.Criteria.Regex) == "ACC0{8}"
+	})).Return(&macie2.CreateAllowListOutput{Id: aws.String("al-new")}, nil)
+
+	customIDs, allowListIDs, err := ResolveMacieIdentifiers(mockClient, MacieIdentifiers{
+		CustomDataIdentifiers: []CustomDataIdentifier{
+			{Name: "employee-id", Regex: "EMP-[0-9]{6}"},
+			{Name: "account-number", Regex: "ACC[0-9]{8}", MaximumMatchDistance: 30},
+		},
+		CustomDataIdentifierIds: []string{"cdi-configured"},
+		AllowLists:              []AllowList{{Name: "test-accounts", Regex: "ACC0{8}"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"cdi-configured", "cdi-existing", "cdi-new"}, customIDs)
+	assert.Equal(t, []string{"al-new"}, allowListIDs)
+	mockClient.AssertNumberOfCalls(t, "CreateCustomDataIdentifier", 1)
+}
+
+func TestSensitiveObjectFromFinding(t *testing.T) {
+	finding := types.Finding{
+		Id:       aws.String("finding-1"),
+		Type:     types.FindingTypeSensitiveDataS3ObjectPersonal,
+		Severity: &types.Severity{Description: types.SeverityDescriptionMedium},
+		ResourcesAffected: &types.ResourcesAffected{
+			S3Object: &types.S3Object{Key: aws.String("exports/customers.csv")},
+		},
+		ClassificationDetails: &types.ClassificationDetails{
+			Result: &types.ClassificationResult{
+				SensitiveData: []types.SensitiveDataItem{
+					{
+						Category: types.SensitiveDataItemCategoryPersonalInformation,
+						Detections: []types.DefaultDetection{
+							{
+								Type:  aws.String("USA_SOCIAL_SECURITY_NUMBER"),
+								Count: aws.Int64(2),
+								Occurrences: &types.Occurrences{
+									Cells: []types.Cell{{CellReference: aws.String("C4")}, {Row: aws.Int64(7), ColumnName: aws.String("ssn")}},
+								},
+							},
+						},
+					},
+				},
+				CustomDataIdentifiers: &types.CustomDataIdentifiers{
+					Detections: []types.CustomDetection{
+						{
+							Name:  aws.String("employee-id"),
+							Count: aws.Int64(3),
+							Occurrences: &types.Occurrences{
+								LineRanges: []types.Range{{Start: aws.Int64(12), End: aws.Int64(12)}, {Start: aws.Int64(20), End: aws.Int64(22)}},
+								Records:    []types.Record{{RecordIndex: aws.Int64(1), JsonPath: aws.String("$.employee.id")}},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	object := SensitiveObjectFromFinding(finding)
+
+	assert.Equal(t, models.SensitiveObject{
+		Key:         "exports/customers.csv",
+		FindingID:   "finding-1",
+		FindingType: "SensitiveData:S3Object/Personal",
+		Severity:    models.SeverityMedium,
+		Detections: []models.SensitiveDetection{
+			{Category: "PERSONAL_INFORMATION", Type: "USA_SOCIAL_SECURITY_NUMBER", Count: 2, Locations: []string{"cell C4", "row 7, column ssn"}},
+			{Category: "CUSTOM_IDENTIFIER", Type: "employee-id", Count: 3, Locations: []string{"line 12", "lines 20-22", "record 1 at $.employee.id"}},
+		},
+	}, object)
+	assert.Equal(t, int64(5), object.Occurrences())
+	assert.Equal(t, []string{"CUSTOM_IDENTIFIER", "PERSONAL_INFORMATION"}, object.Categories())
+}
+
+func TestListJobFindingIDsAndGetFindingsInBatches(t *testing.T) {
+	var page1, page2 []string
+	for i := 0; i < 120; i++ {
+		id := fmt.Sprintf("finding-%d", i)
+		if i < 100 {
+			page1 = append(page1, id)
+		} else {
+			page2 = append(page2, id)
+		}
+	}
+
+	mockClient := new(mockMacieClient)
+	mockClient.On("ListFindings", mock.Anything, mock.MatchedBy(func(in *macie2.ListFindingsInput) bool {
+		return in.NextToken == nil && in.FindingCriteria.Criterion["classificationDetails.jobId"].Eq[0] == "job-1"
+	})).Return(&macie2.ListFindingsOutput{FindingIds: page1, NextToken: aws.String("page-2")}, nil)
+	mockClient.On("ListFindings", mock.Anything, mock.MatchedBy(func(in *macie2.ListFindingsInput) bool {
+		return aws.ToString(in.NextToken) == "page-2"
+	})).Return(&macie2.ListFindingsOutput{FindingIds: page2}, nil)
+	mockClient.On("GetFindings", mock.Anything, mock.Anything).Return(&macie2.GetFindingsOutput{
+		Findings: []types.Finding{{Id: aws.String("finding")}},
+	}, nil)
+
+	ids, err := ListJobFindingIDs(context.Background(), mockClient, "job-1")
+	assert.NoError(t, err)
+	assert.Len(t, ids, 120)
+
+	findings, err := GetFindingsInBatches(context.Background(), mockClient, ids)
+	assert.NoError(t, err)
+	assert.Len(t, findings, 3)
+	var batches []int
+	for _, call := range mockClient.Calls {
+		if call.Method == "GetFindings" {
+			batches = append(batches, len(call.Arguments.Get(1).(*macie2.GetFindingsInput).FindingIds))
+		}
+	}
+	assert.Equal(t, []int{50, 50, 20}, batches)
+}
+
+func TestGetClassificationJobStatusAndCancel(t *testing.T) {
+	mockClient := new(mockMacieClient)
+	mockClient.On("DescribeClassificationJob", mock.Anything, mock.MatchedBy(func(in *macie2.DescribeClassificationJobInput) bool {
+		return aws.ToString(in.JobId) == "job-1"
+	})).Return(&macie2.DescribeClassificationJobOutput{JobStatus: types.JobStatusRunning}, nil)
+	mockClient.On("UpdateClassificationJob", mock.Anything, mock.MatchedBy(func(in *macie2.UpdateClassificationJobInput) bool {
+		return aws.ToString(in.JobId) == "job-1" && in.JobStatus == types.JobStatusCancelled
+	})).Return(&macie2.UpdateClassificationJobOutput{}, nil)
+
+	status, err := GetClassificationJobStatus(context.Background(), mockClient, "job-1")
+	assert.NoError(t, err)
+	assert.Equal(t, types.JobStatusRunning, status)
+
+	assert.NoError(t, CancelClassificationJob(context.Background(), mockClient, "job-1"))
+	mockClient.AssertExpectations(t)
+}
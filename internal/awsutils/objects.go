@@ -0,0 +1,206 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Check IDs of the findings raised by object sampling
+const (
+	CheckIDObjectPublic      = "objects.public-acl"
+	CheckIDObjectUnencrypted = "objects.unencrypted"
+)
+
+// maxEvidenceKeys caps how many object keys a finding lists
+const maxEvidenceKeys = 10
+
+// maxSampleListing caps how many objects are listed to draw a sample from
+const maxSampleListing = 10000
+
+// ObjectSampleOptions controls which objects SampleObjects inspects
+type ObjectSampleOptions struct {
+	// Size is the number of objects to inspect; zero inspects every object
+	// under Prefix
+	Size   int
+	Prefix string
+	// RatePerSecond caps the GetObjectAcl and HeadObject requests made for
+	// the bucket; zero means no limit
+	RatePerSecond int
+}
+
+// SampleObjects lists objects under the prefix and inspects the ACL and
+// encryption of each. A sample of Size objects is spread evenly over the
+// first 10000 listed. Objects that cannot be inspected are recorded in the
+// result rather than failing the sample.
+func SampleObjects(ctx context.Context, s3Client S3ClientAPI, bucketName string, opts ObjectSampleOptions) (models.ObjectSample, error) {
+	sample := models.ObjectSample{
+		Prefix:     opts.Prefix,
+		Encryption: map[string]int{},
+		KMSKeys:    map[string]int{},
+	}
+
+	wait := func() error { return ctx.Err() }
+	if opts.RatePerSecond > 0 {
+		// Rates above one request per millisecond are not limited further
+		ticker := time.NewTicker(max(time.Second/time.Duration(opts.RatePerSecond), time.Millisecond))
+		defer ticker.Stop()
+		wait = func() error {
+			select {
+			case <-ticker.C:
+				return nil
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(bucketName)}
+	if opts.Prefix != "" {
+		input.Prefix = aws.String(opts.Prefix)
+	}
+
+	var keys []string
+	paginator := s3.NewListObjectsV2Paginator(s3Client, input)
+	for paginator.HasMorePages() && (opts.Size == 0 || len(keys) < maxSampleListing) {
+		page, err := paginator.NextPage(ctx)
+		if err != nil {
+			return sample, err
+		}
+		for _, object := range page.Contents {
+			keys = append(keys, aws.ToString(object.Key))
+		}
+	}
+	if opts.Size > 0 && len(keys) > maxSampleListing {
+		keys = keys[:maxSampleListing]
+	}
+
+	for _, key := range SpreadSample(keys, opts.Size) {
+		sample.Sampled++
+
+		if err := wait(); err != nil {
+			return sample, err
+		}
+		public, err := isObjectPublic(ctx, s3Client, bucketName, key)
+		if err != nil {
+			sample.Errors = append(sample.Errors, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		if public {
+			sample.PublicObjects = append(sample.PublicObjects, key)
+		}
+
+		if err := wait(); err != nil {
+			return sample, err
+		}
+		headOutput, err := s3Client.HeadObject(ctx, &s3.HeadObjectInput{
+			Bucket: aws.String(bucketName),
+			Key:    aws.String(key),
+		})
+		if err != nil {
+			sample.Errors = append(sample.Errors, fmt.Sprintf("%s: %v", key, err))
+			continue
+		}
+		algorithm := string(headOutput.ServerSideEncryption)
+		if algorithm == "" {
+			sample.UnencryptedObjects = append(sample.UnencryptedObjects, key)
+			algorithm = "none"
+		}
+		sample.Encryption[algorithm]++
+		if keyID := aws.ToString(headOutput.SSEKMSKeyId); keyID != "" {
+			sample.KMSKeys[keyID]++
+		}
+	}
+
+	return sample, nil
+}
+
+// SpreadSample picks n keys spread evenly over the listing, so the sample is
+// not limited to the first prefix in key order. n of zero keeps every key.
+func SpreadSample(keys []string, n int) []string {
+	if n <= 0 || len(keys) <= n {
+		return keys
+	}
+	sample := make([]string, n)
+	for i := range sample {
+		sample[i] = keys[i*len(keys)/n]
+	}
+	return sample
+}
+
+// isObjectPublic reports whether the object ACL grants access to a public group
+func isObjectPublic(ctx context.Context, s3Client S3ClientAPI, bucketName, key string) (bool, error) {
+	aclOutput, err := s3Client.GetObjectAcl(ctx, &s3.GetObjectAclInput{
+		Bucket: aws.String(bucketName),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return false, err
+	}
+	for _, grant := range aclOutput.Grants {
+		if grant.Grantee == nil || grant.Grantee.URI == nil {
+			continue
+		}
+		if uri := *grant.Grantee.URI; uri == allUsersURI || uri == authenticatedUsersURI {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CheckObjectSample samples objects of the bucket and returns findings for
+// objects with public ACLs and objects stored without encryption. When
+// ignorePublicAcls is set, S3 ignores the public grants, which lowers the
+// severity.
+func CheckObjectSample(ctx context.Context, s3Client S3ClientAPI, bucketName string, opts ObjectSampleOptions, ignorePublicAcls bool) (models.ObjectSample, []models.Finding, error) {
+	sample, err := SampleObjects(ctx, s3Client, bucketName, opts)
+	if err != nil {
+		return sample, nil, err
+	}
+
+	var findings []models.Finding
+	if n := len(sample.PublicObjects); n > 0 {
+		finding := models.Finding{
+			CheckID:     CheckIDObjectPublic,
+			Severity:    models.SeverityHigh,
+			Title:       "Objects have ACLs granting access to a public group",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    sampleEvidence(sample, n, sample.PublicObjects),
+			Remediation: "Reset the object ACLs to private, or set Object Ownership to BucketOwnerEnforced to disable ACLs",
+		}
+		if ignorePublicAcls {
+			finding.Severity = models.SeverityLow
+			finding.Title = "Objects have ACLs granting access to a public group but IgnorePublicAcls is enabled"
+		}
+		findings = append(findings, finding)
+	}
+	if n := len(sample.UnencryptedObjects); n > 0 {
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDObjectUnencrypted,
+			Severity:    models.SeverityMedium,
+			Title:       "Objects are stored without server-side encryption",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    sampleEvidence(sample, n, sample.UnencryptedObjects),
+			Remediation: "Copy the objects onto themselves so they are re-written with the bucket's default encryption",
+		})
+	}
+
+	return sample, findings, nil
+}
+
+// sampleEvidence describes how many sampled objects are affected and lists
+// the first of their keys
+func sampleEvidence(sample models.ObjectSample, affected int, keys []string) []string {
+	evidence := []string{fmt.Sprintf("%d of %d sampled object(s)", affected, sample.Sampled)}
+	if len(keys) > maxEvidenceKeys {
+		evidence = append(evidence, fmt.Sprintf("Keys: %s, ...", strings.Join(keys[:maxEvidenceKeys], ", ")))
+	} else {
+		evidence = append(evidence, fmt.Sprintf("Keys: %s", strings.Join(keys, ", ")))
+	}
+	return evidence
+}
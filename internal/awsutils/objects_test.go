@@ -0,0 +1,114 @@
+package awsutils
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func forKey(key string) interface{} {
+	return mock.MatchedBy(func(in interface{}) bool {
+		switch in := in.(type) {
+		case *s3.GetObjectAclInput:
+			return aws.ToString(in.Key) == key
+		case *s3.HeadObjectInput:
+			return aws.ToString(in.Key) == key
+		}
+		return false
+	})
+}
+
+func setupObjectMocks(m *mockS3Client) {
+	m.On("ListObjectsV2", mock.Anything, mock.Anything).Return(&s3.ListObjectsV2Output{
+		Contents: []types.Object{{Key: aws.String("public.txt")}, {Key: aws.String("old.txt")}, {Key: aws.String("kms.txt")}, {Key: aws.String("denied.txt")}},
+	}, nil)
+
+	private := &s3.GetObjectAclOutput{Grants: []types.Grant{{
+		Grantee:    &types.Grantee{Type: types.TypeCanonicalUser, ID: aws.String("owner-id")},
+		Permission: types.PermissionFullControl,
+	}}}
+	m.On("GetObjectAcl", mock.Anything, forKey("public.txt")).Return(&s3.GetObjectAclOutput{Grants: []types.Grant{{
+		Grantee:    &types.Grantee{Type: types.TypeGroup, URI: aws.String(allUsersURI)},
+		Permission: types.PermissionRead,
+	}}}, nil)
+	m.On("GetObjectAcl", mock.Anything, forKey("old.txt")).Return(private, nil)
+	m.On("GetObjectAcl", mock.Anything, forKey("kms.txt")).Return(private, nil)
+	m.On("GetObjectAcl", mock.Anything, forKey("denied.txt")).Return(&s3.GetObjectAclOutput{}, errors.New("AccessDenied"))
+
+	m.On("HeadObject", mock.Anything, forKey("public.txt")).Return(
+		&s3.HeadObjectOutput{ServerSideEncryption: types.ServerSideEncryptionAes256}, nil)
+	m.On("HeadObject", mock.Anything, forKey("old.txt")).Return(&s3.HeadObjectOutput{}, nil)
+	m.On("HeadObject", mock.Anything, forKey("kms.txt")).Return(
+		&s3.HeadObjectOutput{ServerSideEncryption: types.ServerSideEncryptionAwsKms, SSEKMSKeyId: aws.String("arn:aws:kms:us-east-1:123456789012:key/abc")}, nil)
+}
+
+func TestCheckObjectSample(t *testing.T) {
+	mockClient := new(mockS3Client)
+	setupObjectMocks(mockClient)
+
+	sample, findings, err := CheckObjectSample(context.Background(), mockClient, "bucket", ObjectSampleOptions{}, false)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 4, sample.Sampled)
+	assert.Equal(t, []string{"public.txt"}, sample.PublicObjects)
+	assert.Equal(t, []string{"old.txt"}, sample.UnencryptedObjects)
+	assert.Equal(t, map[string]int{"AES256": 1, "none": 1, "aws:kms": 1}, sample.Encryption)
+	assert.Equal(t, map[string]int{"arn:aws:kms:us-east-1:123456789012:key/abc": 1}, sample.KMSKeys)
+	assert.Len(t, sample.Errors, 1)
+	assert.Len(t, findings, 2)
+	assert.Equal(t, CheckIDObjectPublic, findings[0].CheckID)
+	assert.Equal(t, models.SeverityHigh, findings[0].Severity)
+	assert.Equal(t, []string{"1 of 4 sampled object(s)", "Keys: public.txt"}, findings[0].Evidence)
+	assert.Equal(t, CheckIDObjectUnencrypted, findings[1].CheckID)
+}
+
+func TestCheckObjectSampleLimits(t *testing.T) {
+	mockClient := new(mockS3Client)
+	setupObjectMocks(mockClient)
+
+	sample, findings, err := CheckObjectSample(context.Background(), mockClient, "bucket", ObjectSampleOptions{Size: 1, Prefix: "p", RatePerSecond: 100}, true)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, sample.Sampled)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, models.SeverityLow, findings[0].Severity)
+	mockClient.AssertCalled(t, "ListObjectsV2", mock.Anything, mock.MatchedBy(func(in *s3.ListObjectsV2Input) bool {
+		return aws.ToString(in.Prefix) == "p"
+	}))
+	mockClient.AssertNotCalled(t, "HeadObject", mock.Anything, forKey("old.txt"))
+
+	// The sample is spread over the listing rather than its first keys, and
+	// rates too high for a ticker interval still work
+	sample, _, err = CheckObjectSample(context.Background(), mockClient, "bucket", ObjectSampleOptions{Size: 2, RatePerSecond: 2_000_000_000}, false)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, sample.Sampled)
+	assert.Equal(t, []string{"public.txt"}, sample.PublicObjects)
+	assert.Equal(t, map[string]int{"AES256": 1, "aws:kms": 1}, sample.Encryption)
+}
+
+func TestCheckObjectSampleCancelled(t *testing.T) {
+	mockClient := new(mockS3Client)
+	setupObjectMocks(mockClient)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	_, _, err := CheckObjectSample(ctx, mockClient, "bucket", ObjectSampleOptions{}, false)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	mockClient.AssertNotCalled(t, "GetObjectAcl", mock.Anything, mock.Anything)
+	mockClient.AssertNotCalled(t, "HeadObject", mock.Anything, mock.Anything)
+}
+
+func TestSpreadSample(t *testing.T) {
+	keys := []string{"a", "b", "c", "d", "e", "f", "g", "h", "i", "j"}
+	assert.Equal(t, []string{"a", "d", "g"}, SpreadSample(keys, 3))
+	assert.Equal(t, keys, SpreadSample(keys, 0))
+	assert.Equal(t, keys, SpreadSample(keys, 20))
+}
@@ -0,0 +1,107 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// CheckIDOwnershipACLs identifies findings for buckets that still use ACLs
+const CheckIDOwnershipACLs = "ownership.acls-enabled"
+
+// GetBucketOwnership reads the Object Ownership setting of the bucket and,
+// while ACLs are enabled, the bucket ACL grants to anyone but the owner
+func GetBucketOwnership(s3Client S3ClientAPI, bucketName string) (models.OwnershipStatus, error) {
+	status := models.OwnershipStatus{ObjectOwnership: models.ObjectOwnershipObjectWriter}
+
+	ownershipOutput, err := s3Client.GetBucketOwnershipControls(context.Background(), &s3.GetBucketOwnershipControlsInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil && !isAPIError(err, "OwnershipControlsNotFoundError") {
+		return status, err
+	}
+	if err == nil && ownershipOutput.OwnershipControls != nil && len(ownershipOutput.OwnershipControls.Rules) > 0 {
+		status.ObjectOwnership = string(ownershipOutput.OwnershipControls.Rules[0].ObjectOwnership)
+		status.Configured = true
+	}
+	if !status.ACLsEnabled() {
+		return status, nil
+	}
+
+	aclOutput, err := s3Client.GetBucketAcl(context.Background(), &s3.GetBucketAclInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err != nil {
+		return status, fmt.Errorf("unable to get bucket ACL: %w", err)
+	}
+
+	ownerID := ""
+	if aclOutput.Owner != nil {
+		ownerID = aws.ToString(aclOutput.Owner.ID)
+	}
+	for _, grant := range aclOutput.Grants {
+		if grant.Grantee == nil {
+			continue
+		}
+		if grant.Grantee.Type == types.TypeCanonicalUser && aws.ToString(grant.Grantee.ID) == ownerID {
+			continue
+		}
+		status.NonOwnerGrants = append(status.NonOwnerGrants, models.ACLGrant{
+			Type:       string(grant.Grantee.Type),
+			Grantee:    granteeName(*grant.Grantee),
+			Permission: string(grant.Permission),
+		})
+	}
+	return status, nil
+}
+
+// CheckBucketOwnership reports the Object Ownership setting and returns a
+// finding when ACLs are still enabled, listing every non-owner grant
+func CheckBucketOwnership(s3Client S3ClientAPI, bucketName string) (models.OwnershipStatus, []models.Finding, error) {
+	status, err := GetBucketOwnership(s3Client, bucketName)
+	if err != nil {
+		return status, nil, err
+	}
+	if !status.ACLsEnabled() {
+		return status, nil, nil
+	}
+
+	evidence := []string{fmt.Sprintf("ObjectOwnership=%s", status.ObjectOwnership)}
+	if !status.Configured {
+		evidence[0] = "No ownership controls, so ObjectOwnership defaults to ObjectWriter"
+	}
+	severity := models.SeverityLow
+	for _, grant := range status.NonOwnerGrants {
+		severity = models.SeverityMedium
+		evidence = append(evidence, fmt.Sprintf("Grant %s to %s", grant.Permission, grant.Summary()))
+	}
+
+	remediation := "Set Object Ownership to BucketOwnerEnforced to disable ACLs and control access with policies only"
+	if len(status.NonOwnerGrants) > 0 {
+		remediation = "Replace the ACL grants with bucket policy statements, then set Object Ownership to BucketOwnerEnforced to disable ACLs"
+	}
+	return status, []models.Finding{{
+		CheckID:     CheckIDOwnershipACLs,
+		Severity:    severity,
+		Title:       "Bucket ACLs are enabled",
+		Resource:    models.BucketARN(bucketName),
+		Evidence:    evidence,
+		Remediation: remediation,
+	}}, nil
+}
+
+// granteeName returns the canonical ID, group URI or email address of a grantee
+func granteeName(grantee types.Grantee) string {
+	switch {
+	case grantee.URI != nil:
+		return aws.ToString(grantee.URI)
+	case grantee.EmailAddress != nil:
+		return aws.ToString(grantee.EmailAddress)
+	default:
+		return aws.ToString(grantee.ID)
+	}
+}
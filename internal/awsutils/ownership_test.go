@@ -0,0 +1,92 @@
+package awsutils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckBucketOwnership(t *testing.T) {
+	ownerGrant := types.Grant{
+		Grantee:    &types.Grantee{Type: types.TypeCanonicalUser, ID: aws.String("owner-id")},
+		Permission: types.PermissionFullControl,
+	}
+	logDeliveryGrant := types.Grant{
+		Grantee:    &types.Grantee{Type: types.TypeGroup, URI: aws.String("http://acs.amazonaws.com/groups/s3/LogDelivery")},
+		Permission: types.PermissionWrite,
+	}
+	otherAccountGrant := types.Grant{
+		Grantee:    &types.Grantee{Type: types.TypeCanonicalUser, ID: aws.String("other-id")},
+		Permission: types.PermissionRead,
+	}
+
+	tests := []struct {
+		name              string
+		ownership         types.ObjectOwnership
+		grants            []types.Grant
+		expectedOwnership string
+		expectedGrants    []string
+		expectedSeverity  models.Severity
+	}{
+		{
+			name:              "ACLs disabled",
+			ownership:         types.ObjectOwnershipBucketOwnerEnforced,
+			expectedOwnership: models.ObjectOwnershipEnforced,
+		},
+		{
+			name:              "Bucket owner preferred with only the owner grant",
+			ownership:         types.ObjectOwnershipBucketOwnerPreferred,
+			grants:            []types.Grant{ownerGrant},
+			expectedOwnership: models.ObjectOwnershipPreferred,
+			expectedSeverity:  models.SeverityLow,
+		},
+		{
+			name:              "No ownership controls with non-owner grants",
+			grants:            []types.Grant{ownerGrant, logDeliveryGrant, otherAccountGrant},
+			expectedOwnership: models.ObjectOwnershipObjectWriter,
+			expectedGrants:    []string{"WRITE to the S3 log delivery group", "READ to canonical user other-id"},
+			expectedSeverity:  models.SeverityMedium,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			if tt.ownership == "" {
+				mockClient.On("GetBucketOwnershipControls", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketOwnershipControlsOutput{}, &smithy.GenericAPIError{Code: "OwnershipControlsNotFoundError"})
+			} else {
+				mockClient.On("GetBucketOwnershipControls", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketOwnershipControlsOutput{OwnershipControls: &types.OwnershipControls{
+						Rules: []types.OwnershipControlsRule{{ObjectOwnership: tt.ownership}},
+					}}, nil)
+			}
+			mockClient.On("GetBucketAcl", mock.Anything, mock.Anything).Return(
+				&s3.GetBucketAclOutput{Owner: &types.Owner{ID: aws.String("owner-id")}, Grants: tt.grants}, nil)
+
+			status, findings, err := CheckBucketOwnership(mockClient, "bucket")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedOwnership, status.ObjectOwnership)
+			var grants []string
+			for _, grant := range status.NonOwnerGrants {
+				grants = append(grants, grant.Permission+" to "+grant.Summary())
+			}
+			assert.Equal(t, tt.expectedGrants, grants)
+			if tt.expectedSeverity == models.SeverityNone {
+				assert.Empty(t, findings)
+				mockClient.AssertNotCalled(t, "GetBucketAcl", mock.Anything, mock.Anything)
+				return
+			}
+			assert.Len(t, findings, 1)
+			assert.Equal(t, CheckIDOwnershipACLs, findings[0].CheckID)
+			assert.Equal(t, tt.expectedSeverity, findings[0].Severity)
+		})
+	}
+}
@@ -0,0 +1,339 @@
+package awsutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// PolicyAccess describes the widest access a bucket policy grants
+type PolicyAccess string
+
+const (
+	PolicyAccessNone         PolicyAccess = "none"
+	PolicyAccessRestricted   PolicyAccess = "restricted"
+	PolicyAccessCrossAccount PolicyAccess = "cross-account"
+	PolicyAccessPublic       PolicyAccess = "public"
+	// PolicyAccessUnknown is reported when the policy could not be read
+	PolicyAccessUnknown PolicyAccess = "unknown"
+)
+
+var policyAccessRank = map[PolicyAccess]int{
+	PolicyAccessNone:         0,
+	PolicyAccessRestricted:   1,
+	PolicyAccessCrossAccount: 2,
+	PolicyAccessPublic:       3,
+}
+
+// Wider reports whether a grants more access than b
+func (a PolicyAccess) Wider(b PolicyAccess) bool {
+	return policyAccessRank[a] > policyAccessRank[b]
+}
+
+// BucketPolicy is a parsed S3 bucket policy document
+type BucketPolicy struct {
+	Version    string            `json:"Version"`
+	Statements []PolicyStatement `json:"Statement"`
+}
+
+// PolicyStatement is a single statement of a bucket policy
+type PolicyStatement struct {
+	Sid          string                              `json:"Sid"`
+	Effect       string                              `json:"Effect"`
+	Principal    *PolicyPrincipal                    `json:"Principal"`
+	NotPrincipal *PolicyPrincipal                    `json:"NotPrincipal"`
+	Action       stringOrSlice                       `json:"Action"`
+	NotAction    stringOrSlice                       `json:"NotAction"`
+	Resource     stringOrSlice                       `json:"Resource"`
+	NotResource  stringOrSlice                       `json:"NotResource"`
+	Condition    map[string]map[string]stringOrSlice `json:"Condition"`
+}
+
+// PolicyPrincipal is the Principal or NotPrincipal element of a statement
+type PolicyPrincipal struct {
+	Wildcard      bool
+	AWS           []string
+	Service       []string
+	Federated     []string
+	CanonicalUser []string
+}
+
+// StatementAccess is the result of evaluating one policy statement
+type StatementAccess struct {
+	Statement string
+	Access    PolicyAccess
+	Reason    string
+	Accounts  []string
+}
+
+// stringOrSlice accepts the single value or list forms used throughout IAM
+// policies. Booleans and numbers, as found in conditions, are kept as text.
+type stringOrSlice []string
+
+func (s *stringOrSlice) UnmarshalJSON(data []byte) error {
+	var raw interface{}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+
+	switch v := raw.(type) {
+	case nil:
+		*s = nil
+	case []interface{}:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			values = append(values, fmt.Sprint(item))
+		}
+		*s = values
+	default:
+		*s = []string{fmt.Sprint(v)}
+	}
+	return nil
+}
+
+func (p *PolicyPrincipal) UnmarshalJSON(data []byte) error {
+	var wildcard string
+	if err := json.Unmarshal(data, &wildcard); err == nil {
+		if wildcard != "*" {
+			return fmt.Errorf("unexpected principal %q", wildcard)
+		}
+		p.Wildcard = true
+		return nil
+	}
+
+	var principals map[string]stringOrSlice
+	if err := json.Unmarshal(data, &principals); err != nil {
+		return err
+	}
+	p.AWS = principals["AWS"]
+	p.Service = principals["Service"]
+	p.Federated = principals["Federated"]
+	p.CanonicalUser = principals["CanonicalUser"]
+	for _, arn := range p.AWS {
+		if arn == "*" {
+			p.Wildcard = true
+		}
+	}
+	return nil
+}
+
+func (b *BucketPolicy) UnmarshalJSON(data []byte) error {
+	var raw struct {
+		Version   string          `json:"Version"`
+		Statement json.RawMessage `json:"Statement"`
+	}
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return err
+	}
+	b.Version = raw.Version
+
+	trimmed := strings.TrimSpace(string(raw.Statement))
+	switch {
+	case trimmed == "":
+		b.Statements = nil
+	case strings.HasPrefix(trimmed, "["):
+		return json.Unmarshal(raw.Statement, &b.Statements)
+	default:
+		var statement PolicyStatement
+		if err := json.Unmarshal(raw.Statement, &statement); err != nil {
+			return err
+		}
+		b.Statements = []PolicyStatement{statement}
+	}
+	return nil
+}
+
+// ParseBucketPolicy parses a bucket policy document
+func ParseBucketPolicy(document string) (*BucketPolicy, error) {
+	var policy BucketPolicy
+	if err := json.Unmarshal([]byte(document), &policy); err != nil {
+		return nil, fmt.Errorf("invalid bucket policy: %w", err)
+	}
+	return &policy, nil
+}
+
+// ID returns the statement Sid, or its position when it has none
+func (st PolicyStatement) ID(index int) string {
+	if st.Sid != "" {
+		return st.Sid
+	}
+	return fmt.Sprintf("Statement[%d]", index)
+}
+
+// Evaluate classifies every Allow statement of the policy. accountID is the
+// bucket owner's account; principals from any other account are cross-account.
+func (b *BucketPolicy) Evaluate(accountID string) []StatementAccess {
+	var results []StatementAccess
+	for i, st := range b.Statements {
+		if !strings.EqualFold(st.Effect, "Allow") {
+			continue
+		}
+		result := st.evaluate(accountID)
+		result.Statement = st.ID(i)
+		results = append(results, result)
+	}
+	return results
+}
+
+func (st PolicyStatement) evaluate(accountID string) StatementAccess {
+	if st.NotPrincipal != nil {
+		return st.applyConditions(StatementAccess{
+			Access: PolicyAccessPublic,
+			Reason: "Allow with NotPrincipal grants access to every principal not listed",
+		}, accountID)
+	}
+	if st.Principal == nil {
+		return StatementAccess{Access: PolicyAccessRestricted, Reason: "statement has no principal"}
+	}
+	if st.Principal.Wildcard {
+		return st.applyConditions(StatementAccess{
+			Access: PolicyAccessPublic,
+			Reason: `Principal "*" grants access to everyone`,
+		}, accountID)
+	}
+
+	var foreign []string
+	for _, principal := range st.Principal.AWS {
+		if account := principalAccount(principal); account != "" && account != accountID {
+			foreign = append(foreign, account)
+		}
+	}
+	if len(foreign) > 0 {
+		return StatementAccess{
+			Access:   PolicyAccessCrossAccount,
+			Reason:   fmt.Sprintf("grants access to account(s) %s", strings.Join(foreign, ", ")),
+			Accounts: foreign,
+		}
+	}
+	return StatementAccess{Access: PolicyAccessRestricted, Reason: "principals are limited to the bucket owner account or AWS services"}
+}
+
+// restrictingConditionKeys narrow a wildcard principal to a known set of
+// callers when used with a positive operator such as StringEquals
+var restrictingConditionKeys = map[string]bool{
+	"aws:sourcevpce":       true,
+	"aws:sourcevpc":        true,
+	"aws:principalorgid":   true,
+	"aws:principalaccount": true,
+	"aws:principalarn":     true,
+	"aws:sourceaccount":    true,
+	"aws:sourcearn":        true,
+	"aws:sourceowner":      true,
+	"aws:sourceip":         true,
+	"aws:userid":           true,
+}
+
+// applyConditions downgrades a public statement when its conditions limit
+// who can use it
+func (st PolicyStatement) applyConditions(result StatementAccess, accountID string) StatementAccess {
+	for _, operator := range sortedKeys(st.Condition) {
+		op := strings.ToLower(operator)
+		if op == "null" || strings.Contains(op, "not") || strings.HasSuffix(op, "ifexists") {
+			continue
+		}
+		keys := st.Condition[operator]
+		for _, key := range sortedKeys(keys) {
+			values := keys[key]
+			k := strings.ToLower(key)
+			if !restrictingConditionKeys[k] || !restrictiveValues(k, values) {
+				continue
+			}
+
+			if k == "aws:principalaccount" || k == "aws:sourceaccount" {
+				var foreign []string
+				for _, account := range values {
+					if account != accountID {
+						foreign = append(foreign, account)
+					}
+				}
+				if len(foreign) > 0 {
+					return StatementAccess{
+						Access:   PolicyAccessCrossAccount,
+						Reason:   fmt.Sprintf("%s condition %s limits access to account(s) %s", operator, key, strings.Join(foreign, ", ")),
+						Accounts: foreign,
+					}
+				}
+			}
+			return StatementAccess{
+				Access: PolicyAccessRestricted,
+				Reason: fmt.Sprintf("%s condition %s = %s limits the wildcard principal", operator, key, strings.Join(values, ", ")),
+			}
+		}
+	}
+	return result
+}
+
+// restrictiveValues reports whether condition values actually narrow access
+func restrictiveValues(key string, values []string) bool {
+	if len(values) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if v == "*" || (key == "aws:sourceip" && (v == "0.0.0.0/0" || v == "::/0")) {
+			return false
+		}
+	}
+	return true
+}
+
+// principalAccount extracts the account ID from an AWS principal, which is
+// either a bare account ID or an ARN
+func principalAccount(principal string) string {
+	if len(principal) == 12 && strings.Trim(principal, "0123456789") == "" {
+		return principal
+	}
+	parts := strings.Split(principal, ":")
+	if len(parts) >= 5 && parts[0] == "arn" {
+		return parts[4]
+	}
+	return ""
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// DeniesUnencryptedUploads reports whether a Deny statement rejects PutObject
+// requests that do not ask for server-side encryption
+func (b *BucketPolicy) DeniesUnencryptedUploads() bool {
+	for _, st := range b.Statements {
+		if !strings.EqualFold(st.Effect, "Deny") || !st.coversAction("s3:PutObject") {
+			continue
+		}
+		for operator, keys := range st.Condition {
+			op := strings.ToLower(operator)
+			for key, values := range keys {
+				if !strings.EqualFold(key, "s3:x-amz-server-side-encryption") {
+					continue
+				}
+				// Null = true denies requests without the header and
+				// StringNotEquals denies anything but the listed algorithms
+				if op == "null" && containsString(values, "true") {
+					return true
+				}
+				if strings.HasPrefix(op, "stringnotequals") || strings.HasPrefix(op, "stringnotlike") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// coversAction reports whether the statement applies to the action, taking
+// wildcards such as "s3:Put*" into account
+func (st PolicyStatement) coversAction(action string) bool {
+	for _, a := range st.Action {
+		if ok, _ := path.Match(strings.ToLower(a), strings.ToLower(action)); ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,188 @@
+package awsutils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const ownerAccount = "111111111111"
+
+func TestBucketPolicyEvaluate(t *testing.T) {
+	tests := []struct {
+		name           string
+		policy         string
+		expectedAccess []PolicyAccess
+		expectedStmt   string
+	}{
+		{
+			name: "Wildcard principal is public",
+			policy: `{"Version":"2012-10-17","Statement":{"Sid":"PublicRead","Effect":"Allow","Principal":"*",
+				"Action":"s3:GetObject","Resource":"arn:aws:s3:::bucket/*"}}`,
+			expectedAccess: []PolicyAccess{PolicyAccessPublic},
+			expectedStmt:   "PublicRead",
+		},
+		{
+			name: "AWS wildcard principal is public",
+			policy: `{"Statement":[{"Effect":"Allow","Principal":{"AWS":["*"]},
+				"Action":["s3:GetObject"],"Resource":"arn:aws:s3:::bucket/*"}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessPublic},
+			expectedStmt:   "Statement[0]",
+		},
+		{
+			name: "Wildcard principal limited to a VPC endpoint",
+			policy: `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:*","Resource":"*",
+				"Condition":{"StringEquals":{"aws:SourceVpce":"vpce-1234"}}}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessRestricted},
+		},
+		{
+			name: "Wildcard principal limited to an organization",
+			policy: `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*",
+				"Condition":{"StringEquals":{"aws:PrincipalOrgID":"o-abc123"}}}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessRestricted},
+		},
+		{
+			name: "Source IP of the whole internet stays public",
+			policy: `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*",
+				"Condition":{"IpAddress":{"aws:SourceIp":["0.0.0.0/0"]}}}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessPublic},
+		},
+		{
+			name: "Negated condition does not restrict",
+			policy: `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*",
+				"Condition":{"NotIpAddress":{"aws:SourceIp":"10.0.0.0/8"}}}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessPublic},
+		},
+		{
+			name: "NotPrincipal with Allow is public",
+			policy: `{"Statement":[{"Effect":"Allow","NotPrincipal":{"AWS":"arn:aws:iam::111111111111:root"},
+				"Action":"s3:GetObject","Resource":"*"}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessPublic},
+		},
+		{
+			name: "Other account principal is cross-account",
+			policy: `{"Statement":[{"Effect":"Allow","Principal":{"AWS":["arn:aws:iam::222222222222:role/reader","111111111111"]},
+				"Action":"s3:GetObject","Resource":"*"}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessCrossAccount},
+		},
+		{
+			name: "Wildcard principal limited to another account",
+			policy: `{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:PutObject","Resource":"*",
+				"Condition":{"StringEquals":{"aws:SourceAccount":"333333333333"}}}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessCrossAccount},
+		},
+		{
+			name: "Service principal and Deny statements are restricted",
+			policy: `{"Statement":[{"Effect":"Allow","Principal":{"Service":"logging.s3.amazonaws.com"},"Action":"s3:PutObject","Resource":"*"},
+				{"Effect":"Deny","Principal":"*","Action":"s3:*","Resource":"*","Condition":{"Bool":{"aws:SecureTransport":false}}}]}`,
+			expectedAccess: []PolicyAccess{PolicyAccessRestricted},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			policy, err := ParseBucketPolicy(tt.policy)
+			assert.NoError(t, err)
+
+			results := policy.Evaluate(ownerAccount)
+			var access []PolicyAccess
+			for _, r := range results {
+				access = append(access, r.Access)
+			}
+			assert.Equal(t, tt.expectedAccess, access)
+			if tt.expectedStmt != "" {
+				assert.Equal(t, tt.expectedStmt, results[0].Statement)
+			}
+		})
+	}
+}
+
+func TestCheckBucketPolicy(t *testing.T) {
+	tests := []struct {
+		name           string
+		mockSetup      func(*mockS3Client)
+		expectedAccess PolicyAccess
+		expectedChecks []string
+		expectError    bool
+	}{
+		{
+			name: "Bucket without policy",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{}, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy"})
+			},
+			expectedAccess: PolicyAccessNone,
+		},
+		{
+			name: "Public policy",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{
+						Policy: aws.String(`{"Statement":[{"Sid":"Public","Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"*"}]}`),
+					}, nil)
+				m.On("GetBucketPolicyStatus", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyStatusOutput{PolicyStatus: &types.PolicyStatus{IsPublic: aws.Bool(true)}}, nil)
+			},
+			expectedAccess: PolicyAccessPublic,
+			expectedChecks: []string{CheckIDPolicyPublic},
+		},
+		{
+			name: "Policy status flags what the parser misses",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{
+						Policy: aws.String(`{"Statement":[{"Effect":"Allow","Principal":{"AWS":"arn:aws:iam::111111111111:root"},"Action":"s3:*","Resource":"*"}]}`),
+					}, nil)
+				m.On("GetBucketPolicyStatus", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyStatusOutput{PolicyStatus: &types.PolicyStatus{IsPublic: aws.Bool(true)}}, nil)
+			},
+			expectedAccess: PolicyAccessPublic,
+			expectedChecks: []string{CheckIDPolicyPublic},
+		},
+		{
+			name: "Access denied leaves the access unknown",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{}, &smithy.GenericAPIError{Code: "AccessDenied"})
+			},
+			expectedAccess: PolicyAccessUnknown,
+			expectedChecks: []string{CheckIDPolicyDenied},
+		},
+		{
+			name: "Other failures are errors",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{}, &smithy.GenericAPIError{Code: "InternalError"})
+			},
+			expectedAccess: PolicyAccessNone,
+			expectError:    true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			tt.mockSetup(mockClient)
+
+			document, err := ReadBucketPolicy(mockClient, "bucket")
+			if tt.expectError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			access, findings := CheckBucketPolicy(mockClient, "bucket", ownerAccount, document)
+
+			assert.Equal(t, tt.expectedAccess, access)
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
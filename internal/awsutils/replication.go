@@ -0,0 +1,179 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Check IDs of the findings raised by the replication check
+const (
+	CheckIDReplicationCritical    = "replication.critical-unprotected"
+	CheckIDReplicationUnversioned = "replication.destination-unversioned"
+	CheckIDReplicationTagsDenied  = "replication.tags-denied"
+)
+
+// GetBucketReplication reads the replication rules of the bucket and the
+// versioning state of every destination. A bucket without a replication
+// configuration is reported as not configured.
+func GetBucketReplication(s3Client S3ClientAPI, bucketName string) (models.ReplicationStatus, error) {
+	var status models.ReplicationStatus
+
+	replicationOutput, err := s3Client.GetBucketReplication(context.Background(), &s3.GetBucketReplicationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if isAPIError(err, "ReplicationConfigurationNotFoundError") {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	config := replicationOutput.ReplicationConfiguration
+	if config == nil {
+		return status, nil
+	}
+	status.Role = aws.ToString(config.Role)
+
+	destinationVersioning := map[string]string{}
+	for _, rule := range config.Rules {
+		r := models.ReplicationRule{
+			ID:      aws.ToString(rule.ID),
+			Enabled: rule.Status == types.ReplicationRuleStatusEnabled,
+		}
+		if dest := rule.Destination; dest != nil {
+			r.DestinationBucket = strings.TrimPrefix(aws.ToString(dest.Bucket), "arn:aws:s3:::")
+			r.DestinationAccount = aws.ToString(dest.Account)
+			r.StorageClass = string(dest.StorageClass)
+			if dest.EncryptionConfiguration != nil {
+				r.ReplicaKMSKeyID = aws.ToString(dest.EncryptionConfiguration.ReplicaKmsKeyID)
+			}
+		}
+		if criteria := rule.SourceSelectionCriteria; criteria != nil && criteria.SseKmsEncryptedObjects != nil {
+			r.ReplicatesKMSObjects = criteria.SseKmsEncryptedObjects.Status == types.SseKmsEncryptedObjectsStatusEnabled
+		}
+		if rule.DeleteMarkerReplication != nil {
+			r.DeleteMarkerReplication = rule.DeleteMarkerReplication.Status == types.DeleteMarkerReplicationStatusEnabled
+		}
+
+		if r.DestinationBucket != "" {
+			versioning, ok := destinationVersioning[r.DestinationBucket]
+			if !ok {
+				// A destination in another account usually denies the lookup,
+				// which leaves its versioning Unknown
+				versioning, _ = GetBucketVersioning(s3Client, r.DestinationBucket)
+				destinationVersioning[r.DestinationBucket] = versioning
+			}
+			r.DestinationVersioning = versioning
+		}
+
+		status.Rules = append(status.Rules, r)
+	}
+	status.Configured = len(status.Rules) > 0
+	return status, nil
+}
+
+// HasBucketTag reports whether the bucket carries the tag key with the value
+func HasBucketTag(s3Client S3ClientAPI, bucketName, key, value string) (bool, error) {
+	taggingOutput, err := s3Client.GetBucketTagging(context.Background(), &s3.GetBucketTaggingInput{
+		Bucket: aws.String(bucketName),
+	})
+	if isAPIError(err, "NoSuchTagSet") {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+
+	for _, tag := range taggingOutput.TagSet {
+		if aws.ToString(tag.Key) == key && aws.ToString(tag.Value) == value {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// CheckBucketReplication reports the replication rules of the bucket, with
+// findings for destinations that are no longer versioned and for buckets
+// tagged as critical (criticalKey=criticalValue) that are neither replicated
+// nor backed up. backupProtected holds the ARNs of buckets with AWS Backup
+// recovery points; nil means AWS Backup could not be consulted.
+func CheckBucketReplication(s3Client S3ClientAPI, bucketName, criticalKey, criticalValue string, backupProtected map[string]bool) (models.ReplicationStatus, []models.Finding, error) {
+	status, err := GetBucketReplication(s3Client, bucketName)
+	if err != nil {
+		return status, nil, err
+	}
+	// The tags are only needed to tell whether the bucket is critical, so a
+	// denied lookup leaves it treated as not critical
+	status.Critical, err = HasBucketTag(s3Client, bucketName, criticalKey, criticalValue)
+	if IsAccessDenied(err) {
+		status.TagsDenied = true
+	} else if err != nil {
+		return status, nil, fmt.Errorf("unable to get bucket tags: %w", err)
+	}
+	if backupProtected != nil {
+		protected := backupProtected[models.BucketARN(bucketName)]
+		status.BackupProtected = &protected
+	}
+
+	var findings []models.Finding
+	for _, rule := range status.Rules {
+		if !rule.Enabled || rule.DestinationVersioning == models.VersioningEnabled || rule.DestinationVersioning == models.VersioningUnknown {
+			continue
+		}
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDReplicationUnversioned,
+			Severity:    models.SeverityMedium,
+			Title:       "Replication destination bucket is not versioned",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{fmt.Sprintf("Rule %s replicates to %s, versioning %s", ruleName(rule.ID), rule.DestinationBucket, rule.DestinationVersioning)},
+			Remediation: "Re-enable versioning on the destination bucket; S3 stops replicating to buckets without it",
+		})
+	}
+
+	if (status.Critical || status.TagsDenied) && !status.Replicates() && (status.BackupProtected == nil || !*status.BackupProtected) {
+		evidence := []string{fmt.Sprintf("Bucket is tagged %s=%s", criticalKey, criticalValue)}
+		if status.TagsDenied {
+			evidence = []string{fmt.Sprintf("GetBucketTagging was denied, so whether the bucket is tagged %s=%s is unknown", criticalKey, criticalValue)}
+		}
+		if status.Configured {
+			evidence = append(evidence, "All replication rules are disabled")
+		} else {
+			evidence = append(evidence, "No replication configuration")
+		}
+		if status.BackupProtected == nil {
+			evidence = append(evidence, "AWS Backup coverage could not be checked")
+		} else {
+			evidence = append(evidence, "AWS Backup holds no recovery point for the bucket")
+		}
+		finding := models.Finding{
+			CheckID:     CheckIDReplicationCritical,
+			Severity:    models.SeverityHigh,
+			Title:       "Critical bucket is neither replicated nor backed up",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    evidence,
+			Remediation: "Configure cross-region replication to a versioned bucket or add the bucket to an AWS Backup plan",
+		}
+		if status.TagsDenied {
+			finding.CheckID = CheckIDReplicationTagsDenied
+			finding.Severity = models.SeverityLow
+			finding.Title = "Bucket tags could not be read to tell whether an unreplicated bucket is critical"
+			finding.Remediation = "Grant s3:GetBucketTagging to the auditing principal, or replicate or back up the bucket if it holds critical data"
+		}
+		findings = append(findings, finding)
+	}
+
+	return status, findings, nil
+}
+
+func ruleName(id string) string {
+	if id == "" {
+		return "(unnamed)"
+	}
+	return id
+}
@@ -0,0 +1,174 @@
+package awsutils
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/backup"
+	backuptypes "github.com/aws/aws-sdk-go-v2/service/backup/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type mockBackupClient struct {
+	mock.Mock
+}
+
+func (m *mockBackupClient) ListProtectedResources(ctx context.Context, params *backup.ListProtectedResourcesInput, optFns ...func(*backup.Options)) (*backup.ListProtectedResourcesOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*backup.ListProtectedResourcesOutput), args.Error(1)
+}
+
+func TestCheckBucketReplication(t *testing.T) {
+	crossAccountRule := types.ReplicationRule{
+		ID:     aws.String("dr"),
+		Status: types.ReplicationRuleStatusEnabled,
+		Destination: &types.Destination{
+			Bucket:                  aws.String("arn:aws:s3:::dr-bucket"),
+			Account:                 aws.String("111122223333"),
+			StorageClass:            types.StorageClassStandardIa,
+			EncryptionConfiguration: &types.EncryptionConfiguration{ReplicaKmsKeyID: aws.String("arn:aws:kms:eu-west-1:111122223333:key/replica")},
+		},
+		SourceSelectionCriteria: &types.SourceSelectionCriteria{
+			SseKmsEncryptedObjects: &types.SseKmsEncryptedObjects{Status: types.SseKmsEncryptedObjectsStatusEnabled},
+		},
+		DeleteMarkerReplication: &types.DeleteMarkerReplication{Status: types.DeleteMarkerReplicationStatusEnabled},
+	}
+	suspendedRule := types.ReplicationRule{
+		ID:          aws.String("copy"),
+		Status:      types.ReplicationRuleStatusEnabled,
+		Destination: &types.Destination{Bucket: aws.String("arn:aws:s3:::copy-bucket")},
+	}
+	criticalTags := []types.Tag{{Key: aws.String("data-classification"), Value: aws.String("critical")}}
+
+	tests := []struct {
+		name               string
+		rules              []types.ReplicationRule
+		tags               []types.Tag
+		tagsDenied         bool
+		backupProtected    map[string]bool
+		expectedSummaries  []string
+		expectedChecks     []string
+		expectedBackupFlag *bool
+	}{
+		{
+			name: "Not configured, not critical",
+		},
+		{
+			name:           "Critical bucket without replication or backup",
+			tags:           criticalTags,
+			expectedChecks: []string{CheckIDReplicationCritical},
+		},
+		{
+			name:           "Tags denied on a bucket without replication or backup",
+			tagsDenied:     true,
+			expectedChecks: []string{CheckIDReplicationTagsDenied},
+		},
+		{
+			name:              "Tags denied on a replicated bucket",
+			rules:             []types.ReplicationRule{suspendedRule},
+			tagsDenied:        true,
+			expectedSummaries: []string{"copy -> copy-bucket: versioning Suspended"},
+			expectedChecks:    []string{CheckIDReplicationUnversioned},
+		},
+		{
+			name:               "Critical bucket protected by AWS Backup",
+			tags:               criticalTags,
+			backupProtected:    map[string]bool{"arn:aws:s3:::bucket": true},
+			expectedBackupFlag: aws.Bool(true),
+		},
+		{
+			name:               "Critical bucket replicated cross-account",
+			rules:              []types.ReplicationRule{crossAccountRule},
+			tags:               criticalTags,
+			backupProtected:    map[string]bool{},
+			expectedSummaries:  []string{"dr -> dr-bucket (account 111122223333): versioning Unknown, STANDARD_IA, KMS objects with arn:aws:kms:eu-west-1:111122223333:key/replica, delete markers"},
+			expectedBackupFlag: aws.Bool(false),
+		},
+		{
+			name:              "Destination versioning suspended",
+			rules:             []types.ReplicationRule{suspendedRule},
+			expectedSummaries: []string{"copy -> copy-bucket: versioning Suspended"},
+			expectedChecks:    []string{CheckIDReplicationUnversioned},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			if tt.rules == nil {
+				mockClient.On("GetBucketReplication", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketReplicationOutput{}, &smithy.GenericAPIError{Code: "ReplicationConfigurationNotFoundError"})
+			} else {
+				mockClient.On("GetBucketReplication", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketReplicationOutput{ReplicationConfiguration: &types.ReplicationConfiguration{
+						Role:  aws.String("arn:aws:iam::123456789012:role/replication"),
+						Rules: tt.rules,
+					}}, nil)
+			}
+			if tt.tagsDenied {
+				mockClient.On("GetBucketTagging", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketTaggingOutput{}, &smithy.GenericAPIError{Code: "AccessDenied"})
+			} else if tt.tags == nil {
+				mockClient.On("GetBucketTagging", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketTaggingOutput{}, &smithy.GenericAPIError{Code: "NoSuchTagSet"})
+			} else {
+				mockClient.On("GetBucketTagging", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketTaggingOutput{TagSet: tt.tags}, nil)
+			}
+			mockClient.On("GetBucketVersioning", mock.Anything, mock.MatchedBy(func(in *s3.GetBucketVersioningInput) bool {
+				return aws.ToString(in.Bucket) == "dr-bucket"
+			})).Return(&s3.GetBucketVersioningOutput{}, &smithy.GenericAPIError{Code: "AccessDenied"})
+			mockClient.On("GetBucketVersioning", mock.Anything, mock.MatchedBy(func(in *s3.GetBucketVersioningInput) bool {
+				return aws.ToString(in.Bucket) == "copy-bucket"
+			})).Return(&s3.GetBucketVersioningOutput{Status: types.BucketVersioningStatusSuspended}, nil)
+
+			status, findings, err := CheckBucketReplication(mockClient, "bucket", "data-classification", "critical", tt.backupProtected)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.rules != nil, status.Configured)
+			assert.Equal(t, tt.tags != nil, status.Critical)
+			assert.Equal(t, tt.tagsDenied, status.TagsDenied)
+			assert.Equal(t, tt.expectedBackupFlag, status.BackupProtected)
+			var summaries []string
+			for _, rule := range status.Rules {
+				summaries = append(summaries, rule.Summary())
+			}
+			assert.Equal(t, tt.expectedSummaries, summaries)
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
+
+func TestGetBackupProtectedBuckets(t *testing.T) {
+	mockClient := new(mockBackupClient)
+	mockClient.On("ListProtectedResources", mock.Anything, mock.MatchedBy(func(in *backup.ListProtectedResourcesInput) bool {
+		return in.NextToken == nil
+	})).Return(&backup.ListProtectedResourcesOutput{
+		Results: []backuptypes.ProtectedResource{
+			{ResourceArn: aws.String("arn:aws:s3:::protected"), ResourceType: aws.String("S3")},
+			{ResourceArn: aws.String("arn:aws:ec2:eu-west-1:123456789012:volume/vol-1"), ResourceType: aws.String("EBS")},
+		},
+		NextToken: aws.String("page-2"),
+	}, nil)
+	mockClient.On("ListProtectedResources", mock.Anything, mock.MatchedBy(func(in *backup.ListProtectedResourcesInput) bool {
+		return aws.ToString(in.NextToken) == "page-2"
+	})).Return(&backup.ListProtectedResourcesOutput{
+		Results: []backuptypes.ProtectedResource{
+			{ResourceArn: aws.String("arn:aws:s3:::archive"), ResourceType: aws.String("S3")},
+		},
+	}, nil)
+
+	protected, err := GetBackupProtectedBuckets(mockClient)
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]bool{"arn:aws:s3:::protected": true, "arn:aws:s3:::archive": true}, protected)
+}
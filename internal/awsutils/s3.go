@@ -2,9 +2,12 @@ package awsutils
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
 )
 
@@ -14,8 +17,22 @@ type S3ClientAPI interface {
 	GetBucketLocation(ctx context.Context, params *s3.GetBucketLocationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLocationOutput, error)
 	GetBucketEncryption(ctx context.Context, params *s3.GetBucketEncryptionInput, optFns ...func(*s3.Options)) (*s3.GetBucketEncryptionOutput, error)
 	GetBucketVersioning(ctx context.Context, params *s3.GetBucketVersioningInput, optFns ...func(*s3.Options)) (*s3.GetBucketVersioningOutput, error)
+	GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error)
+	GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error)
 	GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error)
 	GetBucketAcl(ctx context.Context, params *s3.GetBucketAclInput, optFns ...func(*s3.Options)) (*s3.GetBucketAclOutput, error)
+	GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error)
+	GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error)
+	GetBucketCors(ctx context.Context, params *s3.GetBucketCorsInput, optFns ...func(*s3.Options)) (*s3.GetBucketCorsOutput, error)
+	GetBucketWebsite(ctx context.Context, params *s3.GetBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.GetBucketWebsiteOutput, error)
+	GetBucketPolicyStatus(ctx context.Context, params *s3.GetBucketPolicyStatusInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyStatusOutput, error)
+	GetBucketLogging(ctx context.Context, params *s3.GetBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketLoggingOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+	GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	GetBucketReplication(ctx context.Context, params *s3.GetBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error)
+	GetBucketTagging(ctx context.Context, params *s3.GetBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error)
 }
 
 // ListBuckets returns a list of bucket names and their regions
@@ -56,73 +73,497 @@ func GetBucketRegion(s3Client S3ClientAPI, bucketName string) (string, error) {
 	return region, nil
 }
 
-// IsBucketPublic checks if the bucket is publicly accessible
-func IsBucketPublic(s3Client S3ClientAPI, bucketName string) (bool, error) {
-	// Check Public Access Block configuration
+// Check IDs of the findings raised by the S3 checks
+const (
+	CheckIDPublicAccessBlock   = "public.access-block"
+	CheckIDPublicAccessDenied  = "public.access-block-denied"
+	CheckIDPublicACLGrant      = "public.acl-grant"
+	CheckIDEncryption          = "encryption.disabled"
+	CheckIDEncryptionAWSKey    = "encryption.aws-managed-key"
+	CheckIDEncryptionBucketKey = "encryption.bucket-key"
+	CheckIDVersioning          = "versioning.disabled"
+	CheckIDMFADelete           = "versioning.mfa-delete"
+	CheckIDObjectLockMode      = "versioning.object-lock-governance"
+	CheckIDPolicyPublic        = "policy.public"
+	CheckIDPolicyCrossAcct     = "policy.cross-account"
+	CheckIDPolicyDenied        = "policy.denied"
+)
+
+const (
+	allUsersURI           = "http://acs.amazonaws.com/groups/global/AllUsers"
+	authenticatedUsersURI = "http://acs.amazonaws.com/groups/global/AuthenticatedUsers"
+)
+
+// IsBucketPublic checks if the bucket is publicly accessible through its ACL
+// or its policy, under the account-level Block Public Access settings.
+// accountID is the bucket owner's account.
+func IsBucketPublic(s3Client S3ClientAPI, bucketName, accountID string, account models.PublicAccessBlock, accountConfigured bool) (bool, error) {
+	status, err := GetPublicAccessBlockStatus(s3Client, bucketName, account, accountConfigured)
+	if err != nil {
+		return false, err
+	}
+	// RestrictPublicBuckets limits a public policy to AWS services and
+	// principals of the bucket owner account
+	public, _, err := CheckBucketPublicAccess(s3Client, bucketName, status)
+	if err != nil || public || status.Effective.RestrictPublicBuckets {
+		return public, err
+	}
+
+	document, err := ReadBucketPolicy(s3Client, bucketName)
+	if err != nil {
+		return false, err
+	}
+	if document.Denied {
+		return false, errors.New("bucket policy could not be read")
+	}
+	access, _ := CheckBucketPolicy(s3Client, bucketName, accountID, document)
+	return access == PolicyAccessPublic, nil
+}
+
+// GetPublicAccessBlockStatus reads the bucket's Block Public Access settings
+// and combines them with the account-level ones. A bucket without settings
+// has none configured; any other failure, such as access denied, is returned
+// along with the status of the account settings alone.
+func GetPublicAccessBlockStatus(s3Client S3ClientAPI, bucketName string, account models.PublicAccessBlock, accountConfigured bool) (models.PublicAccessBlockStatus, error) {
+	var bucket models.PublicAccessBlock
+	bucketConfigured := false
+
 	pabOutput, err := s3Client.GetPublicAccessBlock(context.Background(), &s3.GetPublicAccessBlockInput{
 		Bucket: aws.String(bucketName),
 	})
-	if err == nil {
+	if err != nil && !isAPIError(err, "NoSuchPublicAccessBlockConfiguration") {
+		return models.NewPublicAccessBlockStatus(account, accountConfigured, bucket, false), err
+	}
+	if err == nil && pabOutput.PublicAccessBlockConfiguration != nil {
 		config := pabOutput.PublicAccessBlockConfiguration
-		if config != nil &&
-			aws.ToBool(config.BlockPublicAcls) &&
-			aws.ToBool(config.BlockPublicPolicy) &&
-			aws.ToBool(config.IgnorePublicAcls) &&
-			aws.ToBool(config.RestrictPublicBuckets) {
-			return false, nil
+		bucket = models.PublicAccessBlock{
+			BlockPublicAcls:       aws.ToBool(config.BlockPublicAcls),
+			IgnorePublicAcls:      aws.ToBool(config.IgnorePublicAcls),
+			BlockPublicPolicy:     aws.ToBool(config.BlockPublicPolicy),
+			RestrictPublicBuckets: aws.ToBool(config.RestrictPublicBuckets),
+		}
+		bucketConfigured = true
+	}
+
+	return models.NewPublicAccessBlockStatus(account, accountConfigured, bucket, bucketConfigured), nil
+}
+
+// CheckBucketPublicAccess checks if the bucket is publicly accessible under
+// the given Block Public Access status and returns findings for every ACL
+// grant or Public Access Block gap involved. When the bucket settings could
+// not be read, the gap is reported as unknown rather than as disabled.
+func CheckBucketPublicAccess(s3Client S3ClientAPI, bucketName string, status models.PublicAccessBlockStatus) (bool, []models.Finding, error) {
+	if status.Effective.AllEnabled() {
+		return false, nil, nil
+	}
+
+	if status.BucketDenied {
+		evidence := []string{"GetPublicAccessBlock was denied"}
+		if status.AccountConfigured {
+			evidence = append(evidence, fmt.Sprintf("Account-level settings: %s", status.Account.Summary()))
+		} else {
+			evidence = append(evidence, "No account-level Public Access Block configuration")
 		}
+		return checkBucketACL(s3Client, bucketName, status, []models.Finding{{
+			CheckID:     CheckIDPublicAccessDenied,
+			Severity:    models.SeverityLow,
+			Title:       "Bucket Block Public Access settings could not be checked",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    evidence,
+			Remediation: "Grant s3:GetBucketPublicAccessBlock to the auditing principal",
+		}})
+	}
+
+	var evidence []string
+	for _, name := range status.Effective.Disabled() {
+		evidence = append(evidence, fmt.Sprintf("%s=false", name))
 	}
+	if !status.BucketConfigured {
+		evidence = append(evidence, "No bucket-level Public Access Block configuration")
+	}
+	if status.AccountConfigured {
+		evidence = append(evidence, fmt.Sprintf("Account-level settings: %s", status.Account.Summary()))
+	} else {
+		evidence = append(evidence, "No account-level Public Access Block configuration")
+	}
+	findings := []models.Finding{{
+		CheckID:     CheckIDPublicAccessBlock,
+		Severity:    models.SeverityMedium,
+		Title:       "Public Access Block is not fully enabled",
+		Resource:    models.BucketARN(bucketName),
+		Evidence:    evidence,
+		Remediation: "Enable all four Block Public Access settings on the bucket or the account",
+	}}
+	return checkBucketACL(s3Client, bucketName, status, findings)
+}
 
-	// Check bucket ACL
+// checkBucketACL adds a finding to findings for every ACL grant to a public
+// group and reports whether any of them makes the bucket public
+func checkBucketACL(s3Client S3ClientAPI, bucketName string, status models.PublicAccessBlockStatus, findings []models.Finding) (bool, []models.Finding, error) {
 	aclOutput, err := s3Client.GetBucketAcl(context.Background(), &s3.GetBucketAclInput{
 		Bucket: aws.String(bucketName),
 	})
 	if err != nil {
-		return false, err
+		return false, findings, err
 	}
 
+	public := false
 	for _, grant := range aclOutput.Grants {
-		if grant.Grantee != nil && grant.Grantee.URI != nil {
-			if *grant.Grantee.URI == "http://acs.amazonaws.com/groups/global/AllUsers" ||
-				*grant.Grantee.URI == "http://acs.amazonaws.com/groups/global/AuthenticatedUsers" {
-				return true, nil
-			}
+		if grant.Grantee == nil || grant.Grantee.URI == nil {
+			continue
+		}
+		uri := *grant.Grantee.URI
+		if uri != allUsersURI && uri != authenticatedUsersURI {
+			continue
+		}
+
+		grantEvidence := fmt.Sprintf("Grant %s to %s", grant.Permission, uri)
+		if status.Effective.IgnorePublicAcls {
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDPublicACLGrant,
+				Severity:    models.SeverityLow,
+				Title:       "Bucket ACL grants access to a public group but IgnorePublicAcls is enabled",
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    []string{grantEvidence},
+				Remediation: "Remove the grant so the bucket stays private if IgnorePublicAcls is ever disabled",
+			})
+			continue
 		}
+
+		public = true
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDPublicACLGrant,
+			Severity:    models.SeverityHigh,
+			Title:       "Bucket ACL grants access to a public group",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{grantEvidence},
+			Remediation: "Remove the grant from the bucket ACL or disable ACLs with Object Ownership set to BucketOwnerEnforced",
+		})
 	}
 
-	return false, nil
+	return public, findings, nil
 }
 
-// GetBucketEncryption checks if server-side encryption is enabled
+// GetBucketEncryption summarises the default encryption of a bucket. A bucket
+// without a configuration is "Not Enabled"; failures are reported as
+// "Access Denied" or "Unknown" along with the error.
 func GetBucketEncryption(s3Client S3ClientAPI, bucketName string) (string, error) {
+	status, _, err := CheckBucketEncryption(s3Client, nil, bucketName, nil)
+	if IsAccessDenied(err) {
+		return "Access Denied", err
+	}
+	if err != nil {
+		return "Unknown", err
+	}
+	return status.Summary(), nil
+}
+
+// CheckBucketEncryption reads every default encryption rule of the bucket,
+// resolves who manages the KMS keys involved and whether the bucket policy
+// denies unencrypted uploads. A bucket without an encryption configuration
+// is reported through a finding; any other failure, such as access denied,
+// is returned as an error. kmsClient may be nil, and so may document, which
+// leaves DeniesUnencryptedUploads unknown.
+func CheckBucketEncryption(s3Client S3ClientAPI, kmsClient KMSClientAPI, bucketName string, document *PolicyDocument) (models.EncryptionStatus, []models.Finding, error) {
+	var status models.EncryptionStatus
+	var findings []models.Finding
+
 	encryptionOutput, err := s3Client.GetBucketEncryption(context.Background(), &s3.GetBucketEncryptionInput{
 		Bucket: aws.String(bucketName),
 	})
-	if err != nil {
-		return "Not Enabled", err
+	if err != nil && !isAPIError(err, "ServerSideEncryptionConfigurationNotFoundError") {
+		return status, nil, err
+	}
+	if err == nil && encryptionOutput.ServerSideEncryptionConfiguration != nil {
+		for _, rule := range encryptionOutput.ServerSideEncryptionConfiguration.Rules {
+			if rule.ApplyServerSideEncryptionByDefault == nil {
+				continue
+			}
+			def := rule.ApplyServerSideEncryptionByDefault
+			r := models.EncryptionRule{
+				Algorithm:        string(def.SSEAlgorithm),
+				BucketKeyEnabled: aws.ToBool(rule.BucketKeyEnabled),
+				DualLayer:        def.SSEAlgorithm == types.ServerSideEncryptionAwsKmsDsse,
+			}
+			if def.SSEAlgorithm == types.ServerSideEncryptionAwsKms || def.SSEAlgorithm == types.ServerSideEncryptionAwsKmsDsse {
+				r.KeyManager, r.KMSKeyID = GetKMSKeyManager(kmsClient, aws.ToString(def.KMSMasterKeyID))
+			}
+			status.Rules = append(status.Rules, r)
+		}
+		status.Configured = len(status.Rules) > 0
+	}
+
+	// The policy is only consulted for extra detail, so failing to read it
+	// leaves DeniesUnencryptedUploads unknown rather than failing the check
+	if document != nil && !document.Denied {
+		denies := document.Policy != nil && document.Policy.DeniesUnencryptedUploads()
+		status.DeniesUnencryptedUploads = &denies
+	}
+
+	if !status.Configured {
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDEncryption,
+			Severity:    models.SeverityMedium,
+			Title:       "Default server-side encryption is not enabled",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{"No default encryption rule in the bucket encryption configuration"},
+			Remediation: "Configure default encryption with SSE-S3 or SSE-KMS",
+		})
+		return status, findings, nil
 	}
 
-	if len(encryptionOutput.ServerSideEncryptionConfiguration.Rules) > 0 {
-		return string(encryptionOutput.ServerSideEncryptionConfiguration.Rules[0].ApplyServerSideEncryptionByDefault.SSEAlgorithm), nil
+	for _, rule := range status.Rules {
+		if rule.KeyManager == models.KeyManagerAWS {
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDEncryptionAWSKey,
+				Severity:    models.SeverityLow,
+				Title:       "Bucket is encrypted with the AWS managed KMS key",
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    []string{fmt.Sprintf("SSEAlgorithm=%s, KMSMasterKeyID=%s", rule.Algorithm, keyOrDefault(rule.KMSKeyID))},
+				Remediation: "Use a customer managed KMS key to control the key policy, rotation and cross-account use",
+			})
+		}
+		// S3 Bucket Keys only apply to SSE-KMS, not to DSSE-KMS
+		if rule.Algorithm == string(types.ServerSideEncryptionAwsKms) && !rule.BucketKeyEnabled {
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDEncryptionBucketKey,
+				Severity:    models.SeverityLow,
+				Title:       "S3 Bucket Keys are not enabled for KMS encryption",
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    []string{fmt.Sprintf("SSEAlgorithm=%s, BucketKeyEnabled=false", rule.Algorithm)},
+				Remediation: "Enable S3 Bucket Keys to reduce KMS request costs",
+			})
+		}
 	}
 
-	return "Not Enabled", nil
+	return status, findings, nil
 }
 
-// GetBucketVersioning checks if versioning is enabled
+func keyOrDefault(keyID string) string {
+	if keyID == "" {
+		return "(default aws/s3 key)"
+	}
+	return keyID
+}
+
+// GetBucketVersioning returns the versioning state of the bucket: Enabled,
+// Suspended or Disabled
 func GetBucketVersioning(s3Client S3ClientAPI, bucketName string) (string, error) {
+	status, err := getVersioningStatus(s3Client, bucketName)
+	if err != nil {
+		return models.VersioningUnknown, err
+	}
+	return status.Status, nil
+}
+
+func getVersioningStatus(s3Client S3ClientAPI, bucketName string) (models.VersioningStatus, error) {
 	versioningOutput, err := s3Client.GetBucketVersioning(context.Background(), &s3.GetBucketVersioningInput{
 		Bucket: aws.String(bucketName),
 	})
 	if err != nil {
-		return "Unknown", err
+		return models.VersioningStatus{}, err
+	}
+
+	status := models.VersioningStatus{
+		Status:    string(versioningOutput.Status),
+		MFADelete: versioningOutput.MFADelete == types.MFADeleteStatusEnabled,
+	}
+	if status.Status == "" {
+		status.Status = models.VersioningDisabled
+	}
+	return status, nil
+}
+
+// GetObjectLockStatus reads the Object Lock configuration of the bucket. A
+// bucket created without Object Lock is reported as not enabled.
+func GetObjectLockStatus(s3Client S3ClientAPI, bucketName string) (models.ObjectLockStatus, error) {
+	var status models.ObjectLockStatus
+
+	lockOutput, err := s3Client.GetObjectLockConfiguration(context.Background(), &s3.GetObjectLockConfigurationInput{
+		Bucket: aws.String(bucketName),
+	})
+	if isAPIError(err, "ObjectLockConfigurationNotFoundError") {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	config := lockOutput.ObjectLockConfiguration
+	if config == nil || config.ObjectLockEnabled != types.ObjectLockEnabledEnabled {
+		return status, nil
+	}
+	status.Enabled = true
+	if config.Rule != nil && config.Rule.DefaultRetention != nil {
+		retention := config.Rule.DefaultRetention
+		status.Mode = string(retention.Mode)
+		status.Days = aws.ToInt32(retention.Days)
+		status.Years = aws.ToInt32(retention.Years)
+	}
+	return status, nil
+}
+
+// CheckBucketVersioning reports the versioning state, MFA Delete and Object
+// Lock configuration of the bucket, with findings for versioning that is not
+// enabled, MFA Delete that is off and retention that can be bypassed
+func CheckBucketVersioning(s3Client S3ClientAPI, bucketName string) (models.VersioningStatus, []models.Finding, error) {
+	status, err := getVersioningStatus(s3Client, bucketName)
+	if err != nil {
+		return status, nil, err
+	}
+	lock, err := GetObjectLockStatus(s3Client, bucketName)
+	if err != nil {
+		return status, nil, fmt.Errorf("unable to get Object Lock configuration: %w", err)
+	}
+	status.ObjectLock = &lock
+
+	var findings []models.Finding
+	if status.Status != models.VersioningEnabled {
+		evidence := "Status is not set"
+		if status.Status == models.VersioningSuspended {
+			evidence = fmt.Sprintf("Status=%s", status.Status)
+		}
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDVersioning,
+			Severity:    models.SeverityLow,
+			Title:       "Versioning is not enabled",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{evidence},
+			Remediation: "Enable versioning to protect objects from accidental overwrites and deletes",
+		})
+	} else if !status.MFADelete {
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDMFADelete,
+			Severity:    models.SeverityLow,
+			Title:       "MFA Delete is not enabled",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{"MFADelete is not Enabled in the versioning configuration"},
+			Remediation: "Enable MFA Delete with the root account so object versions cannot be permanently deleted with stolen credentials",
+		})
+	}
+
+	if lock.Mode == string(types.ObjectLockRetentionModeGovernance) {
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDObjectLockMode,
+			Severity:    models.SeverityLow,
+			Title:       "Object Lock default retention uses governance mode",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{fmt.Sprintf("Mode=%s, retention %s", lock.Mode, lock.Retention())},
+			Remediation: "Use COMPLIANCE mode, or tightly restrict s3:BypassGovernanceRetention, so retention cannot be lifted by a compromised principal",
+		})
 	}
 
-	if versioningOutput.Status == "Enabled" {
-		return "Enabled", nil
+	return status, findings, nil
+}
+
+// GetBucketPolicy returns the bucket policy document, or an empty string
+// when the bucket has no policy
+func GetBucketPolicy(s3Client S3ClientAPI, bucketName string) (string, error) {
+	policyOutput, err := s3Client.GetBucketPolicy(context.Background(), &s3.GetBucketPolicyInput{
+		Bucket: aws.String(bucketName),
+	})
+	if isAPIError(err, "NoSuchBucketPolicy") {
+		return "", nil
+	}
+	if err != nil {
+		return "", err
+	}
+	return aws.ToString(policyOutput.Policy), nil
+}
+
+// PolicyDocument is a bucket policy read once and shared by the checks that
+// evaluate it
+type PolicyDocument struct {
+	// Policy is nil when the bucket has no policy or it could not be read
+	Policy *BucketPolicy
+	// Denied is true when GetBucketPolicy was denied
+	Denied bool
+}
+
+// ReadBucketPolicy fetches and parses the bucket policy. A denied read is
+// reported through Denied rather than an error.
+func ReadBucketPolicy(s3Client S3ClientAPI, bucketName string) (PolicyDocument, error) {
+	document, err := GetBucketPolicy(s3Client, bucketName)
+	if IsAccessDenied(err) {
+		return PolicyDocument{Denied: true}, nil
+	}
+	if err != nil || document == "" {
+		return PolicyDocument{}, err
+	}
+
+	policy, err := ParseBucketPolicy(document)
+	if err != nil {
+		return PolicyDocument{}, err
+	}
+	return PolicyDocument{Policy: policy}, nil
+}
+
+// CheckBucketPolicy evaluates the bucket policy and returns the widest access
+// it grants along with a finding for every statement causing exposure. A
+// policy that could not be read is reported as PolicyAccessUnknown with a
+// finding. accountID is the bucket owner's account.
+func CheckBucketPolicy(s3Client S3ClientAPI, bucketName, accountID string, document PolicyDocument) (PolicyAccess, []models.Finding) {
+	if document.Denied {
+		return PolicyAccessUnknown, []models.Finding{{
+			CheckID:     CheckIDPolicyDenied,
+			Severity:    models.SeverityLow,
+			Title:       "Bucket policy could not be checked",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{"GetBucketPolicy was denied"},
+			Remediation: "Grant s3:GetBucketPolicy to the auditing principal",
+		}}
+	}
+	policy := document.Policy
+	if policy == nil {
+		return PolicyAccessNone, nil
+	}
+
+	var findings []models.Finding
+	access := PolicyAccessRestricted
+	for _, result := range policy.Evaluate(accountID) {
+		if result.Access.Wider(access) {
+			access = result.Access
+		}
+		switch result.Access {
+		case PolicyAccessPublic:
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDPolicyPublic,
+				Severity:    models.SeverityHigh,
+				Title:       "Bucket policy allows public access",
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    []string{fmt.Sprintf("Statement %s: %s", result.Statement, result.Reason)},
+				Remediation: "Restrict the statement principal or add a condition such as aws:PrincipalOrgID or aws:SourceVpce",
+			})
+		case PolicyAccessCrossAccount:
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDPolicyCrossAcct,
+				Severity:    models.SeverityMedium,
+				Title:       "Bucket policy grants cross-account access",
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    []string{fmt.Sprintf("Statement %s: %s", result.Statement, result.Reason)},
+				Remediation: "Confirm the external accounts are trusted and limit the granted actions",
+			})
+		}
+	}
+
+	// S3's own evaluation catches cases the parser does not model. It is a
+	// second opinion only, so a failure to fetch it is not an error.
+	statusOutput, err := s3Client.GetBucketPolicyStatus(context.Background(), &s3.GetBucketPolicyStatusInput{
+		Bucket: aws.String(bucketName),
+	})
+	if err == nil && statusOutput.PolicyStatus != nil && aws.ToBool(statusOutput.PolicyStatus.IsPublic) && access != PolicyAccessPublic {
+		access = PolicyAccessPublic
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDPolicyPublic,
+			Severity:    models.SeverityHigh,
+			Title:       "Bucket policy allows public access",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{"GetBucketPolicyStatus reports IsPublic=true"},
+			Remediation: "Restrict the statement principal or add a condition such as aws:PrincipalOrgID or aws:SourceVpce",
+		})
 	}
 
-	return "Disabled", nil
+	return access, findings
 }
 
 // GetBucketNames returns a slice of bucket names
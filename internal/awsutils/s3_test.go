@@ -7,6 +7,10 @@ import (
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	s3controltypes "github.com/aws/aws-sdk-go-v2/service/s3control/types"
+	"github.com/aws/smithy-go"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -37,6 +41,16 @@ func (m *mockS3Client) GetBucketVersioning(ctx context.Context, params *s3.GetBu
 	return args.Get(0).(*s3.GetBucketVersioningOutput), args.Error(1)
 }
 
+func (m *mockS3Client) GetObjectLockConfiguration(ctx context.Context, params *s3.GetObjectLockConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetObjectLockConfigurationOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetObjectLockConfigurationOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketLifecycleConfiguration(ctx context.Context, params *s3.GetBucketLifecycleConfigurationInput, optFns ...func(*s3.Options)) (*s3.GetBucketLifecycleConfigurationOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketLifecycleConfigurationOutput), args.Error(1)
+}
+
 func (m *mockS3Client) GetPublicAccessBlock(ctx context.Context, params *s3.GetPublicAccessBlockInput, optFns ...func(*s3.Options)) (*s3.GetPublicAccessBlockOutput, error) {
 	args := m.Called(ctx, params)
 	return args.Get(0).(*s3.GetPublicAccessBlockOutput), args.Error(1)
@@ -47,6 +61,66 @@ func (m *mockS3Client) GetBucketAcl(ctx context.Context, params *s3.GetBucketAcl
 	return args.Get(0).(*s3.GetBucketAclOutput), args.Error(1)
 }
 
+func (m *mockS3Client) GetBucketOwnershipControls(ctx context.Context, params *s3.GetBucketOwnershipControlsInput, optFns ...func(*s3.Options)) (*s3.GetBucketOwnershipControlsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketOwnershipControlsOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketPolicy(ctx context.Context, params *s3.GetBucketPolicyInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketPolicyOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketPolicyStatus(ctx context.Context, params *s3.GetBucketPolicyStatusInput, optFns ...func(*s3.Options)) (*s3.GetBucketPolicyStatusOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketPolicyStatusOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketCors(ctx context.Context, params *s3.GetBucketCorsInput, optFns ...func(*s3.Options)) (*s3.GetBucketCorsOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketCorsOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketWebsite(ctx context.Context, params *s3.GetBucketWebsiteInput, optFns ...func(*s3.Options)) (*s3.GetBucketWebsiteOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketWebsiteOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketLogging(ctx context.Context, params *s3.GetBucketLoggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketLoggingOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketLoggingOutput), args.Error(1)
+}
+
+func (m *mockS3Client) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.ListObjectsV2Output), args.Error(1)
+}
+
+func (m *mockS3Client) GetObjectAcl(ctx context.Context, params *s3.GetObjectAclInput, optFns ...func(*s3.Options)) (*s3.GetObjectAclOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetObjectAclOutput), args.Error(1)
+}
+
+func (m *mockS3Client) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.HeadObjectOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetObjectOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketReplication(ctx context.Context, params *s3.GetBucketReplicationInput, optFns ...func(*s3.Options)) (*s3.GetBucketReplicationOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketReplicationOutput), args.Error(1)
+}
+
+func (m *mockS3Client) GetBucketTagging(ctx context.Context, params *s3.GetBucketTaggingInput, optFns ...func(*s3.Options)) (*s3.GetBucketTaggingOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3.GetBucketTaggingOutput), args.Error(1)
+}
+
 func TestGetBucketEncryption(t *testing.T) {
 	tests := []struct {
 		name          string
@@ -80,9 +154,29 @@ func TestGetBucketEncryption(t *testing.T) {
 			bucketName: "unencrypted-bucket",
 			mockSetup: func(m *mockS3Client) {
 				m.On("GetBucketEncryption", mock.Anything, mock.Anything).Return(
-					&s3.GetBucketEncryptionOutput{}, &types.NoSuchBucket{})
+					&s3.GetBucketEncryptionOutput{}, &smithy.GenericAPIError{Code: "ServerSideEncryptionConfigurationNotFoundError"})
 			},
 			expectedValue: "Not Enabled",
+			expectError:   false,
+		},
+		{
+			name:       "Lookup failure is not reported as unencrypted",
+			bucketName: "missing-bucket",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketEncryption", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketEncryptionOutput{}, &types.NoSuchBucket{})
+			},
+			expectedValue: "Unknown",
+			expectError:   true,
+		},
+		{
+			name:       "Access denied is not reported as unencrypted",
+			bucketName: "restricted-bucket",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketEncryption", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketEncryptionOutput{}, &smithy.GenericAPIError{Code: "AccessDenied"})
+			},
+			expectedValue: "Access Denied",
 			expectError:   true,
 		},
 	}
@@ -134,6 +228,18 @@ func TestGetBucketVersioning(t *testing.T) {
 			expectedValue: "Disabled",
 			expectError:   false,
 		},
+		{
+			name:       "Bucket with versioning suspended",
+			bucketName: "suspended-bucket",
+			mockSetup: func(m *mockS3Client) {
+				m.On("GetBucketVersioning", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketVersioningOutput{
+						Status: "Suspended",
+					}, nil)
+			},
+			expectedValue: "Suspended",
+			expectError:   false,
+		},
 		{
 			name:       "Error getting versioning status",
 			bucketName: "error-bucket",
@@ -164,9 +270,24 @@ func TestGetBucketVersioning(t *testing.T) {
 }
 
 func TestIsBucketPublic(t *testing.T) {
+	noPolicy := func(m *mockS3Client) {
+		m.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+			&s3.GetBucketPolicyOutput{}, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy"})
+	}
+	publicPolicy := func(m *mockS3Client) {
+		m.On("GetPublicAccessBlock", mock.Anything, mock.Anything).Return(
+			&s3.GetPublicAccessBlockOutput{}, &smithy.GenericAPIError{Code: "NoSuchPublicAccessBlockConfiguration"})
+		m.On("GetBucketAcl", mock.Anything, mock.Anything).Return(&s3.GetBucketAclOutput{}, nil)
+		m.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(&s3.GetBucketPolicyOutput{
+			Policy: aws.String(`{"Statement":[{"Effect":"Allow","Principal":"*","Action":"s3:GetObject","Resource":"arn:aws:s3:::policy-bucket/*"}]}`),
+		}, nil)
+		m.On("GetBucketPolicyStatus", mock.Anything, mock.Anything).Return(&s3.GetBucketPolicyStatusOutput{}, nil)
+	}
+
 	tests := []struct {
 		name          string
 		bucketName    string
+		account       models.PublicAccessBlock
 		mockSetup     func(*mockS3Client)
 		expectedValue bool
 		expectError   bool
@@ -227,10 +348,24 @@ func TestIsBucketPublic(t *testing.T) {
 					&s3.GetBucketAclOutput{
 						Grants: []types.Grant{},
 					}, nil)
+				noPolicy(m)
 			},
 			expectedValue: false,
 			expectError:   false,
 		},
+		{
+			name:          "Bucket with public policy",
+			bucketName:    "policy-bucket",
+			mockSetup:     publicPolicy,
+			expectedValue: true,
+		},
+		{
+			name:          "Public policy restricted by the account settings",
+			bucketName:    "policy-bucket",
+			account:       models.PublicAccessBlock{RestrictPublicBuckets: true},
+			mockSetup:     publicPolicy,
+			expectedValue: false,
+		},
 	}
 
 	for _, tt := range tests {
@@ -238,7 +373,7 @@ func TestIsBucketPublic(t *testing.T) {
 			mockClient := new(mockS3Client)
 			tt.mockSetup(mockClient)
 
-			result, err := IsBucketPublic(mockClient, tt.bucketName)
+			result, err := IsBucketPublic(mockClient, tt.bucketName, ownerAccount, tt.account, true)
 
 			if tt.expectError {
 				assert.Error(t, err)
@@ -249,3 +384,275 @@ func TestIsBucketPublic(t *testing.T) {
 		})
 	}
 }
+
+func TestCheckBucketPublicAccessFindings(t *testing.T) {
+	mockClient := new(mockS3Client)
+	mockClient.On("GetPublicAccessBlock", mock.Anything, mock.Anything).Return(
+		&s3.GetPublicAccessBlockOutput{
+			PublicAccessBlockConfiguration: &types.PublicAccessBlockConfiguration{
+				BlockPublicAcls:       aws.Bool(true),
+				BlockPublicPolicy:     aws.Bool(false),
+				IgnorePublicAcls:      aws.Bool(false),
+				RestrictPublicBuckets: aws.Bool(true),
+			},
+		}, nil)
+	mockClient.On("GetBucketAcl", mock.Anything, mock.Anything).Return(
+		&s3.GetBucketAclOutput{
+			Grants: []types.Grant{
+				{
+					Grantee: &types.Grantee{
+						URI: aws.String("http://acs.amazonaws.com/groups/global/AllUsers"),
+					},
+					Permission: types.PermissionRead,
+				},
+			},
+		}, nil)
+
+	status, err := GetPublicAccessBlockStatus(mockClient, "public-bucket", models.PublicAccessBlock{}, false)
+	assert.NoError(t, err)
+	public, findings, err := CheckBucketPublicAccess(mockClient, "public-bucket", status)
+
+	assert.NoError(t, err)
+	assert.True(t, public)
+	assert.Len(t, findings, 2)
+
+	assert.Equal(t, CheckIDPublicAccessBlock, findings[0].CheckID)
+	assert.Equal(t, models.SeverityMedium, findings[0].Severity)
+	assert.Equal(t, []string{
+		"IgnorePublicAcls=false",
+		"BlockPublicPolicy=false",
+		"No account-level Public Access Block configuration",
+	}, findings[0].Evidence)
+
+	assert.Equal(t, CheckIDPublicACLGrant, findings[1].CheckID)
+	assert.Equal(t, models.SeverityHigh, findings[1].Severity)
+	assert.Equal(t, "arn:aws:s3:::public-bucket", findings[1].Resource)
+	assert.Equal(t, []string{"Grant READ to http://acs.amazonaws.com/groups/global/AllUsers"}, findings[1].Evidence)
+}
+
+func TestCheckBucketVersioningFindings(t *testing.T) {
+	tests := []struct {
+		name           string
+		versioning     *s3.GetBucketVersioningOutput
+		lockSetup      func(*mockS3Client)
+		expectedStatus string
+		expectedLock   models.ObjectLockStatus
+		expectedChecks []string
+	}{
+		{
+			name:       "Suspended versioning",
+			versioning: &s3.GetBucketVersioningOutput{Status: types.BucketVersioningStatusSuspended},
+			lockSetup: func(m *mockS3Client) {
+				m.On("GetObjectLockConfiguration", mock.Anything, mock.Anything).Return(
+					&s3.GetObjectLockConfigurationOutput{}, &smithy.GenericAPIError{Code: "ObjectLockConfigurationNotFoundError"})
+			},
+			expectedStatus: models.VersioningSuspended,
+			expectedChecks: []string{CheckIDVersioning},
+		},
+		{
+			name:       "Versioning without MFA Delete",
+			versioning: &s3.GetBucketVersioningOutput{Status: types.BucketVersioningStatusEnabled},
+			lockSetup: func(m *mockS3Client) {
+				m.On("GetObjectLockConfiguration", mock.Anything, mock.Anything).Return(
+					&s3.GetObjectLockConfigurationOutput{}, &smithy.GenericAPIError{Code: "ObjectLockConfigurationNotFoundError"})
+			},
+			expectedStatus: models.VersioningEnabled,
+			expectedChecks: []string{CheckIDMFADelete},
+		},
+		{
+			name: "Object Lock in compliance mode",
+			versioning: &s3.GetBucketVersioningOutput{
+				Status:    types.BucketVersioningStatusEnabled,
+				MFADelete: types.MFADeleteStatusEnabled,
+			},
+			lockSetup: func(m *mockS3Client) {
+				m.On("GetObjectLockConfiguration", mock.Anything, mock.Anything).Return(&s3.GetObjectLockConfigurationOutput{
+					ObjectLockConfiguration: &types.ObjectLockConfiguration{
+						ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+						Rule: &types.ObjectLockRule{DefaultRetention: &types.DefaultRetention{
+							Mode: types.ObjectLockRetentionModeCompliance,
+							Days: aws.Int32(30),
+						}},
+					},
+				}, nil)
+			},
+			expectedStatus: models.VersioningEnabled,
+			expectedLock:   models.ObjectLockStatus{Enabled: true, Mode: "COMPLIANCE", Days: 30},
+		},
+		{
+			name: "Object Lock in governance mode",
+			versioning: &s3.GetBucketVersioningOutput{
+				Status:    types.BucketVersioningStatusEnabled,
+				MFADelete: types.MFADeleteStatusEnabled,
+			},
+			lockSetup: func(m *mockS3Client) {
+				m.On("GetObjectLockConfiguration", mock.Anything, mock.Anything).Return(&s3.GetObjectLockConfigurationOutput{
+					ObjectLockConfiguration: &types.ObjectLockConfiguration{
+						ObjectLockEnabled: types.ObjectLockEnabledEnabled,
+						Rule: &types.ObjectLockRule{DefaultRetention: &types.DefaultRetention{
+							Mode:  types.ObjectLockRetentionModeGovernance,
+							Years: aws.Int32(1),
+						}},
+					},
+				}, nil)
+			},
+			expectedStatus: models.VersioningEnabled,
+			expectedLock:   models.ObjectLockStatus{Enabled: true, Mode: "GOVERNANCE", Years: 1},
+			expectedChecks: []string{CheckIDObjectLockMode},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			mockClient.On("GetBucketVersioning", mock.Anything, mock.Anything).Return(tt.versioning, nil)
+			tt.lockSetup(mockClient)
+
+			status, findings, err := CheckBucketVersioning(mockClient, "bucket")
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedStatus, status.Status)
+			if assert.NotNil(t, status.ObjectLock) {
+				assert.Equal(t, tt.expectedLock, *status.ObjectLock)
+			}
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
+
+func TestCheckBucketPublicAccessWithAccountSettings(t *testing.T) {
+	publicACL := &s3.GetBucketAclOutput{
+		Grants: []types.Grant{
+			{
+				Grantee: &types.Grantee{
+					URI: aws.String("http://acs.amazonaws.com/groups/global/AllUsers"),
+				},
+				Permission: types.PermissionRead,
+			},
+		},
+	}
+
+	tests := []struct {
+		name             string
+		account          models.PublicAccessBlock
+		expectedPublic   bool
+		expectedFindings []models.Severity
+	}{
+		{
+			name: "Account blocks everything",
+			account: models.PublicAccessBlock{
+				BlockPublicAcls: true, IgnorePublicAcls: true, BlockPublicPolicy: true, RestrictPublicBuckets: true,
+			},
+			expectedPublic: false,
+		},
+		{
+			name:             "Account ignores public ACLs",
+			account:          models.PublicAccessBlock{IgnorePublicAcls: true},
+			expectedPublic:   false,
+			expectedFindings: []models.Severity{models.SeverityMedium, models.SeverityLow},
+		},
+		{
+			name:             "No account settings",
+			account:          models.PublicAccessBlock{},
+			expectedPublic:   true,
+			expectedFindings: []models.Severity{models.SeverityMedium, models.SeverityHigh},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			mockClient.On("GetPublicAccessBlock", mock.Anything, mock.Anything).Return(
+				&s3.GetPublicAccessBlockOutput{}, &smithy.GenericAPIError{Code: "NoSuchPublicAccessBlockConfiguration"})
+			mockClient.On("GetBucketAcl", mock.Anything, mock.Anything).Return(publicACL, nil)
+
+			status, err := GetPublicAccessBlockStatus(mockClient, "bucket", tt.account, true)
+			assert.NoError(t, err)
+			public, findings, err := CheckBucketPublicAccess(mockClient, "bucket", status)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedPublic, public)
+			assert.False(t, status.BucketConfigured)
+			var severities []models.Severity
+			for _, f := range findings {
+				severities = append(severities, f.Severity)
+			}
+			assert.Equal(t, tt.expectedFindings, severities)
+		})
+	}
+}
+
+func TestGetPublicAccessBlockStatus_Errors(t *testing.T) {
+	account := models.PublicAccessBlock{BlockPublicAcls: true}
+
+	// Errors other than a missing configuration are returned, not treated
+	// as an unconfigured bucket
+	throttled := new(mockS3Client)
+	throttled.On("GetPublicAccessBlock", mock.Anything, mock.Anything).Return(
+		&s3.GetPublicAccessBlockOutput{}, &smithy.GenericAPIError{Code: "SlowDown"})
+	_, err := GetPublicAccessBlockStatus(throttled, "bucket", account, true)
+	assert.Error(t, err)
+	assert.False(t, IsAccessDenied(err))
+
+	// A denied read is reported as unknown, not as disabled settings
+	denied := new(mockS3Client)
+	denied.On("GetPublicAccessBlock", mock.Anything, mock.Anything).Return(
+		&s3.GetPublicAccessBlockOutput{}, &smithy.GenericAPIError{Code: "AccessDenied"})
+	denied.On("GetBucketAcl", mock.Anything, mock.Anything).Return(&s3.GetBucketAclOutput{}, nil)
+	status, err := GetPublicAccessBlockStatus(denied, "bucket", account, true)
+	assert.True(t, IsAccessDenied(err))
+	assert.Equal(t, account, status.Effective)
+	status.BucketDenied = true
+
+	public, findings, err := CheckBucketPublicAccess(denied, "bucket", status)
+
+	assert.NoError(t, err)
+	assert.False(t, public)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, CheckIDPublicAccessDenied, findings[0].CheckID)
+	assert.Equal(t, models.SeverityLow, findings[0].Severity)
+}
+
+type mockS3ControlClient struct {
+	mock.Mock
+}
+
+func (m *mockS3ControlClient) GetPublicAccessBlock(ctx context.Context, params *s3control.GetPublicAccessBlockInput, optFns ...func(*s3control.Options)) (*s3control.GetPublicAccessBlockOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*s3control.GetPublicAccessBlockOutput), args.Error(1)
+}
+
+func TestGetAccountPublicAccessBlock(t *testing.T) {
+	t.Run("Configured account", func(t *testing.T) {
+		mockClient := new(mockS3ControlClient)
+		mockClient.On("GetPublicAccessBlock", mock.Anything, mock.Anything).Return(
+			&s3control.GetPublicAccessBlockOutput{
+				PublicAccessBlockConfiguration: &s3controltypes.PublicAccessBlockConfiguration{
+					BlockPublicAcls:   aws.Bool(true),
+					BlockPublicPolicy: aws.Bool(true),
+				},
+			}, nil)
+
+		pab, configured, err := GetAccountPublicAccessBlock(mockClient, "111111111111")
+
+		assert.NoError(t, err)
+		assert.True(t, configured)
+		assert.Equal(t, models.PublicAccessBlock{BlockPublicAcls: true, BlockPublicPolicy: true}, pab)
+	})
+
+	t.Run("Unconfigured account", func(t *testing.T) {
+		mockClient := new(mockS3ControlClient)
+		mockClient.On("GetPublicAccessBlock", mock.Anything, mock.Anything).Return(
+			&s3control.GetPublicAccessBlockOutput{}, &smithy.GenericAPIError{Code: "NoSuchPublicAccessBlockConfiguration"})
+
+		pab, configured, err := GetAccountPublicAccessBlock(mockClient, "111111111111")
+
+		assert.NoError(t, err)
+		assert.False(t, configured)
+		assert.Equal(t, models.PublicAccessBlock{}, pab)
+	})
+}
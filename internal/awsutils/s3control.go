@@ -0,0 +1,39 @@
+package awsutils
+
+import (
+	"context"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// S3ControlClientAPI defines the interface for S3 Control operations we use
+type S3ControlClientAPI interface {
+	GetPublicAccessBlock(ctx context.Context, params *s3control.GetPublicAccessBlockInput, optFns ...func(*s3control.Options)) (*s3control.GetPublicAccessBlockOutput, error)
+}
+
+// GetAccountPublicAccessBlock returns the account-wide Block Public Access
+// settings and whether the account has them configured at all
+func GetAccountPublicAccessBlock(client S3ControlClientAPI, accountID string) (models.PublicAccessBlock, bool, error) {
+	output, err := client.GetPublicAccessBlock(context.Background(), &s3control.GetPublicAccessBlockInput{
+		AccountId: aws.String(accountID),
+	})
+	if isAPIError(err, "NoSuchPublicAccessBlockConfiguration") {
+		return models.PublicAccessBlock{}, false, nil
+	}
+	if err != nil {
+		return models.PublicAccessBlock{}, false, err
+	}
+
+	config := output.PublicAccessBlockConfiguration
+	if config == nil {
+		return models.PublicAccessBlock{}, false, nil
+	}
+	return models.PublicAccessBlock{
+		BlockPublicAcls:       aws.ToBool(config.BlockPublicAcls),
+		IgnorePublicAcls:      aws.ToBool(config.IgnorePublicAcls),
+		BlockPublicPolicy:     aws.ToBool(config.BlockPublicPolicy),
+		RestrictPublicBuckets: aws.ToBool(config.RestrictPublicBuckets),
+	}, true, nil
+}
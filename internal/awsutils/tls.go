@@ -0,0 +1,205 @@
+package awsutils
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Check IDs of the findings raised by the TLS check
+const (
+	CheckIDTLSNotEnforced = "tls.not-enforced"
+	CheckIDTLSPartial     = "tls.partial"
+	CheckIDTLSMinVersion  = "tls.min-version"
+	CheckIDTLSDenied      = "tls.denied"
+)
+
+// recommendedTLSVersion is the lowest s3:TlsVersion we accept as a minimum
+const recommendedTLSVersion = 1.2
+
+// CheckBucketTLS reports whether the bucket policy denies requests made
+// without TLS and which TLS version it requires at least. A policy that could
+// not be read is reported through a finding.
+func CheckBucketTLS(bucketName string, document PolicyDocument) (models.TLSStatus, []models.Finding) {
+	if document.Denied {
+		return models.TLSStatus{Denied: true}, []models.Finding{{
+			CheckID:     CheckIDTLSDenied,
+			Severity:    models.SeverityLow,
+			Title:       "TLS enforcement could not be checked",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{"GetBucketPolicy was denied"},
+			Remediation: "Grant s3:GetBucketPolicy to the auditing principal",
+		}}
+	}
+
+	policy := document.Policy
+	if policy == nil {
+		policy = &BucketPolicy{}
+	}
+	status := policy.TLSEnforcement(bucketName)
+
+	var findings []models.Finding
+	switch {
+	case status.Partial:
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDTLSPartial,
+			Severity:    models.SeverityMedium,
+			Title:       "Bucket policy only partially enforces TLS",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    status.Gaps,
+			Remediation: "Deny s3:* for Principal \"*\" on both the bucket and bucket/* ARNs when aws:SecureTransport is false",
+		})
+	case !status.Enforced:
+		evidence := "No Deny statement with Bool aws:SecureTransport = false"
+		if document.Policy == nil {
+			evidence = "Bucket has no policy"
+		}
+		findings = append(findings, models.Finding{
+			CheckID:     CheckIDTLSNotEnforced,
+			Severity:    models.SeverityMedium,
+			Title:       "Bucket accepts requests over plain HTTP",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    []string{evidence},
+			Remediation: "Deny s3:* for Principal \"*\" on both the bucket and bucket/* ARNs when aws:SecureTransport is false, and when s3:TlsVersion is less than 1.2",
+		})
+	}
+
+	// A minimum version only matters once TLS itself is required
+	if status.Enforced || status.Partial {
+		version, _ := strconv.ParseFloat(status.MinTLSVersion, 64)
+		if version < recommendedTLSVersion {
+			evidence := "No Deny statement with NumericLessThan s3:TlsVersion"
+			if status.MinTLSVersion != "" {
+				evidence = fmt.Sprintf("Minimum TLS version is %s", status.MinTLSVersion)
+			}
+			findings = append(findings, models.Finding{
+				CheckID:     CheckIDTLSMinVersion,
+				Severity:    models.SeverityLow,
+				Title:       "Bucket policy does not require TLS 1.2 or later",
+				Resource:    models.BucketARN(bucketName),
+				Evidence:    []string{evidence},
+				Remediation: "Deny requests when s3:TlsVersion is NumericLessThan 1.2",
+			})
+		}
+	}
+
+	return status, findings
+}
+
+// TLSEnforcement evaluates the Deny statements that refuse insecure transport.
+// Enforcement is complete when, taken together, statements denying every
+// action to every principal cover both the bucket and object ARNs.
+func (b *BucketPolicy) TLSEnforcement(bucketName string) models.TLSStatus {
+	var status models.TLSStatus
+	bucketARN := models.BucketARN(bucketName)
+	objectARN := bucketARN + "/*"
+
+	found := false
+	var bucketCovered, objectsCovered bool
+	var gaps []string
+	var minVersion float64
+	for i, st := range b.Statements {
+		if !strings.EqualFold(st.Effect, "Deny") {
+			continue
+		}
+		allPrincipals := st.NotPrincipal == nil && st.Principal != nil && st.Principal.Wildcard
+
+		if version, ok := st.minTLSVersion(); ok && allPrincipals && st.coversResource(objectARN) && version > minVersion {
+			minVersion = version
+			status.MinTLSVersion = strconv.FormatFloat(version, 'f', -1, 64)
+		}
+
+		if !st.deniesInsecureTransport() {
+			continue
+		}
+		found = true
+		switch {
+		case !allPrincipals:
+			gaps = append(gaps, fmt.Sprintf("Statement %s applies to specific principals only", st.ID(i)))
+		case !st.coversAllActions():
+			gaps = append(gaps, fmt.Sprintf("Statement %s only denies %s", st.ID(i), strings.Join(st.Action, ", ")))
+		default:
+			bucketCovered = bucketCovered || st.coversResource(bucketARN)
+			objectsCovered = objectsCovered || st.coversResource(objectARN)
+		}
+	}
+	if !found {
+		return status
+	}
+
+	status.Enforced = bucketCovered && objectsCovered
+	if status.Enforced {
+		return status
+	}
+	if !bucketCovered {
+		gaps = append(gaps, fmt.Sprintf("Requests to %s are not covered", bucketARN))
+	}
+	if !objectsCovered {
+		gaps = append(gaps, fmt.Sprintf("Requests to %s are not covered", objectARN))
+	}
+	status.Partial = true
+	status.Gaps = gaps
+	return status
+}
+
+// deniesInsecureTransport reports whether the statement matches requests
+// sent without TLS
+func (st PolicyStatement) deniesInsecureTransport() bool {
+	for operator, keys := range st.Condition {
+		op := strings.ToLower(operator)
+		if op != "bool" && op != "boolifexists" {
+			continue
+		}
+		for key, values := range keys {
+			if strings.EqualFold(key, "aws:SecureTransport") && containsString(values, "false") {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// minTLSVersion returns the version below which the statement matches requests
+func (st PolicyStatement) minTLSVersion() (float64, bool) {
+	for operator, keys := range st.Condition {
+		if !strings.EqualFold(operator, "NumericLessThan") {
+			continue
+		}
+		for key, values := range keys {
+			if !strings.EqualFold(key, "s3:TlsVersion") || len(values) != 1 {
+				continue
+			}
+			if version, err := strconv.ParseFloat(values[0], 64); err == nil {
+				return version, true
+			}
+		}
+	}
+	return 0, false
+}
+
+// coversAllActions reports whether the statement applies to every S3 action
+func (st PolicyStatement) coversAllActions() bool {
+	for _, a := range st.Action {
+		if a == "*" || strings.EqualFold(a, "s3:*") {
+			return true
+		}
+	}
+	return false
+}
+
+// coversResource reports whether one of the statement's resources matches
+// the ARN, where "*" and "?" in the resource match any characters
+func (st PolicyStatement) coversResource(arn string) bool {
+	for _, resource := range st.Resource {
+		pattern := regexp.QuoteMeta(resource)
+		pattern = strings.ReplaceAll(pattern, `\*`, ".*")
+		pattern = strings.ReplaceAll(pattern, `\?`, ".")
+		if ok, _ := regexp.MatchString("^"+pattern+"$", arn); ok {
+			return true
+		}
+	}
+	return false
+}
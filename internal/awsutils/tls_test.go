@@ -0,0 +1,108 @@
+package awsutils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckBucketTLS(t *testing.T) {
+	tests := []struct {
+		name             string
+		policy           string
+		expectedEnforced bool
+		expectedPartial  bool
+		expectedVersion  string
+		expectedChecks   []string
+	}{
+		{
+			name:           "No bucket policy",
+			expectedChecks: []string{CheckIDTLSNotEnforced},
+		},
+		{
+			name: "Policy without a SecureTransport deny",
+			policy: `{"Statement": [{"Effect": "Allow", "Principal": {"AWS": "arn:aws:iam::111111111111:root"},
+				"Action": "s3:GetObject", "Resource": "arn:aws:s3:::data/*"}]}`,
+			expectedChecks: []string{CheckIDTLSNotEnforced},
+		},
+		{
+			name: "Full enforcement with minimum TLS 1.2",
+			policy: `{"Statement": [
+				{"Sid": "DenyHTTP", "Effect": "Deny", "Principal": "*", "Action": "s3:*",
+				 "Resource": ["arn:aws:s3:::data", "arn:aws:s3:::data/*"],
+				 "Condition": {"Bool": {"aws:SecureTransport": false}}},
+				{"Sid": "DenyOldTLS", "Effect": "Deny", "Principal": "*", "Action": "s3:*",
+				 "Resource": ["arn:aws:s3:::data", "arn:aws:s3:::data/*"],
+				 "Condition": {"NumericLessThan": {"s3:TlsVersion": 1.2}}}]}`,
+			expectedEnforced: true,
+			expectedVersion:  "1.2",
+		},
+		{
+			name: "Enforcement without a minimum version",
+			policy: `{"Statement": {"Effect": "Deny", "Principal": {"AWS": "*"}, "Action": "*",
+				"Resource": "arn:aws:s3:::data*", "Condition": {"Bool": {"aws:SecureTransport": "false"}}}}`,
+			expectedEnforced: true,
+			expectedChecks:   []string{CheckIDTLSMinVersion},
+		},
+		{
+			name: "Only the object ARN is covered",
+			policy: `{"Statement": {"Effect": "Deny", "Principal": "*", "Action": "s3:*",
+				"Resource": "arn:aws:s3:::data/*", "Condition": {"Bool": {"aws:SecureTransport": "false"}}}}`,
+			expectedPartial: true,
+			expectedChecks:  []string{CheckIDTLSPartial, CheckIDTLSMinVersion},
+		},
+		{
+			name: "Deny limited to some actions",
+			policy: `{"Statement": {"Effect": "Deny", "Principal": "*", "Action": ["s3:GetObject"],
+				"Resource": ["arn:aws:s3:::data", "arn:aws:s3:::data/*"],
+				"Condition": {"Bool": {"aws:SecureTransport": "false"}}}}`,
+			expectedPartial: true,
+			expectedChecks:  []string{CheckIDTLSPartial, CheckIDTLSMinVersion},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			if tt.policy == "" {
+				mockClient.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{}, &smithy.GenericAPIError{Code: "NoSuchBucketPolicy"})
+			} else {
+				mockClient.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketPolicyOutput{Policy: aws.String(tt.policy)}, nil)
+			}
+
+			document, err := ReadBucketPolicy(mockClient, "data")
+			assert.NoError(t, err)
+			status, findings := CheckBucketTLS("data", document)
+
+			assert.Equal(t, tt.expectedEnforced, status.Enforced)
+			assert.Equal(t, tt.expectedPartial, status.Partial)
+			assert.Equal(t, tt.expectedVersion, status.MinTLSVersion)
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
+
+func TestCheckBucketTLS_AccessDenied(t *testing.T) {
+	mockClient := new(mockS3Client)
+	mockClient.On("GetBucketPolicy", mock.Anything, mock.Anything).Return(
+		&s3.GetBucketPolicyOutput{}, &smithy.GenericAPIError{Code: "AccessDenied"})
+
+	document, err := ReadBucketPolicy(mockClient, "data")
+	assert.NoError(t, err)
+	status, findings := CheckBucketTLS("data", document)
+
+	assert.True(t, status.Denied)
+	assert.False(t, status.Enforced)
+	assert.Len(t, findings, 1)
+	assert.Equal(t, CheckIDTLSDenied, findings[0].CheckID)
+}
@@ -0,0 +1,131 @@
+package awsutils
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Check IDs of the findings raised by the website check
+const (
+	CheckIDWebsitePublic  = "website.public"
+	CheckIDWebsiteEnabled = "website.enabled"
+)
+
+// GetBucketWebsite reads the static website hosting configuration of the
+// bucket. A bucket without one is reported as not enabled.
+func GetBucketWebsite(s3Client S3ClientAPI, bucketName string) (models.WebsiteStatus, error) {
+	var status models.WebsiteStatus
+
+	websiteOutput, err := s3Client.GetBucketWebsite(context.Background(), &s3.GetBucketWebsiteInput{
+		Bucket: aws.String(bucketName),
+	})
+	if isAPIError(err, "NoSuchWebsiteConfiguration") {
+		return status, nil
+	}
+	if err != nil {
+		return status, err
+	}
+
+	status.Enabled = true
+	if websiteOutput.IndexDocument != nil {
+		status.IndexDocument = aws.ToString(websiteOutput.IndexDocument.Suffix)
+	}
+	if websiteOutput.ErrorDocument != nil {
+		status.ErrorDocument = aws.ToString(websiteOutput.ErrorDocument.Key)
+	}
+	if redirect := websiteOutput.RedirectAllRequestsTo; redirect != nil {
+		status.RedirectAllTo = aws.ToString(redirect.HostName)
+		if redirect.Protocol != "" {
+			status.RedirectAllTo = fmt.Sprintf("%s://%s", redirect.Protocol, status.RedirectAllTo)
+		}
+	}
+	for _, rule := range websiteOutput.RoutingRules {
+		status.RoutingRules = append(status.RoutingRules, routingRuleSummary(rule))
+	}
+	return status, nil
+}
+
+// CheckBucketWebsite reads the website configuration and combines it with
+// whether the bucket is publicly readable, which is nil when public access
+// was not checked. A public website is reported as exposed; a private one as
+// an endpoint that cannot serve content.
+func CheckBucketWebsite(s3Client S3ClientAPI, bucketName string, public *bool) (models.WebsiteStatus, []models.Finding, error) {
+	status, err := GetBucketWebsite(s3Client, bucketName)
+	if err != nil {
+		return status, nil, err
+	}
+	if !status.Enabled {
+		return status, nil, nil
+	}
+	status.PubliclyReadable = public
+
+	evidence := []string{"GetBucketWebsite returned a website configuration"}
+	if status.RedirectAllTo != "" {
+		evidence = append(evidence, fmt.Sprintf("All requests redirect to %s", status.RedirectAllTo))
+	}
+	if len(status.RoutingRules) > 0 {
+		evidence = append(evidence, fmt.Sprintf("%d routing rule(s): %s", len(status.RoutingRules), strings.Join(status.RoutingRules, "; ")))
+	}
+
+	if public != nil && *public {
+		return status, []models.Finding{{
+			CheckID:     CheckIDWebsitePublic,
+			Severity:    models.SeverityHigh,
+			Title:       "Bucket is website-hosted and publicly readable",
+			Resource:    models.BucketARN(bucketName),
+			Evidence:    append(evidence, "Bucket is publicly readable"),
+			Remediation: "Confirm every object is meant to be public, or serve the site through CloudFront with Origin Access Control and block public access",
+		}}, nil
+	}
+	return status, []models.Finding{{
+		CheckID:     CheckIDWebsiteEnabled,
+		Severity:    models.SeverityLow,
+		Title:       "Static website hosting is enabled",
+		Resource:    models.BucketARN(bucketName),
+		Evidence:    evidence,
+		Remediation: "Remove the website configuration if the bucket does not serve a site; website endpoints only support HTTP",
+	}}, nil
+}
+
+// routingRuleSummary describes a routing rule, e.g. "prefix docs/ -> host example.com"
+func routingRuleSummary(rule types.RoutingRule) string {
+	condition := "all requests"
+	if c := rule.Condition; c != nil {
+		var parts []string
+		if prefix := aws.ToString(c.KeyPrefixEquals); prefix != "" {
+			parts = append(parts, "prefix "+prefix)
+		}
+		if code := aws.ToString(c.HttpErrorCodeReturnedEquals); code != "" {
+			parts = append(parts, "error "+code)
+		}
+		if len(parts) > 0 {
+			condition = strings.Join(parts, ", ")
+		}
+	}
+
+	var target []string
+	if r := rule.Redirect; r != nil {
+		if host := aws.ToString(r.HostName); host != "" {
+			target = append(target, "host "+host)
+		}
+		if key := aws.ToString(r.ReplaceKeyPrefixWith); key != "" {
+			target = append(target, "prefix "+key)
+		}
+		if key := aws.ToString(r.ReplaceKeyWith); key != "" {
+			target = append(target, "key "+key)
+		}
+		if code := aws.ToString(r.HttpRedirectCode); code != "" {
+			target = append(target, "code "+code)
+		}
+	}
+	if len(target) == 0 {
+		target = append(target, "same host")
+	}
+	return condition + " -> " + strings.Join(target, ", ")
+}
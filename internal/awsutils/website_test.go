@@ -0,0 +1,102 @@
+package awsutils
+
+import (
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/aws/smithy-go"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestCheckBucketWebsite(t *testing.T) {
+	website := &s3.GetBucketWebsiteOutput{
+		IndexDocument: &types.IndexDocument{Suffix: aws.String("index.html")},
+		RoutingRules: []types.RoutingRule{{
+			Condition: &types.Condition{KeyPrefixEquals: aws.String("docs/")},
+			Redirect:  &types.Redirect{HostName: aws.String("docs.example.com"), HttpRedirectCode: aws.String("301")},
+		}},
+	}
+
+	tests := []struct {
+		name            string
+		output          *s3.GetBucketWebsiteOutput
+		public          *bool
+		expectedSummary string
+		expectedChecks  []string
+	}{
+		{
+			name:            "Website hosting disabled",
+			public:          aws.Bool(true),
+			expectedSummary: "Not Enabled",
+		},
+		{
+			name:            "Website-hosted and publicly readable",
+			output:          website,
+			public:          aws.Bool(true),
+			expectedSummary: "website-hosted and publicly readable",
+			expectedChecks:  []string{CheckIDWebsitePublic},
+		},
+		{
+			name:            "Website-hosted but private",
+			output:          website,
+			public:          aws.Bool(false),
+			expectedSummary: "website-hosted but not publicly readable",
+			expectedChecks:  []string{CheckIDWebsiteEnabled},
+		},
+		{
+			name:            "Public access not checked",
+			output:          &s3.GetBucketWebsiteOutput{RedirectAllRequestsTo: &types.RedirectAllRequestsTo{HostName: aws.String("example.com"), Protocol: types.ProtocolHttps}},
+			expectedSummary: "redirects all requests to https://example.com",
+			expectedChecks:  []string{CheckIDWebsiteEnabled},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockClient := new(mockS3Client)
+			if tt.output == nil {
+				mockClient.On("GetBucketWebsite", mock.Anything, mock.Anything).Return(
+					&s3.GetBucketWebsiteOutput{}, &smithy.GenericAPIError{Code: "NoSuchWebsiteConfiguration"})
+			} else {
+				mockClient.On("GetBucketWebsite", mock.Anything, mock.Anything).Return(tt.output, nil)
+			}
+
+			status, findings, err := CheckBucketWebsite(mockClient, "bucket", tt.public)
+
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expectedSummary, status.Summary())
+			var checks []string
+			for _, f := range findings {
+				checks = append(checks, f.CheckID)
+			}
+			assert.Equal(t, tt.expectedChecks, checks)
+		})
+	}
+}
+
+func TestWebsiteRoutingRules(t *testing.T) {
+	mockClient := new(mockS3Client)
+	mockClient.On("GetBucketWebsite", mock.Anything, mock.Anything).Return(&s3.GetBucketWebsiteOutput{
+		RoutingRules: []types.RoutingRule{
+			{
+				Condition: &types.Condition{KeyPrefixEquals: aws.String("docs/")},
+				Redirect:  &types.Redirect{HostName: aws.String("docs.example.com"), HttpRedirectCode: aws.String("301")},
+			},
+			{
+				Condition: &types.Condition{HttpErrorCodeReturnedEquals: aws.String("404")},
+				Redirect:  &types.Redirect{ReplaceKeyWith: aws.String("missing.html")},
+			},
+		},
+	}, nil)
+
+	status, err := GetBucketWebsite(mockClient, "bucket")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{
+		"prefix docs/ -> host docs.example.com, code 301",
+		"error 404 -> key missing.html",
+	}, status.RoutingRules)
+}
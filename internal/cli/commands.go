@@ -0,0 +1,914 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"path"
+	"slices"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/backup"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatch"
+	macietypes "github.com/aws/aws-sdk-go-v2/service/macie2/types"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fatih/color"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/audit"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/config"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/llm"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+)
+
+// Exit codes returned by Run
+const (
+	ExitOK       = 0
+	ExitFindings = 1
+	ExitError    = 2
+)
+
+const usage = `Usage: s3auditor [command] [flags]
+
+Run without a command to start the interactive menu.
+
+Commands:
+  list       List buckets in the account
+  audit      Audit buckets and print or save a report
+  report     Render a saved JSON report
+  inventory  Show bucket size, object count and storage classes
+  profile    Profile the columns of CSV, JSON and Parquet objects for personal data
+  jobs       List and clean up the Macie jobs the tool created
+
+Run "s3auditor <command> -h" for the flags of each command.
+`
+
+// Defaults for the local classifier and secret scan, which read objects
+// themselves
+const (
+	defaultLocalMaxObjects = 1000
+	defaultLocalMaxSize    = 10 * 1024 * 1024
+)
+
+// Defaults for the column profiler, which reads Parquet files whole and so
+// allows larger objects
+const (
+	defaultProfileMaxObjects = 100
+	defaultProfileMaxSize    = 64 * 1024 * 1024
+	defaultProfileRows       = 1000
+)
+
+// Defaults for the LLM classifier, which sends a small sample of excerpts so
+// a run stays cheap and fast
+const (
+	defaultLLMSample          = 20
+	defaultLLMExcerptSize     = 4096
+	defaultLLMMaxOutputTokens = 1024
+)
+
+// stringList is a flag value that accepts repeated or comma-separated values
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			*l = append(*l, v)
+		}
+	}
+	return nil
+}
+
+// bucketFilter selects buckets by name, glob pattern and region
+type bucketFilter struct {
+	names    stringList
+	patterns stringList
+	regions  stringList
+}
+
+func (f *bucketFilter) register(fs *flag.FlagSet) {
+	fs.Var(&f.names, "bucket", "bucket name to include (repeatable or comma-separated)")
+	fs.Var(&f.patterns, "pattern", "glob pattern matched against bucket names, e.g. 'logs-*' (repeatable)")
+	fs.Var(&f.regions, "region", "only include buckets in this region (repeatable)")
+}
+
+func (f *bucketFilter) apply(buckets []models.BucketBasicInfo) ([]models.BucketBasicInfo, error) {
+	for _, p := range f.patterns {
+		if _, err := path.Match(p, ""); err != nil {
+			return nil, fmt.Errorf("invalid pattern %q: %w", p, err)
+		}
+	}
+
+	var selected []models.BucketBasicInfo
+	for _, bucket := range buckets {
+		if len(f.regions) > 0 && !slices.Contains(f.regions, bucket.Region) {
+			continue
+		}
+		if len(f.names) == 0 && len(f.patterns) == 0 {
+			selected = append(selected, bucket)
+			continue
+		}
+		if slices.Contains(f.names, bucket.Name) || matchesAny(f.patterns, bucket.Name) {
+			selected = append(selected, bucket)
+		}
+	}
+	return selected, nil
+}
+
+// macieScopeFlags limits which objects the Macie check classifies
+type macieScopeFlags struct {
+	includePrefixes   stringList
+	excludePrefixes   stringList
+	includeExtensions stringList
+	excludeExtensions stringList
+	includeTags       stringList
+	excludeTags       stringList
+	minSize           int64
+	maxSize           int64
+	sampling          int
+}
+
+func (f *macieScopeFlags) register(fs *flag.FlagSet) {
+	fs.Var(&f.includePrefixes, "macie-include-prefix", "only classify objects under this key prefix (repeatable)")
+	fs.Var(&f.excludePrefixes, "macie-exclude-prefix", "skip objects under this key prefix (repeatable)")
+	fs.Var(&f.includeExtensions, "macie-include-ext", "only classify objects with this file extension, e.g. csv (repeatable)")
+	fs.Var(&f.excludeExtensions, "macie-exclude-ext", "skip objects with this file extension (repeatable)")
+	fs.Var(&f.includeTags, "macie-include-tag", "only classify objects with this tag, as key or key=value (repeatable)")
+	fs.Var(&f.excludeTags, "macie-exclude-tag", "skip objects with this tag, as key or key=value (repeatable)")
+	fs.Int64Var(&f.minSize, "macie-min-size", 0, "only classify objects of at least this many bytes")
+	fs.Int64Var(&f.maxSize, "macie-max-size", 0, "only classify objects of at most this many bytes (0 for no limit)")
+	fs.IntVar(&f.sampling, "macie-sampling", 0, "classify this percentage of the matching objects, 1-100 (default all)")
+}
+
+func (f *macieScopeFlags) scope() (awsutils.MacieJobScope, error) {
+	scope := awsutils.MacieJobScope{
+		IncludePrefixes:    f.includePrefixes,
+		ExcludePrefixes:    f.excludePrefixes,
+		IncludeExtensions:  f.includeExtensions,
+		ExcludeExtensions:  f.excludeExtensions,
+		IncludeTags:        parseTags(f.includeTags),
+		ExcludeTags:        parseTags(f.excludeTags),
+		MinSize:            f.minSize,
+		MaxSize:            f.maxSize,
+		SamplingPercentage: int32(f.sampling),
+	}
+	if err := scope.Validate(); err != nil {
+		return scope, fmt.Errorf("invalid Macie scope: %w", err)
+	}
+	return scope, nil
+}
+
+// llmFlags selects the model endpoint and budget of the LLM classifier
+type llmFlags struct {
+	endpoint    string
+	apiStyle    string
+	model       string
+	sample      int
+	excerptSize int
+	maxTokens   int
+	maxCost     float64
+	inputPrice  float64
+	outputPrice float64
+}
+
+func (f *llmFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&f.endpoint, "llm-endpoint", config.GetLLMEndpoint(), "base URL of the model endpoint, e.g. http://localhost:11434/v1 (default the Bedrock endpoint of the region with -llm-api-style bedrock)")
+	fs.StringVar(&f.apiStyle, "llm-api-style", config.GetLLMAPIStyle(), "API the model endpoint speaks: "+llm.APIStyleOpenAI+" or "+llm.APIStyleBedrock)
+	fs.StringVar(&f.model, "llm-model", config.GetLLMModel(), "model the LLM classifier uses")
+	fs.IntVar(&f.sample, "llm-sample", defaultLLMSample, "send excerpts of this many objects per bucket to the model")
+	fs.IntVar(&f.excerptSize, "llm-excerpt-size", defaultLLMExcerptSize, "send at most this many bytes of the text of each object to the model")
+	fs.IntVar(&f.maxTokens, "llm-max-tokens", config.GetLLMTokenBudget(), "stop sending excerpts once the run has used this many tokens (0 for no limit)")
+	fs.Float64Var(&f.maxCost, "llm-max-cost", 0, "stop sending excerpts once the run has spent this many USD (0 for no limit; needs -llm-input-price and -llm-output-price)")
+	fs.Float64Var(&f.inputPrice, "llm-input-price", 0, "USD price of one million input tokens, used by -llm-max-cost")
+	fs.Float64Var(&f.outputPrice, "llm-output-price", 0, "USD price of one million output tokens, used by -llm-max-cost")
+}
+
+func (f *llmFlags) validate() error {
+	if f.sample < 1 {
+		return fmt.Errorf("invalid -llm-sample %d", f.sample)
+	}
+	if f.excerptSize < 1 {
+		return fmt.Errorf("invalid -llm-excerpt-size %d", f.excerptSize)
+	}
+	if f.maxTokens < 0 || f.maxCost < 0 || f.inputPrice < 0 || f.outputPrice < 0 {
+		return errors.New("LLM budget and prices must not be negative")
+	}
+	if f.maxCost > 0 && f.inputPrice == 0 && f.outputPrice == 0 {
+		return errors.New("-llm-max-cost needs -llm-input-price or -llm-output-price")
+	}
+	return nil
+}
+
+// newLLMClassifier builds the model client and the classifier that sends it
+// excerpts of a sample of the objects that objects selects
+func newLLMClassifier(cfg aws.Config, s3Client awsutils.S3ClientAPI, f llmFlags, objects audit.LocalScanOptions) (*audit.LLMClassifier, error) {
+	client, err := llm.NewClient(llm.Config{
+		APIStyle:        f.apiStyle,
+		Endpoint:        f.endpoint,
+		Model:           f.model,
+		APIKey:          config.GetLLMAPIKey(),
+		MaxOutputTokens: defaultLLMMaxOutputTokens,
+		AWS:             cfg,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("unable to configure LLM classifier: %w", err)
+	}
+	objects.MaxObjects = f.sample
+	return audit.NewLLMClassifier(s3Client, client, audit.LLMClassifierOptions{
+		Objects:         objects,
+		ExcerptBytes:    f.excerptSize,
+		MaxOutputTokens: defaultLLMMaxOutputTokens,
+		Budget: &llm.Budget{
+			MaxTokens:   f.maxTokens,
+			MaxCost:     f.maxCost,
+			InputPrice:  f.inputPrice,
+			OutputPrice: f.outputPrice,
+		},
+	}), nil
+}
+
+// parseTags turns key or key=value arguments into a tag map
+func parseTags(values []string) map[string]string {
+	if len(values) == 0 {
+		return nil
+	}
+	tags := make(map[string]string, len(values))
+	for _, v := range values {
+		key, value, _ := strings.Cut(v, "=")
+		tags[key] = value
+	}
+	return tags
+}
+
+func matchesAny(patterns []string, name string) bool {
+	for _, p := range patterns {
+		if ok, _ := path.Match(p, name); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// Run executes a non-interactive command and returns the process exit code
+func Run(args []string) int {
+	if len(args) == 0 {
+		fmt.Fprint(os.Stderr, usage)
+		return ExitError
+	}
+
+	// Keep progress messages off stdout so reports can be piped
+	color.Output = os.Stderr
+
+	var err error
+	code := ExitOK
+	switch args[0] {
+	case "list":
+		err = runList(args[1:])
+	case "audit":
+		code, err = runAudit(args[1:])
+	case "report":
+		code, err = runReport(args[1:])
+	case "inventory":
+		err = runInventory(args[1:])
+	case "profile":
+		err = runProfile(args[1:])
+	case "jobs":
+		err = runJobs(args[1:])
+	case "help", "-h", "-help", "--help":
+		fmt.Fprint(os.Stdout, usage)
+		return ExitOK
+	default:
+		err = fmt.Errorf("unknown command %q", args[0])
+		fmt.Fprint(os.Stderr, usage)
+	}
+
+	if errors.Is(err, flag.ErrHelp) {
+		return ExitOK
+	}
+	if err != nil {
+		color.Red("Error: %v", err)
+		log.Printf("Error: %v", err)
+		return ExitError
+	}
+	return code
+}
+
+func runList(args []string) error {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	var filter bucketFilter
+	filter.register(fs)
+	format := fs.String("format", "text", "output format: text or json")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	clients, err := awsutils.NewAWSClients(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to initialize AWS clients: %w", err)
+	}
+
+	buckets, err := awsutils.ListBuckets(clients.S3Client)
+	if err != nil {
+		return fmt.Errorf("unable to list buckets: %w", err)
+	}
+	buckets, err = filter.apply(buckets)
+	if err != nil {
+		return err
+	}
+
+	switch *format {
+	case "text":
+		for _, bucket := range buckets {
+			fmt.Fprintf(os.Stdout, "%s\t%s\n", bucket.Name, bucket.Region)
+		}
+		return nil
+	case "json":
+		return writeJSON(os.Stdout, buckets)
+	default:
+		return fmt.Errorf("unknown format %q", *format)
+	}
+}
+
+func runAudit(args []string) (int, error) {
+	fs := flag.NewFlagSet("audit", flag.ContinueOnError)
+	var filter bucketFilter
+	filter.register(fs)
+	var checks stringList
+	fs.Var(&checks, "checks", "checks to run: "+strings.Join(audit.AllChecks, ", ")+" (default all)")
+	format := fs.String("format", "text", "output format: text or json")
+	output := fs.String("output", "", "write the report to this file instead of stdout")
+	failOn := fs.String("fail-on", "none", "exit with code 1 when a bucket reaches this severity: low, medium, high, critical or none")
+	concurrency := fs.Int("concurrency", config.GetAuditConcurrency(), "number of buckets to audit in parallel")
+	sampleObjects := fs.Int("sample-objects", 0, "inspect the ACL and encryption of this many objects per bucket (0 disables sampling unless -sample-prefix is set)")
+	samplePrefix := fs.String("sample-prefix", "", "only sample objects under this prefix; without -sample-objects every object under it is inspected")
+	sampleRate := fs.Int("sample-rate", config.GetObjectSampleRate(), "maximum object requests per second per bucket while sampling")
+	var macieFlags macieScopeFlags
+	macieFlags.register(fs)
+	macieConfig := fs.String("macie-config", config.GetMacieConfigFile(), "JSON file selecting the managed and custom data identifiers and allow lists Macie uses")
+	classifier := fs.String("classifier", audit.ClassifierMacie, "sensitive data backend: "+strings.Join(audit.Classifiers, ", "))
+	secrets := fs.Bool("secrets", false, "scan object contents for access keys, tokens, private keys and other secrets")
+	localPrefix := fs.String("local-prefix", "", "only read objects under this prefix with the local and LLM classifiers and secret scan")
+	localMaxObjects := fs.Int("local-max-objects", defaultLocalMaxObjects, "read at most this many objects per bucket with the local classifier and secret scan (0 for no limit)")
+	localMaxSize := fs.Int64("local-max-size", defaultLocalMaxSize, "read at most this many bytes of each object with the local and LLM classifiers and secret scan; larger archives, PDFs and Parquet files are skipped (0 for no limit)")
+	macieNewJob := fs.Bool("macie-new-job", false, "always create a new Macie job instead of reattaching to one an earlier run started")
+	var llmOpts llmFlags
+	llmOpts.register(fs)
+	if err := fs.Parse(args); err != nil {
+		return ExitError, err
+	}
+	if *format != "text" && *format != "json" {
+		return ExitError, fmt.Errorf("unknown format %q", *format)
+	}
+	threshold, err := models.ParseSeverity(*failOn)
+	if err != nil {
+		return ExitError, err
+	}
+	if *sampleObjects < 0 {
+		return ExitError, fmt.Errorf("invalid -sample-objects %d", *sampleObjects)
+	}
+	if *sampleRate < 1 || *sampleRate > config.MaxObjectSampleRate {
+		return ExitError, fmt.Errorf("invalid -sample-rate %d: must be between 1 and %d", *sampleRate, config.MaxObjectSampleRate)
+	}
+	if !slices.Contains(audit.Classifiers, *classifier) {
+		return ExitError, fmt.Errorf("unknown classifier %q", *classifier)
+	}
+	if *classifier == audit.ClassifierLLM {
+		if err := llmOpts.validate(); err != nil {
+			return ExitError, err
+		}
+	}
+	macieScope, err := macieFlags.scope()
+	if err != nil {
+		return ExitError, err
+	}
+	var macieIdentifiers *awsutils.MacieIdentifiers
+	if *macieConfig != "" {
+		identifiers, err := awsutils.LoadMacieIdentifiers(*macieConfig)
+		if err != nil {
+			return ExitError, fmt.Errorf("unable to load Macie config: %w", err)
+		}
+		macieIdentifiers = &identifiers
+	}
+
+	clients, err := awsutils.NewAWSClients(context.Background())
+	if err != nil {
+		return ExitError, fmt.Errorf("unable to initialize AWS clients: %w", err)
+	}
+
+	scanner := audit.NewScanner(clients.Config, clients.S3Client, clients.MacieClient, sts.NewFromConfig(clients.Config))
+	if err := scanner.SetChecks(checks); err != nil {
+		return ExitError, err
+	}
+	scanner.SetS3ControlClient(clients.S3ControlClient)
+	scanner.SetCloudTrailClient(clients.CloudTrailClient)
+	scanner.SetKMSClient(clients.KMSClient)
+	scanner.SetBackupClients(func(region string) awsutils.BackupClientAPI {
+		return regionalBackupClient(clients.Config, region)
+	})
+	scanner.SetProgressOutput(os.Stderr)
+	scanner.SetConcurrency(*concurrency)
+	scanner.SetMacieScope(macieScope)
+	if macieIdentifiers != nil {
+		scanner.SetMacieIdentifiers(*macieIdentifiers)
+	}
+	localScan := audit.LocalScanOptions{
+		Prefix:     *localPrefix,
+		MaxObjects: *localMaxObjects,
+		MaxBytes:   *localMaxSize,
+	}
+	if *classifier == audit.ClassifierLocal {
+		scanner.SetClassifier(audit.NewLocalClassifier(clients.S3Client, localScan))
+	}
+	if *classifier == audit.ClassifierLLM {
+		llmClassifier, err := newLLMClassifier(clients.Config, clients.S3Client, llmOpts, localScan)
+		if err != nil {
+			return ExitError, err
+		}
+		scanner.SetClassifier(llmClassifier)
+	}
+	if *secrets {
+		scanner.SetSecretScanner(audit.NewSecretScanner(clients.S3Client, localScan))
+	}
+	scanner.SetJobStore(audit.NewJobStore(config.GetMacieJobsFile()))
+	if *macieNewJob {
+		scanner.SetMacieReattach(func(models.MacieJobRecord) bool { return false })
+	}
+	if *sampleObjects > 0 || *samplePrefix != "" {
+		scanner.SetObjectSampling(awsutils.ObjectSampleOptions{
+			Size:          *sampleObjects,
+			Prefix:        *samplePrefix,
+			RatePerSecond: *sampleRate,
+		})
+	}
+
+	buckets, err := awsutils.ListBuckets(clients.S3Client)
+	if err != nil {
+		return ExitError, fmt.Errorf("unable to list buckets: %w", err)
+	}
+	buckets, err = filter.apply(buckets)
+	if err != nil {
+		return ExitError, err
+	}
+	if len(buckets) == 0 {
+		return ExitError, fmt.Errorf("no buckets match the given filters")
+	}
+
+	names := make([]string, len(buckets))
+	for i, bucket := range buckets {
+		names[i] = bucket.Name
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	report := models.AuditReport{GeneratedAt: time.Now().UTC()}
+	for _, result := range scanner.AuditBuckets(ctx, names) {
+		if result.Err != nil {
+			color.Red("Error: %v", result.Err)
+			report.Errors = append(report.Errors, models.BucketError{Bucket: result.Info.Name, Error: result.Err.Error()})
+			continue
+		}
+		report.Buckets = append(report.Buckets, result.Info)
+	}
+
+	if err := writeReportTo(*output, *format, report); err != nil {
+		return ExitError, err
+	}
+	return exitCode(report, threshold), nil
+}
+
+func runReport(args []string) (int, error) {
+	fs := flag.NewFlagSet("report", flag.ContinueOnError)
+	input := fs.String("input", "-", "JSON report produced by 'audit -format json' ('-' for stdin)")
+	format := fs.String("format", "text", "output format: text or json")
+	output := fs.String("output", "", "write the report to this file instead of stdout")
+	failOn := fs.String("fail-on", "none", "exit with code 1 when a bucket reaches this severity: low, medium, high, critical or none")
+	if err := fs.Parse(args); err != nil {
+		return ExitError, err
+	}
+	if *format != "text" && *format != "json" {
+		return ExitError, fmt.Errorf("unknown format %q", *format)
+	}
+	threshold, err := models.ParseSeverity(*failOn)
+	if err != nil {
+		return ExitError, err
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			return ExitError, fmt.Errorf("unable to open report: %w", err)
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var report models.AuditReport
+	if err := json.NewDecoder(r).Decode(&report); err != nil {
+		return ExitError, fmt.Errorf("unable to parse report: %w", err)
+	}
+
+	if err := writeReportTo(*output, *format, report); err != nil {
+		return ExitError, err
+	}
+	return exitCode(report, threshold), nil
+}
+
+func runInventory(args []string) error {
+	fs := flag.NewFlagSet("inventory", flag.ContinueOnError)
+	var filter bucketFilter
+	filter.register(fs)
+	format := fs.String("format", "text", "output format: text or json")
+	source := fs.String("source", "auto", "where to read sizes from: auto (CloudWatch metrics, else a listing), cloudwatch or list")
+	maxObjects := fs.Int("max-objects", 100000, "stop listing a bucket after this many objects (0 for no limit)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *source != "auto" && *source != "cloudwatch" && *source != "list" {
+		return fmt.Errorf("unknown source %q", *source)
+	}
+
+	clients, err := awsutils.NewAWSClients(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to initialize AWS clients: %w", err)
+	}
+
+	buckets, err := awsutils.ListBuckets(clients.S3Client)
+	if err != nil {
+		return fmt.Errorf("unable to list buckets: %w", err)
+	}
+	buckets, err = filter.apply(buckets)
+	if err != nil {
+		return err
+	}
+
+	var inventories []models.BucketInventory
+	for _, bucket := range buckets {
+		color.Cyan("Reading inventory of bucket: %s", bucket.Name)
+		var inventory models.BucketInventory
+		var err error
+		switch *source {
+		case "list":
+			inventory, err = awsutils.ListBucketInventory(clients.S3Client, bucket.Name, *maxObjects)
+		case "cloudwatch":
+			var ok bool
+			inventory, ok, err = awsutils.GetBucketMetrics(regionalCloudWatchClient(clients.Config, bucket.Region), bucket.Name)
+			if err == nil && !ok {
+				err = fmt.Errorf("no CloudWatch storage metrics")
+			}
+		default:
+			inventory, err = awsutils.GetBucketInventory(clients.S3Client, regionalCloudWatchClient(clients.Config, bucket.Region), bucket.Name, *maxObjects)
+		}
+		if err != nil {
+			color.Red("Error: unable to read inventory of bucket %s: %v", bucket.Name, err)
+			log.Printf("Error: unable to read inventory of bucket %s: %v", bucket.Name, err)
+			continue
+		}
+		inventory.Region = bucket.Region
+		inventories = append(inventories, inventory)
+	}
+
+	// Largest buckets first, as those are the ones worth sending to Macie
+	sort.SliceStable(inventories, func(i, j int) bool {
+		return inventories[i].TotalSize > inventories[j].TotalSize
+	})
+
+	if *format == "json" {
+		return writeJSON(os.Stdout, inventories)
+	}
+	for _, inventory := range inventories {
+		writeInventory(os.Stdout, inventory)
+	}
+	return nil
+}
+
+func runProfile(args []string) error {
+	fs := flag.NewFlagSet("profile", flag.ContinueOnError)
+	var filter bucketFilter
+	filter.register(fs)
+	format := fs.String("format", "text", "output format: text, json or csv")
+	output := fs.String("output", "", "write the profile to this file instead of stdout")
+	prefix := fs.String("prefix", "", "only profile objects under this prefix")
+	maxObjects := fs.Int("max-objects", defaultProfileMaxObjects, "profile at most this many CSV, JSON and Parquet objects per bucket (0 for no limit)")
+	maxSize := fs.Int64("max-size", defaultProfileMaxSize, "read at most this many bytes of each object; larger Parquet files are skipped (0 for no limit)")
+	rows := fs.Int("rows", defaultProfileRows, "sample at most this many rows of each object (0 for every row)")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" && *format != "csv" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+	if *rows < 0 {
+		return fmt.Errorf("invalid -rows %d", *rows)
+	}
+
+	clients, err := awsutils.NewAWSClients(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to initialize AWS clients: %w", err)
+	}
+
+	buckets, err := awsutils.ListBuckets(clients.S3Client)
+	if err != nil {
+		return fmt.Errorf("unable to list buckets: %w", err)
+	}
+	buckets, err = filter.apply(buckets)
+	if err != nil {
+		return err
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	profiler := audit.NewColumnProfiler(clients.S3Client, audit.ProfileOptions{
+		Objects: audit.LocalScanOptions{
+			Prefix:     *prefix,
+			MaxObjects: *maxObjects,
+			MaxBytes:   *maxSize,
+		},
+		MaxRows: *rows,
+	})
+	var profiles []models.BucketProfile
+	for _, bucket := range buckets {
+		color.Cyan("Profiling bucket: %s", bucket.Name)
+		profile, err := profiler.Profile(ctx, bucket.Name)
+		if err != nil {
+			if ctx.Err() != nil {
+				return err
+			}
+			color.Red("Error: unable to profile bucket %s: %v", bucket.Name, err)
+			log.Printf("Error: unable to profile bucket %s: %v", bucket.Name, err)
+			continue
+		}
+		profile.Region = bucket.Region
+		profiles = append(profiles, profile)
+	}
+
+	return writeOutput(*output, func(w io.Writer) error {
+		switch *format {
+		case "json":
+			return writeJSON(w, profiles)
+		case "csv":
+			return audit.WriteProfilesCSV(w, profiles)
+		}
+		for _, profile := range profiles {
+			audit.WriteBucketProfile(w, profile)
+		}
+		return nil
+	})
+}
+
+func runJobs(args []string) error {
+	fs := flag.NewFlagSet("jobs", flag.ContinueOnError)
+	format := fs.String("format", "text", "output format: text or json")
+	clean := fs.Bool("clean", false, "cancel running jobs and forget finished ones")
+	jobID := fs.String("job", "", "only list or clean up the job with this ID")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if *format != "text" && *format != "json" {
+		return fmt.Errorf("unknown format %q", *format)
+	}
+
+	store := audit.NewJobStore(config.GetMacieJobsFile())
+	jobs, err := store.Jobs()
+	if err != nil {
+		return err
+	}
+	if *jobID != "" {
+		jobs = slices.DeleteFunc(jobs, func(job models.MacieJobRecord) bool { return job.JobID != *jobID })
+		if len(jobs) == 0 {
+			return fmt.Errorf("no recorded Macie job with ID %q", *jobID)
+		}
+	}
+
+	clients, err := awsutils.NewAWSClients(context.Background())
+	if err != nil {
+		return fmt.Errorf("unable to initialize AWS clients: %w", err)
+	}
+
+	ctx := context.Background()
+	identity, err := sts.NewFromConfig(clients.Config).GetCallerIdentity(ctx, &sts.GetCallerIdentityInput{})
+	if err != nil {
+		return fmt.Errorf("unable to retrieve account ID: %w", err)
+	}
+	accountID, region := aws.ToString(identity.Account), clients.Config.Region
+
+	statuses := make([]jobStatus, 0, len(jobs))
+	for _, job := range jobs {
+		// Macie only knows the jobs of the current account and region; the
+		// others are left for a session there
+		if !job.InSession(accountID, region) {
+			statuses = append(statuses, jobStatus{MacieJobRecord: job, Status: statusOtherSession})
+			continue
+		}
+		status, err := awsutils.GetClassificationJobStatus(ctx, clients.MacieClient, job.JobID)
+		switch {
+		case awsutils.IsNotFound(err):
+			status = "NOT_FOUND"
+		case err != nil:
+			color.Red("Error: unable to get status of Macie job %s: %v", job.JobID, err)
+			log.Printf("Error: unable to get status of Macie job %s: %v", job.JobID, err)
+			status = "UNKNOWN"
+		}
+		statuses = append(statuses, jobStatus{MacieJobRecord: job, Status: string(status)})
+	}
+
+	if *clean {
+		for i, job := range statuses {
+			if job.Status == statusOtherSession {
+				continue
+			}
+			if err := cleanUpJob(ctx, clients.MacieClient, store, job); err != nil {
+				color.Red("Error: unable to clean up Macie job %s: %v", job.JobID, err)
+				log.Printf("Error: unable to clean up Macie job %s: %v", job.JobID, err)
+				continue
+			}
+			statuses[i].Removed = true
+		}
+	}
+
+	if *format == "json" {
+		return writeJSON(os.Stdout, statuses)
+	}
+	if len(statuses) == 0 {
+		fmt.Fprintln(os.Stdout, "No Macie jobs recorded")
+		return nil
+	}
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "JOB ID\tBUCKET\tACCOUNT\tREGION\tCREATED\tSTATUS")
+	for _, job := range statuses {
+		status := job.Status
+		if job.Removed {
+			status += " (removed)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", job.JobID, job.Bucket, valueOrDash(job.AccountID), valueOrDash(job.Region),
+			job.CreatedAt.Local().Format(time.DateTime), status)
+	}
+	return tw.Flush()
+}
+
+// valueOrDash shows empty table cells as "-"
+func valueOrDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+// statusOtherSession is the status of recorded jobs in another account or
+// region than the current session, whose status cannot be looked up
+const statusOtherSession = "OTHER_ACCOUNT_OR_REGION"
+
+// jobStatus is a recorded Macie job with its current status
+type jobStatus struct {
+	models.MacieJobRecord
+	Status string `json:"status"`
+	// Removed is set when -clean cancelled or forgot the job
+	Removed bool `json:"removed,omitempty"`
+}
+
+// cleanUpJob cancels a job that is still running or paused and forgets it
+func cleanUpJob(ctx context.Context, client awsutils.MacieClientAPI, store *audit.JobStore, job jobStatus) error {
+	switch job.Status {
+	case "UNKNOWN":
+		return fmt.Errorf("job status unknown")
+	case statusOtherSession:
+		return fmt.Errorf("job runs in another account or region")
+	case "NOT_FOUND":
+		// Macie deletes jobs some time after they finish
+	case string(macietypes.JobStatusRunning), string(macietypes.JobStatusPaused), string(macietypes.JobStatusUserPaused), string(macietypes.JobStatusIdle):
+		if err := awsutils.CancelClassificationJob(ctx, client, job.JobID); err != nil {
+			return err
+		}
+		color.Yellow("Cancelled Macie job %s for bucket %s", job.JobID, job.Bucket)
+		log.Printf("Cancelled Macie job %s for bucket %s", job.JobID, job.Bucket)
+	}
+	return store.Remove(job.JobID)
+}
+
+// regionalCloudWatchClient returns a CloudWatch client for the region S3
+// publishes a bucket's storage metrics in
+func regionalCloudWatchClient(cfg aws.Config, region string) *cloudwatch.Client {
+	return cloudwatch.NewFromConfig(cfg, func(o *cloudwatch.Options) {
+		if region != "" && region != "unknown" {
+			o.Region = region
+		}
+	})
+}
+
+// regionalBackupClient returns an AWS Backup client for the region a bucket
+// lives in, since AWS Backup only lists the resources of its own region
+func regionalBackupClient(cfg aws.Config, region string) *backup.Client {
+	return backup.NewFromConfig(cfg, func(o *backup.Options) {
+		if region != "" && region != "unknown" {
+			o.Region = region
+		}
+	})
+}
+
+func writeInventory(w io.Writer, inventory models.BucketInventory) {
+	truncated := ""
+	if inventory.Truncated {
+		truncated = "+"
+	}
+	fmt.Fprintf(w, "%s\t%s\t%d%s objects\t%s%s\t(%s)\n", inventory.Bucket, inventory.Region,
+		inventory.ObjectCount, truncated, models.FormatSize(inventory.TotalSize), truncated, inventory.Source)
+
+	classes := make([]string, 0, len(inventory.StorageClasses))
+	for class := range inventory.StorageClasses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		usage := inventory.StorageClasses[class]
+		fmt.Fprintf(w, "  %-20s %s\n", class, models.FormatSize(usage.Size))
+	}
+	if inventory.Oldest != nil && inventory.Newest != nil {
+		fmt.Fprintf(w, "  Modified between %s and %s\n", inventory.Oldest.Format(time.DateOnly), inventory.Newest.Format(time.DateOnly))
+	}
+	for _, object := range inventory.LargestObjects {
+		fmt.Fprintf(w, "  %10s  %s\n", models.FormatSize(object.Size), object.Key)
+	}
+}
+
+func writeReportTo(output, format string, report models.AuditReport) error {
+	return writeOutput(output, func(w io.Writer) error {
+		return writeReport(w, format, report)
+	})
+}
+
+// writeOutput calls write with stdout, or with the named file when output
+// is set
+func writeOutput(output string, write func(w io.Writer) error) error {
+	if output == "" {
+		return write(os.Stdout)
+	}
+
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("unable to create output file: %w", err)
+	}
+	defer f.Close()
+
+	// Escape codes have no place in a saved report
+	noColor := color.NoColor
+	color.NoColor = true
+	defer func() { color.NoColor = noColor }()
+
+	return write(f)
+}
+
+func writeReport(w io.Writer, format string, report models.AuditReport) error {
+	if format == "json" {
+		return writeJSON(w, report)
+	}
+
+	for _, info := range report.Buckets {
+		audit.WriteBucketReport(w, info)
+	}
+	for _, failure := range report.Errors {
+		fmt.Fprintf(w, "\nFailed to audit %s: %s\n", failure.Bucket, failure.Error)
+	}
+	fmt.Fprintf(w, "\nAudited %d bucket(s), %d failed\n", len(report.Buckets), len(report.Errors))
+	return nil
+}
+
+func writeJSON(w io.Writer, v interface{}) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// exitCode maps a report to ExitError when buckets failed, ExitFindings when
+// any bucket reaches the threshold and ExitOK otherwise
+func exitCode(report models.AuditReport, threshold models.Severity) int {
+	if len(report.Errors) > 0 {
+		return ExitError
+	}
+	if threshold == models.SeverityNone {
+		return ExitOK
+	}
+	for _, info := range report.Buckets {
+		if audit.HighestSeverity(info) >= threshold {
+			return ExitFindings
+		}
+	}
+	return ExitOK
+}
@@ -0,0 +1,128 @@
+package cli
+
+import (
+	"flag"
+	"testing"
+
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBucketFilter(t *testing.T) {
+	buckets := []models.BucketBasicInfo{
+		{Name: "logs-prod", Region: "us-east-1"},
+		{Name: "logs-dev", Region: "eu-west-1"},
+		{Name: "assets", Region: "us-east-1"},
+	}
+
+	tests := []struct {
+		name     string
+		args     []string
+		expected []string
+		wantErr  bool
+	}{
+		{
+			name:     "No filters selects everything",
+			args:     nil,
+			expected: []string{"logs-prod", "logs-dev", "assets"},
+		},
+		{
+			name:     "Glob pattern",
+			args:     []string{"-pattern", "logs-*"},
+			expected: []string{"logs-prod", "logs-dev"},
+		},
+		{
+			name:     "Pattern combined with region",
+			args:     []string{"-pattern", "logs-*", "-region", "eu-west-1"},
+			expected: []string{"logs-dev"},
+		},
+		{
+			name:     "Comma-separated bucket names",
+			args:     []string{"-bucket", "assets,logs-dev"},
+			expected: []string{"logs-dev", "assets"},
+		},
+		{
+			name:    "Invalid pattern",
+			args:    []string{"-pattern", "[logs"},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var filter bucketFilter
+			fs := flag.NewFlagSet("test", flag.ContinueOnError)
+			filter.register(fs)
+			assert.NoError(t, fs.Parse(tt.args))
+
+			selected, err := filter.apply(buckets)
+			if tt.wantErr {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+
+			var names []string
+			for _, b := range selected {
+				names = append(names, b.Name)
+			}
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	publicBucket := models.BucketInfo{Name: "public", IsPublic: true, Findings: []models.Finding{
+		{CheckID: "public.acl-grant", Severity: models.SeverityHigh, Title: "Bucket ACL grants access to a public group"},
+	}}
+	unversioned := models.BucketInfo{Name: "unversioned", Findings: []models.Finding{
+		{CheckID: "versioning.disabled", Severity: models.SeverityLow, Title: "Versioning is not enabled"},
+	}}
+
+	tests := []struct {
+		name      string
+		report    models.AuditReport
+		threshold models.Severity
+		expected  int
+	}{
+		{
+			name:      "No threshold never fails",
+			report:    models.AuditReport{Buckets: []models.BucketInfo{publicBucket}},
+			threshold: models.SeverityNone,
+			expected:  ExitOK,
+		},
+		{
+			name:      "Public bucket reaches high threshold",
+			report:    models.AuditReport{Buckets: []models.BucketInfo{publicBucket}},
+			threshold: models.SeverityHigh,
+			expected:  ExitFindings,
+		},
+		{
+			name:      "Low severity below threshold",
+			report:    models.AuditReport{Buckets: []models.BucketInfo{unversioned}},
+			threshold: models.SeverityMedium,
+			expected:  ExitOK,
+		},
+		{
+			name:      "Bucket without findings",
+			report:    models.AuditReport{Buckets: []models.BucketInfo{{Name: "clean"}}},
+			threshold: models.SeverityLow,
+			expected:  ExitOK,
+		},
+		{
+			name: "Failed buckets take precedence",
+			report: models.AuditReport{
+				Buckets: []models.BucketInfo{publicBucket},
+				Errors:  []models.BucketError{{Bucket: "broken", Error: "access denied"}},
+			},
+			threshold: models.SeverityHigh,
+			expected:  ExitError,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, exitCode(tt.report, tt.threshold))
+		})
+	}
+}
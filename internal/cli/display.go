@@ -1,11 +1,17 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"sort"
 	"strings"
+	"time"
 
+	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
+	"github.com/aws/aws-sdk-go-v2/service/sts"
 	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
@@ -13,7 +19,38 @@ import (
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/ui"
 )
 
-func DisplayBucketsList(s3Client *s3.Client, buckets []models.BucketBasicInfo) {
+// detailsMaxObjects caps how many objects the bucket details view lists, on
+// request, when CloudWatch storage metrics are not available
+const detailsMaxObjects = 10000
+
+// accountSettings is what the details view needs to know about the account
+// to tell whether a bucket is public
+type accountSettings struct {
+	id            string
+	pab           models.PublicAccessBlock
+	pabConfigured bool
+}
+
+// lookupAccountSettings reads the account ID and its Block Public Access
+// settings. Settings that cannot be read are treated as unconfigured, as in
+// an audit.
+func lookupAccountSettings(cfg aws.Config) accountSettings {
+	var account accountSettings
+	identity, err := sts.NewFromConfig(cfg).GetCallerIdentity(context.Background(), &sts.GetCallerIdentityInput{})
+	if err != nil {
+		log.Printf("Warning: unable to retrieve account ID: %v", err)
+		return account
+	}
+	account.id = aws.ToString(identity.Account)
+
+	account.pab, account.pabConfigured, err = awsutils.GetAccountPublicAccessBlock(s3control.NewFromConfig(cfg), account.id)
+	if err != nil {
+		log.Printf("Warning: unable to read account-level Block Public Access settings: %v", err)
+	}
+	return account
+}
+
+func DisplayBucketsList(cfg aws.Config, s3Client *s3.Client, buckets []models.BucketBasicInfo) {
 	if len(buckets) == 0 {
 		color.Yellow("\nNo S3 buckets found.")
 		return
@@ -21,6 +58,7 @@ func DisplayBucketsList(s3Client *s3.Client, buckets []models.BucketBasicInfo) {
 
 	// Add Exit option to the list
 	items := append(buckets, models.BucketBasicInfo{Name: "[ Exit ]", Region: ""})
+	var account *accountSettings
 
 	for {
 		prompt := &promptui.Select{
@@ -76,22 +114,23 @@ func DisplayBucketsList(s3Client *s3.Client, buckets []models.BucketBasicInfo) {
 			return
 		}
 
-		// Display details for selected bucket
-		displayBucketDetails(s3Client, buckets[idx])
+		// Display details for selected bucket, looking the account up once
+		if account == nil {
+			settings := lookupAccountSettings(cfg)
+			account = &settings
+		}
+		displayBucketDetails(cfg, s3Client, *account, buckets[idx])
 	}
 }
 
-func displayBucketDetails(s3Client *s3.Client, bucket models.BucketBasicInfo) {
+func displayBucketDetails(cfg aws.Config, s3Client *s3.Client, account accountSettings, bucket models.BucketBasicInfo) {
 	color.Cyan("\nBucket Details:")
 	color.Cyan("=====================================================================")
 	color.Green("Name              : %s", bucket.Name)
 	color.Cyan("Region            : %s", bucket.Region)
 
-	// Get encryption status
-	encryption, err := awsutils.GetBucketEncryption(s3Client, bucket.Name)
-	if err != nil {
-		encryption = "Not Enabled"
-	}
+	// Get encryption status; on failure the summary says whether access was denied
+	encryption, _ := awsutils.GetBucketEncryption(s3Client, bucket.Name)
 	color.Cyan("Encryption        : %s", encryption)
 
 	// Get versioning status
@@ -101,8 +140,57 @@ func displayBucketDetails(s3Client *s3.Client, bucket models.BucketBasicInfo) {
 	}
 	color.Cyan("Versioning        : %s", versioning)
 
+	// Get lifecycle rules
+	lifecycle, err := awsutils.GetBucketLifecycle(s3Client, bucket.Name)
+	switch {
+	case err != nil:
+		color.Yellow("Lifecycle         : Unknown")
+	case !lifecycle.Configured:
+		color.Yellow("Lifecycle         : Not Configured")
+	default:
+		color.Cyan("Lifecycle         : %d rule(s)", len(lifecycle.Rules))
+		for _, rule := range lifecycle.Rules {
+			color.Cyan("  - %s", rule.Summary())
+		}
+		if transitions := lifecycle.ArchiveTransitions(); len(transitions) > 0 {
+			color.Cyan("Archive           : %s", strings.Join(transitions, "; "))
+		}
+	}
+
+	// Get replication rules
+	replication, err := awsutils.GetBucketReplication(s3Client, bucket.Name)
+	switch {
+	case err != nil:
+		color.Yellow("Replication       : Unknown")
+	case !replication.Configured:
+		color.Yellow("Replication       : Not Configured")
+	default:
+		color.Cyan("Replication       : %d rule(s)", len(replication.Rules))
+		for _, rule := range replication.Rules {
+			color.Cyan("  - %s", rule.Summary())
+		}
+	}
+
+	// Show what is stored in the bucket. Listing a large bucket is slow, so
+	// it is only done on request when CloudWatch has no storage metrics.
+	inventory, ok, err := awsutils.GetBucketMetrics(regionalCloudWatchClient(cfg, bucket.Region), bucket.Name)
+	if err != nil || !ok {
+		color.Yellow("Contents          : No CloudWatch storage metrics")
+		if promptConfirm(fmt.Sprintf("List up to %d objects to count them", detailsMaxObjects)) {
+			inventory, err = awsutils.ListBucketInventory(s3Client, bucket.Name, detailsMaxObjects)
+			if err != nil {
+				color.Yellow("Contents          : Unknown")
+			} else {
+				ok = true
+			}
+		}
+	}
+	if ok {
+		displayInventory(inventory)
+	}
+
 	// Check if bucket is public
-	isPublic, err := awsutils.IsBucketPublic(s3Client, bucket.Name)
+	isPublic, err := awsutils.IsBucketPublic(s3Client, bucket.Name, account.id, account.pab, account.pabConfigured)
 	if err != nil {
 		color.Yellow("Public Access     : Unknown")
 	} else if isPublic {
@@ -118,3 +206,27 @@ func displayBucketDetails(s3Client *s3.Client, bucket models.BucketBasicInfo) {
 		log.Printf("Error reading input: %v", err)
 	}
 }
+
+// displayInventory prints the object count, size per storage class and, for
+// listings, the age range and largest objects of a bucket
+func displayInventory(inventory models.BucketInventory) {
+	more := ""
+	if inventory.Truncated {
+		more = "+"
+	}
+	color.Cyan("Contents          : %d%s object(s), %s%s (from %s)", inventory.ObjectCount, more, models.FormatSize(inventory.TotalSize), more, inventory.Source)
+	classes := make([]string, 0, len(inventory.StorageClasses))
+	for class := range inventory.StorageClasses {
+		classes = append(classes, class)
+	}
+	sort.Strings(classes)
+	for _, class := range classes {
+		color.Cyan("  - %-16s: %s", class, models.FormatSize(inventory.StorageClasses[class].Size))
+	}
+	if inventory.Oldest != nil && inventory.Newest != nil {
+		color.Cyan("Last Modified     : %s to %s", inventory.Oldest.Format(time.DateOnly), inventory.Newest.Format(time.DateOnly))
+	}
+	for _, object := range inventory.LargestObjects {
+		color.Cyan("  %10s  %s", models.FormatSize(object.Size), object.Key)
+	}
+}
@@ -1,17 +1,26 @@
 package cli
 
 import (
+	"context"
 	"fmt"
 	"log"
+	"os"
+	"os/signal"
 	"strings"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudtrail"
+	"github.com/aws/aws-sdk-go-v2/service/kms"
 	"github.com/aws/aws-sdk-go-v2/service/macie2"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3control"
 	"github.com/aws/aws-sdk-go-v2/service/sts"
+	"github.com/fatih/color"
 	"github.com/manifoldco/promptui"
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/audit"
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/awsutils"
+	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/config"
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/models"
 	"github.com/marko-durasic/aws-s3-bucket-auditor/internal/ui"
 )
@@ -27,10 +36,152 @@ func HandleBucketAudit(cfg aws.Config, s3Client *s3.Client, macieClient *macie2.
 		return
 	}
 
+	classifier, err := promptClassifier()
+	if err == promptui.ErrInterrupt {
+		return
+	}
+	if err != nil {
+		ui.ShowError("Error selecting classifier: %v", err)
+		log.Printf("Error selecting classifier: %v", err)
+		return
+	}
+
 	stsClient := sts.NewFromConfig(cfg)
 	scanner := audit.NewScanner(cfg, s3Client, macieClient, stsClient)
-	if err := scanner.AuditBucket(bucketName); err != nil {
+	scanner.SetS3ControlClient(s3control.NewFromConfig(cfg))
+	scanner.SetCloudTrailClient(cloudtrail.NewFromConfig(cfg))
+	scanner.SetKMSClient(kms.NewFromConfig(cfg))
+	scanner.SetBackupClients(func(region string) awsutils.BackupClientAPI {
+		return regionalBackupClient(cfg, region)
+	})
+	if path := config.GetMacieConfigFile(); path != "" {
+		identifiers, err := awsutils.LoadMacieIdentifiers(path)
+		if err != nil {
+			ui.ShowError("Unable to load Macie config: %v", err)
+			log.Printf("Error: unable to load Macie config: %v", err)
+			return
+		}
+		scanner.SetMacieIdentifiers(identifiers)
+	}
+	localScan := audit.LocalScanOptions{
+		MaxObjects: defaultLocalMaxObjects,
+		MaxBytes:   defaultLocalMaxSize,
+	}
+	if classifier == audit.ClassifierLocal {
+		scanner.SetClassifier(audit.NewLocalClassifier(s3Client, localScan))
+	}
+	if classifier == audit.ClassifierLLM {
+		llmClassifier, err := newLLMClassifier(cfg, s3Client, llmFlags{
+			endpoint:    config.GetLLMEndpoint(),
+			apiStyle:    config.GetLLMAPIStyle(),
+			model:       config.GetLLMModel(),
+			sample:      defaultLLMSample,
+			excerptSize: defaultLLMExcerptSize,
+			maxTokens:   config.GetLLMTokenBudget(),
+		}, localScan)
+		if err != nil {
+			ui.ShowError("%v", err)
+			log.Printf("Error: %v", err)
+			return
+		}
+		scanner.SetClassifier(llmClassifier)
+	}
+	if promptConfirm(fmt.Sprintf("Also scan up to %d objects for secrets such as access keys and tokens", defaultLocalMaxObjects)) {
+		scanner.SetSecretScanner(audit.NewSecretScanner(s3Client, localScan))
+	}
+	scanner.SetJobStore(audit.NewJobStore(config.GetMacieJobsFile()))
+	scanner.SetMacieReattach(promptReattach)
+
+	// Ctrl+C during the audit cancels the Macie job instead of leaving it running
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	info, err := scanner.ScanBucket(ctx, bucketName)
+	stop()
+	if err != nil {
+		ui.ShowError("Audit error: %v", err)
 		log.Printf("Audit error: %v", err)
+		return
+	}
+	audit.PrintBucketReport(info)
+
+	if details := info.SensitiveDataDetails; details != nil && len(details.Objects) > 0 {
+		browseSensitiveObjects(details.Objects)
+	}
+
+	// Return to the main menu after the report is complete
+	color.Cyan("\nReturning to the main menu...\n")
+	log.Println("Returning to the main menu...")
+}
+
+// promptClassifier asks which backend should look for sensitive data. The
+// LLM classifier is only offered once a model is configured.
+func promptClassifier() (string, error) {
+	classifiers := []string{audit.ClassifierMacie, audit.ClassifierLocal}
+	items := []string{
+		"Amazon Macie classification job (thorough, billed by Macie)",
+		fmt.Sprintf("Local scan of up to %d objects (text only, no Macie charges)", defaultLocalMaxObjects),
+	}
+	if model := config.GetLLMModel(); model != "" {
+		classifiers = append(classifiers, audit.ClassifierLLM)
+		items = append(items, fmt.Sprintf("Review excerpts of %d objects with %s (sends object contents to the model endpoint)", defaultLLMSample, model))
+	}
+
+	prompt := promptui.Select{
+		Label: "How should the bucket be checked for sensitive data?",
+		Items: items,
+	}
+	idx, _, err := prompt.Run()
+	if err != nil {
+		return "", err
+	}
+	return classifiers[idx], nil
+}
+
+// promptConfirm asks a yes or no question, defaulting to no
+func promptConfirm(label string) bool {
+	prompt := promptui.Prompt{
+		Label:     label,
+		IsConfirm: true,
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// promptReattach asks whether to wait for a Macie job an earlier run started
+// for the bucket rather than create a new one
+func promptReattach(job models.MacieJobRecord) bool {
+	prompt := promptui.Prompt{
+		Label:     fmt.Sprintf("Macie job %s for this bucket was started %s. Reattach to it", job.JobID, job.CreatedAt.Local().Format(time.DateTime)),
+		IsConfirm: true,
+		Default:   "y",
+	}
+	_, err := prompt.Run()
+	return err == nil
+}
+
+// browseSensitiveObjects lets the user drill into what Macie found in each object
+func browseSensitiveObjects(objects []models.SensitiveObject) {
+	items := make([]string, 0, len(objects)+1)
+	for _, object := range objects {
+		items = append(items, fmt.Sprintf("[%s] %s (%d occurrence(s))", strings.ToUpper(object.Severity.String()), object.Key, object.Occurrences()))
+	}
+	items = append(items, "[ Exit ]")
+
+	for {
+		prompt := &promptui.Select{
+			Label: "Objects with sensitive data (Enter for details, Ctrl+C or Exit option to return)",
+			Items: items,
+			Size:  10,
+		}
+		idx, _, err := prompt.Run()
+		if err == promptui.ErrInterrupt || idx == len(objects) {
+			return
+		}
+		if err != nil {
+			ui.ShowError("Error displaying objects: %v", err)
+			log.Printf("Error displaying objects: %v", err)
+			return
+		}
+		audit.WriteSensitiveObject(color.Output, objects[idx])
 	}
 }
 
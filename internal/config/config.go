@@ -3,11 +3,18 @@ package config
 import (
 	"os"
 	"strconv"
+	"strings"
 	"time"
 )
 
 const (
-	defaultMacieTimeout = 40 * time.Minute
+	defaultMacieTimeout     = 40 * time.Minute
+	defaultAuditConcurrency = 5
+	defaultCriticalTag      = "data-classification=critical"
+	defaultObjectSampleRate = 10
+	defaultMacieJobsFile    = "macie_jobs.json"
+	defaultLLMAPIStyle      = "openai"
+	defaultLLMTokenBudget   = 100000
 )
 
 // GetMacieTimeout returns the Macie job timeout duration from environment variable
@@ -25,3 +32,116 @@ func GetMacieTimeout() time.Duration {
 
 	return time.Duration(timeout) * time.Minute
 }
+
+// GetAuditConcurrency returns how many buckets are audited in parallel from
+// environment variable or falls back to default value (5)
+func GetAuditConcurrency() int {
+	concurrencyStr := os.Getenv("AUDIT_CONCURRENCY")
+	if concurrencyStr == "" {
+		return defaultAuditConcurrency
+	}
+
+	concurrency, err := strconv.Atoi(concurrencyStr)
+	if err != nil || concurrency < 1 {
+		return defaultAuditConcurrency
+	}
+
+	return concurrency
+}
+
+// GetCriticalBucketTag returns the tag key and value that mark a bucket as
+// holding critical data from environment variable CRITICAL_BUCKET_TAG, in
+// key=value form, or falls back to default value (data-classification=critical)
+func GetCriticalBucketTag() (string, string) {
+	tag := os.Getenv("CRITICAL_BUCKET_TAG")
+	key, value, ok := strings.Cut(tag, "=")
+	if !ok || key == "" {
+		key, value, _ = strings.Cut(defaultCriticalTag, "=")
+	}
+	return key, value
+}
+
+// MaxObjectSampleRate is the highest object sampling rate accepted, in
+// requests per second
+const MaxObjectSampleRate = 1000
+
+// GetObjectSampleRate returns how many object requests per second object
+// sampling makes for each bucket from environment variable OBJECT_SAMPLE_RATE
+// or falls back to default value (10) when it is unset or not between 1 and
+// MaxObjectSampleRate
+func GetObjectSampleRate() int {
+	rateStr := os.Getenv("OBJECT_SAMPLE_RATE")
+	if rateStr == "" {
+		return defaultObjectSampleRate
+	}
+
+	rate, err := strconv.Atoi(rateStr)
+	if err != nil || rate < 1 || rate > MaxObjectSampleRate {
+		return defaultObjectSampleRate
+	}
+
+	return rate
+}
+
+// GetMacieConfigFile returns the path of the JSON file selecting Macie data
+// identifiers and allow lists from environment variable MACIE_CONFIG_FILE,
+// or an empty string to use Macie's defaults
+func GetMacieConfigFile() string {
+	return os.Getenv("MACIE_CONFIG_FILE")
+}
+
+// GetMacieJobsFile returns the path of the file recording the Macie jobs the
+// tool created from environment variable MACIE_JOBS_FILE or falls back to
+// default value (macie_jobs.json)
+func GetMacieJobsFile() string {
+	if path := os.Getenv("MACIE_JOBS_FILE"); path != "" {
+		return path
+	}
+	return defaultMacieJobsFile
+}
+
+// GetLLMEndpoint returns the base URL of the model endpoint used by the LLM
+// classifier from environment variable LLM_ENDPOINT, or an empty string to
+// use the Bedrock endpoint of the audited region
+func GetLLMEndpoint() string {
+	return os.Getenv("LLM_ENDPOINT")
+}
+
+// GetLLMAPIStyle returns the API the model endpoint speaks, openai or
+// bedrock, from environment variable LLM_API_STYLE or falls back to default
+// value (openai)
+func GetLLMAPIStyle() string {
+	if style := os.Getenv("LLM_API_STYLE"); style != "" {
+		return style
+	}
+	return defaultLLMAPIStyle
+}
+
+// GetLLMModel returns the model the LLM classifier uses from environment
+// variable LLM_MODEL, or an empty string when none is configured
+func GetLLMModel() string {
+	return os.Getenv("LLM_MODEL")
+}
+
+// GetLLMAPIKey returns the bearer token sent to OpenAI-compatible endpoints
+// from environment variable LLM_API_KEY, or an empty string for none
+func GetLLMAPIKey() string {
+	return os.Getenv("LLM_API_KEY")
+}
+
+// GetLLMTokenBudget returns how many tokens a run may spend on model requests
+// from environment variable LLM_TOKEN_BUDGET, where 0 means no limit, or falls
+// back to default value (100000)
+func GetLLMTokenBudget() int {
+	budgetStr := os.Getenv("LLM_TOKEN_BUDGET")
+	if budgetStr == "" {
+		return defaultLLMTokenBudget
+	}
+
+	budget, err := strconv.Atoi(budgetStr)
+	if err != nil || budget < 0 {
+		return defaultLLMTokenBudget
+	}
+
+	return budget
+}
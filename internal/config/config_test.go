@@ -49,3 +49,179 @@ func TestGetMacieTimeout(t *testing.T) {
 		})
 	}
 }
+
+func TestGetAuditConcurrency(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedValue int
+		setup         func()
+		cleanup       func()
+	}{
+		{
+			name:          "Default value when env not set",
+			expectedValue: defaultAuditConcurrency,
+			setup:         func() { os.Unsetenv("AUDIT_CONCURRENCY") },
+			cleanup:       func() {},
+		},
+		{
+			name:          "Custom value from env",
+			expectedValue: 20,
+			setup:         func() { os.Setenv("AUDIT_CONCURRENCY", "20") },
+			cleanup:       func() { os.Unsetenv("AUDIT_CONCURRENCY") },
+		},
+		{
+			name:          "Non-positive value falls back to default",
+			expectedValue: defaultAuditConcurrency,
+			setup:         func() { os.Setenv("AUDIT_CONCURRENCY", "0") },
+			cleanup:       func() { os.Unsetenv("AUDIT_CONCURRENCY") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			defer tt.cleanup()
+
+			got := GetAuditConcurrency()
+			if got != tt.expectedValue {
+				t.Errorf("GetAuditConcurrency() = %v, want %v", got, tt.expectedValue)
+			}
+		})
+	}
+}
+
+func TestGetCriticalBucketTag(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedKey   string
+		expectedValue string
+		setup         func()
+		cleanup       func()
+	}{
+		{
+			name:          "Default tag when env not set",
+			expectedKey:   "data-classification",
+			expectedValue: "critical",
+			setup:         func() { os.Unsetenv("CRITICAL_BUCKET_TAG") },
+			cleanup:       func() {},
+		},
+		{
+			name:          "Custom tag from env",
+			expectedKey:   "tier",
+			expectedValue: "gold",
+			setup:         func() { os.Setenv("CRITICAL_BUCKET_TAG", "tier=gold") },
+			cleanup:       func() { os.Unsetenv("CRITICAL_BUCKET_TAG") },
+		},
+		{
+			name:          "Malformed tag falls back to default",
+			expectedKey:   "data-classification",
+			expectedValue: "critical",
+			setup:         func() { os.Setenv("CRITICAL_BUCKET_TAG", "critical") },
+			cleanup:       func() { os.Unsetenv("CRITICAL_BUCKET_TAG") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			defer tt.cleanup()
+
+			key, value := GetCriticalBucketTag()
+			if key != tt.expectedKey || value != tt.expectedValue {
+				t.Errorf("GetCriticalBucketTag() = %v=%v, want %v=%v", key, value, tt.expectedKey, tt.expectedValue)
+			}
+		})
+	}
+}
+
+func TestGetObjectSampleRate(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedValue int
+		setup         func()
+		cleanup       func()
+	}{
+		{
+			name:          "Default value when env not set",
+			expectedValue: defaultObjectSampleRate,
+			setup:         func() { os.Unsetenv("OBJECT_SAMPLE_RATE") },
+			cleanup:       func() {},
+		},
+		{
+			name:          "Custom value from env",
+			expectedValue: 50,
+			setup:         func() { os.Setenv("OBJECT_SAMPLE_RATE", "50") },
+			cleanup:       func() { os.Unsetenv("OBJECT_SAMPLE_RATE") },
+		},
+		{
+			name:          "Non-positive value falls back to default",
+			expectedValue: defaultObjectSampleRate,
+			setup:         func() { os.Setenv("OBJECT_SAMPLE_RATE", "0") },
+			cleanup:       func() { os.Unsetenv("OBJECT_SAMPLE_RATE") },
+		},
+		{
+			name:          "Value above the maximum falls back to default",
+			expectedValue: defaultObjectSampleRate,
+			setup:         func() { os.Setenv("OBJECT_SAMPLE_RATE", "2000000000") },
+			cleanup:       func() { os.Unsetenv("OBJECT_SAMPLE_RATE") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			defer tt.cleanup()
+
+			got := GetObjectSampleRate()
+			if got != tt.expectedValue {
+				t.Errorf("GetObjectSampleRate() = %v, want %v", got, tt.expectedValue)
+			}
+		})
+	}
+}
+
+func TestGetLLMTokenBudget(t *testing.T) {
+	tests := []struct {
+		name          string
+		expectedValue int
+		setup         func()
+		cleanup       func()
+	}{
+		{
+			name:          "Default value when env not set",
+			expectedValue: defaultLLMTokenBudget,
+			setup:         func() { os.Unsetenv("LLM_TOKEN_BUDGET") },
+			cleanup:       func() {},
+		},
+		{
+			name:          "Custom value from env",
+			expectedValue: 5000,
+			setup:         func() { os.Setenv("LLM_TOKEN_BUDGET", "5000") },
+			cleanup:       func() { os.Unsetenv("LLM_TOKEN_BUDGET") },
+		},
+		{
+			name:          "Zero disables the limit",
+			expectedValue: 0,
+			setup:         func() { os.Setenv("LLM_TOKEN_BUDGET", "0") },
+			cleanup:       func() { os.Unsetenv("LLM_TOKEN_BUDGET") },
+		},
+		{
+			name:          "Negative value falls back to default",
+			expectedValue: defaultLLMTokenBudget,
+			setup:         func() { os.Setenv("LLM_TOKEN_BUDGET", "-1") },
+			cleanup:       func() { os.Unsetenv("LLM_TOKEN_BUDGET") },
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.setup()
+			defer tt.cleanup()
+
+			got := GetLLMTokenBudget()
+			if got != tt.expectedValue {
+				t.Errorf("GetLLMTokenBudget() = %v, want %v", got, tt.expectedValue)
+			}
+		})
+	}
+}
@@ -0,0 +1,166 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+)
+
+// extractGzip decompresses a gzip stream and extracts what it holds, which is
+// named after the archive without its extension
+func (e *extractor) extractGzip(name string, r *bufio.Reader, truncated bool, depth int) error {
+	compressed := &counting{r: r}
+	gz, err := gzip.NewReader(compressed)
+	if err != nil {
+		e.skip(name, FormatGzip, fmt.Sprintf("invalid gzip stream: %v", err))
+		return nil
+	}
+	defer gz.Close()
+
+	inner := gz.Name
+	if inner == "" {
+		base := path.Base(e.fileName(name))
+		switch ext := strings.ToLower(path.Ext(base)); ext {
+		case ".tgz":
+			inner = strings.TrimSuffix(base, path.Ext(base)) + ".tar"
+		case ".gz":
+			inner = strings.TrimSuffix(base, path.Ext(base))
+		default:
+			inner = base
+		}
+	}
+	decompressed := e.bound(gz, func() int64 { return compressed.n })
+	return e.extract(memberName(name, inner), bufio.NewReader(tolerateTruncation(decompressed, truncated)), truncated, depth+1)
+}
+
+// extractTar extracts the regular files in a tar archive
+func (e *extractor) extractTar(name string, r io.Reader, truncated bool, depth int) error {
+	tr := tar.NewReader(r)
+	for entries := 0; ; entries++ {
+		header, err := tr.Next()
+		if err == io.EOF || (truncated && errors.Is(err, io.ErrUnexpectedEOF)) {
+			return nil
+		}
+		if err != nil {
+			e.skip(name, FormatTar, fmt.Sprintf("invalid tar archive: %v", err))
+			return nil
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		if entries >= e.opts.MaxEntries {
+			e.skip(name, FormatTar, fmt.Sprintf("only the first %d files were read", e.opts.MaxEntries))
+			return nil
+		}
+
+		member := memberName(name, header.Name)
+		// Tar does not compress, so only the total limit applies
+		content := e.bound(tr, func() int64 { return header.Size })
+		err = e.extract(member, bufio.NewReader(tolerateTruncation(content, truncated)), truncated, depth+1)
+		if done, err := e.memberError(member, err); done {
+			return err
+		}
+	}
+}
+
+// extractZIP extracts the files in a ZIP archive, or the text of an Office
+// document, which is a ZIP archive of XML files
+func (e *extractor) extractZIP(name string, data []byte, format string, depth int) error {
+	zr, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		e.skip(name, format, fmt.Sprintf("invalid ZIP archive: %v", err))
+		return nil
+	}
+
+	// Office documents saved under another extension are still recognized
+	if format == FormatZIP {
+		for _, f := range zr.File {
+			switch f.Name {
+			case "word/document.xml":
+				format = FormatDOCX
+			case "xl/workbook.xml":
+				format = FormatXLSX
+			}
+		}
+	}
+	switch format {
+	case FormatDOCX:
+		return e.extractDOCX(name, zr)
+	case FormatXLSX:
+		return e.extractXLSX(name, zr)
+	}
+
+	entries := 0
+	for _, f := range zr.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if entries >= e.opts.MaxEntries {
+			e.skip(name, FormatZIP, fmt.Sprintf("only the first %d of %d files were read", e.opts.MaxEntries, len(zr.File)))
+			return nil
+		}
+		entries++
+
+		member := memberName(name, f.Name)
+		err := e.extractZIPFile(member, f, depth)
+		if done, err := e.memberError(member, err); done {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *extractor) extractZIPFile(member string, f *zip.File, depth int) error {
+	if f.Flags&0x1 != 0 {
+		e.skip(member, "", "encrypted")
+		return nil
+	}
+	// Reject bombs from their headers before decompressing anything
+	if f.UncompressedSize64 > ratioGrace && f.UncompressedSize64 > f.CompressedSize64*uint64(e.opts.MaxRatio) {
+		e.skip(member, "", fmt.Sprintf("compression ratio above %d", e.opts.MaxRatio))
+		return nil
+	}
+	if int64(f.UncompressedSize64) > e.remaining {
+		return fmt.Errorf("%w: object expands to more than %d bytes", ErrLimitExceeded, e.opts.MaxBytes)
+	}
+
+	rc, err := f.Open()
+	if err != nil {
+		e.skip(member, "", fmt.Sprintf("unable to open: %v", err))
+		return nil
+	}
+	defer rc.Close()
+	content := e.bound(rc, func() int64 { return int64(f.CompressedSize64) })
+	return e.extract(member, bufio.NewReader(content), false, depth+1)
+}
+
+// memberError handles the error from extracting a file in an archive. A file
+// that breaks a limit is skipped, and the rest of the archive too once the
+// total limit is reached. done reports whether to stop reading the archive.
+func (e *extractor) memberError(member string, err error) (done bool, _ error) {
+	if !errors.Is(err, ErrLimitExceeded) {
+		return err != nil, err
+	}
+	e.skip(member, "", err.Error())
+	return e.remaining < 0, nil
+}
+
+// readZIPFile reads a file from an Office document within the limits
+func (e *extractor) readZIPFile(f *zip.File) ([]byte, error) {
+	if f.UncompressedSize64 > ratioGrace && f.UncompressedSize64 > f.CompressedSize64*uint64(e.opts.MaxRatio) {
+		return nil, fmt.Errorf("%w: compression ratio of %s above %d", ErrLimitExceeded, f.Name, e.opts.MaxRatio)
+	}
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	return io.ReadAll(e.bound(rc, func() int64 { return int64(f.CompressedSize64) }))
+}
@@ -0,0 +1,305 @@
+// Package extract turns objects into text that content scanners can read. It
+// detects the format of an object and yields the text of PDFs, Word documents,
+// Excel workbooks, JSON and Parquet files, looking inside gzip, tar and ZIP
+// archives within limits that stop decompression bombs. Columns reads the
+// values of CSV, JSON and Parquet tables by column instead.
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"path"
+	"strings"
+)
+
+// Formats Extract recognizes, as MIME types
+const (
+	FormatText    = "text/plain"
+	FormatCSV     = "text/csv"
+	FormatJSON    = "application/json"
+	FormatPDF     = "application/pdf"
+	FormatDOCX    = "application/vnd.openxmlformats-officedocument.wordprocessingml.document"
+	FormatXLSX    = "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	FormatZIP     = "application/zip"
+	FormatGzip    = "application/gzip"
+	FormatTar     = "application/x-tar"
+	FormatParquet = "application/vnd.apache.parquet"
+)
+
+// Defaults for the limits in Options
+const (
+	DefaultMaxDepth   = 3
+	DefaultMaxEntries = 1000
+	DefaultMaxBytes   = 100 * 1024 * 1024
+	DefaultMaxRatio   = 100
+)
+
+// ratioGrace is how much a stream may expand before its compression ratio is
+// checked, so small, highly compressible files are not mistaken for bombs
+const ratioGrace = 1024 * 1024
+
+// ErrLimitExceeded is returned once an object expands past Options.MaxBytes
+var ErrLimitExceeded = errors.New("extraction limit exceeded")
+
+// Options limits how far Extract unpacks an object. Zero values use the
+// defaults.
+type Options struct {
+	// MaxDepth caps how many archives may be nested in each other
+	MaxDepth int
+	// MaxEntries caps how many files are read from each archive
+	MaxEntries int
+	// MaxBytes caps the decompressed bytes read from one object in total
+	MaxBytes int64
+	// MaxRatio caps how many times larger than its compressed size a file may
+	// decompress to
+	MaxRatio int64
+}
+
+func (o Options) withDefaults() Options {
+	if o.MaxDepth <= 0 {
+		o.MaxDepth = DefaultMaxDepth
+	}
+	if o.MaxEntries <= 0 {
+		o.MaxEntries = DefaultMaxEntries
+	}
+	if o.MaxBytes <= 0 {
+		o.MaxBytes = DefaultMaxBytes
+	}
+	if o.MaxRatio <= 0 {
+		o.MaxRatio = DefaultMaxRatio
+	}
+	return o
+}
+
+// Document is text extracted from an object or from a file inside it
+type Document struct {
+	// Name is empty for the object itself, or the path of the file inside it,
+	// with the paths in nested archives joined by "!"
+	Name string
+	// Format is the MIME type the text was extracted from
+	Format string
+	Text   io.Reader
+}
+
+// Skipped records a document no text was extracted from
+type Skipped struct {
+	Name   string
+	Format string
+	Reason string
+}
+
+// Extract detects the format of the object read from r and calls fn with the
+// text of each document in it. name is the object key, whose extension helps
+// detect the format. truncated reports that r holds only the start of the
+// object, which is enough for text but not for formats indexed from the end,
+// such as ZIP, PDF and Parquet.
+//
+// Documents no text can be extracted from are returned as skipped, with the
+// reason. An error returned by fn stops the extraction and is returned, unless
+// it wraps ErrLimitExceeded, which skips the rest of the object instead.
+func Extract(name string, r io.Reader, truncated bool, opts Options, fn func(Document) error) ([]Skipped, error) {
+	e := &extractor{key: name, opts: opts.withDefaults(), fn: fn}
+	e.remaining = e.opts.MaxBytes
+	err := e.extract("", bufio.NewReader(r), truncated, 0)
+	if errors.Is(err, ErrLimitExceeded) {
+		e.skip("", "", err.Error())
+		err = nil
+	}
+	return e.skipped, err
+}
+
+type extractor struct {
+	key       string
+	opts      Options
+	fn        func(Document) error
+	remaining int64
+	skipped   []Skipped
+}
+
+func (e *extractor) skip(name, format, reason string) {
+	e.skipped = append(e.skipped, Skipped{Name: name, Format: format, Reason: reason})
+}
+
+// emit passes a document to fn
+func (e *extractor) emit(name, format string, text io.Reader) error {
+	return e.fn(Document{Name: name, Format: format, Text: text})
+}
+
+// extract handles one document: the object itself when name is empty, or a
+// file inside it
+func (e *extractor) extract(name string, r *bufio.Reader, truncated bool, depth int) error {
+	head, _ := r.Peek(512)
+	format := Detect(e.fileName(name), head)
+
+	switch format {
+	case FormatText, FormatCSV:
+		return e.emit(name, format, tolerateTruncation(r, truncated))
+	case FormatJSON:
+		return e.extractJSON(name, r, truncated)
+	case FormatGzip, FormatTar:
+		if depth >= e.opts.MaxDepth {
+			e.skip(name, format, fmt.Sprintf("archives nested more than %d deep", e.opts.MaxDepth))
+			return nil
+		}
+		if format == FormatGzip {
+			return e.extractGzip(name, r, truncated, depth)
+		}
+		return e.extractTar(name, r, truncated, depth)
+	case FormatZIP, FormatDOCX, FormatXLSX, FormatPDF, FormatParquet:
+		if format == FormatZIP && depth >= e.opts.MaxDepth {
+			e.skip(name, format, fmt.Sprintf("archives nested more than %d deep", e.opts.MaxDepth))
+			return nil
+		}
+		// These formats are indexed from the end of the file
+		if truncated {
+			e.skip(name, format, "only the start of the object was read, which is not enough for this format")
+			return nil
+		}
+		data, err := e.readAll(r)
+		if err != nil {
+			return err
+		}
+		switch format {
+		case FormatPDF:
+			return e.extractPDF(name, data)
+		case FormatParquet:
+			return e.extractParquet(name, data)
+		default:
+			return e.extractZIP(name, data, format, depth)
+		}
+	default:
+		e.skip(name, format, "unsupported format "+format)
+		return nil
+	}
+}
+
+// readAll reads a whole document into memory, up to Options.MaxBytes
+func (e *extractor) readAll(r io.Reader) ([]byte, error) {
+	data, err := io.ReadAll(io.LimitReader(r, e.opts.MaxBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(data)) > e.opts.MaxBytes {
+		return nil, fmt.Errorf("%w: object is larger than %d bytes", ErrLimitExceeded, e.opts.MaxBytes)
+	}
+	return data, nil
+}
+
+// fileName is the name used to detect the format of a document
+func (e *extractor) fileName(name string) string {
+	if name == "" {
+		return e.key
+	}
+	return name
+}
+
+// memberName joins the name of a file inside an archive to the archive's
+func memberName(archive, member string) string {
+	if archive == "" {
+		return member
+	}
+	return archive + "!" + member
+}
+
+// Detect returns the MIME type of a file from its name and first bytes
+func Detect(name string, head []byte) string {
+	ext := strings.ToLower(path.Ext(name))
+	switch {
+	case bytes.HasPrefix(head, []byte("%PDF-")):
+		return FormatPDF
+	case bytes.HasPrefix(head, []byte{0x1f, 0x8b}):
+		return FormatGzip
+	case bytes.HasPrefix(head, []byte("PAR1")):
+		return FormatParquet
+	case bytes.HasPrefix(head, []byte("PK\x03\x04")), bytes.HasPrefix(head, []byte("PK\x05\x06")):
+		// Office documents are ZIP archives; the extension tells them apart
+		// without reading the central directory
+		switch ext {
+		case ".docx", ".docm":
+			return FormatDOCX
+		case ".xlsx", ".xlsm":
+			return FormatXLSX
+		}
+		return FormatZIP
+	case len(head) > 262 && string(head[257:262]) == "ustar":
+		return FormatTar
+	}
+
+	if sniffed := http.DetectContentType(head); !strings.HasPrefix(sniffed, "text/") {
+		return sniffed
+	}
+	switch ext {
+	case ".csv", ".tsv":
+		return FormatCSV
+	case ".json", ".jsonl", ".ndjson", ".geojson":
+		return FormatJSON
+	}
+	if trimmed := bytes.TrimLeft(head, " \t\r\n\ufeff"); len(trimmed) > 0 && (trimmed[0] == '{' || trimmed[0] == '[') {
+		return FormatJSON
+	}
+	return FormatText
+}
+
+// bounded counts the bytes read from a decompressor against the limits of the
+// extraction
+type bounded struct {
+	r io.Reader
+	e *extractor
+	// compressed returns how many compressed bytes produced the output
+	compressed func() int64
+	out        int64
+}
+
+func (e *extractor) bound(r io.Reader, compressed func() int64) io.Reader {
+	return &bounded{r: r, e: e, compressed: compressed}
+}
+
+func (b *bounded) Read(p []byte) (int, error) {
+	n, err := b.r.Read(p)
+	b.out += int64(n)
+	b.e.remaining -= int64(n)
+	if b.e.remaining < 0 {
+		return n, fmt.Errorf("%w: object expands to more than %d bytes", ErrLimitExceeded, b.e.opts.MaxBytes)
+	}
+	if b.out > ratioGrace && b.out > b.compressed()*b.e.opts.MaxRatio {
+		return n, fmt.Errorf("%w: compression ratio above %d", ErrLimitExceeded, b.e.opts.MaxRatio)
+	}
+	return n, err
+}
+
+// counting counts the bytes read through it
+type counting struct {
+	r io.Reader
+	n int64
+}
+
+func (c *counting) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// tolerateTruncation ends the text of a truncated object at the cut instead of
+// failing with an unexpected EOF
+func tolerateTruncation(r io.Reader, truncated bool) io.Reader {
+	if !truncated {
+		return r
+	}
+	return truncatedReader{r}
+}
+
+type truncatedReader struct {
+	r io.Reader
+}
+
+func (t truncatedReader) Read(p []byte) (int, error) {
+	n, err := t.r.Read(p)
+	if errors.Is(err, io.ErrUnexpectedEOF) {
+		err = io.EOF
+	}
+	return n, err
+}
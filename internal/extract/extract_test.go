@@ -0,0 +1,220 @@
+package extract
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// extractAll returns the text of each document by name
+func extractAll(t *testing.T, name string, data []byte, truncated bool, opts Options) (map[string]string, []Skipped) {
+	t.Helper()
+	docs := map[string]string{}
+	skipped, err := Extract(name, bytes.NewReader(data), truncated, opts, func(doc Document) error {
+		text, err := io.ReadAll(doc.Text)
+		docs[doc.Name] = string(text)
+		return err
+	})
+	assert.NoError(t, err)
+	return docs, skipped
+}
+
+func zipFile(t *testing.T, files map[string][]byte, order ...string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, name := range order {
+		w, err := zw.Create(name)
+		assert.NoError(t, err)
+		_, err = w.Write(files[name])
+		assert.NoError(t, err)
+	}
+	assert.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func gzipFile(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	_, err := gz.Write(data)
+	assert.NoError(t, err)
+	assert.NoError(t, gz.Close())
+	return buf.Bytes()
+}
+
+func TestDetect(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		head     []byte
+		expected string
+	}{
+		{name: "Plain text", fileName: "notes.txt", head: []byte("hello\n"), expected: FormatText},
+		{name: "Latin-1 text", fileName: "notes.txt", head: []byte("caf\xe9\n"), expected: FormatText},
+		{name: "CSV by extension", fileName: "export.csv", head: []byte("a,b\n1,2\n"), expected: FormatCSV},
+		{name: "JSON by extension", fileName: "data.jsonl", head: []byte(`{"a":1}`), expected: FormatJSON},
+		{name: "JSON by content", fileName: "data", head: []byte("\n  [1, 2]"), expected: FormatJSON},
+		{name: "PDF", fileName: "report", head: []byte("%PDF-1.7\n"), expected: FormatPDF},
+		{name: "Gzip", fileName: "dump.gz", head: []byte{0x1f, 0x8b, 0x08}, expected: FormatGzip},
+		{name: "Parquet", fileName: "part-0000", head: []byte("PAR1\x15\x04"), expected: FormatParquet},
+		{name: "ZIP", fileName: "backup.zip", head: []byte("PK\x03\x04"), expected: FormatZIP},
+		{name: "Word document", fileName: "Contract.DOCX", head: []byte("PK\x03\x04"), expected: FormatDOCX},
+		{name: "Excel workbook", fileName: "payroll.xlsx", head: []byte("PK\x03\x04"), expected: FormatXLSX},
+		{name: "PNG image", fileName: "logo.png", head: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"), expected: "image/png"},
+		{name: "Unknown binary", fileName: "blob", head: []byte{0x00, 0x01, 0x02}, expected: "application/octet-stream"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.expected, Detect(tt.fileName, tt.head))
+		})
+	}
+}
+
+func TestExtract_TextAndJSON(t *testing.T) {
+	docs, skipped := extractAll(t, "notes.txt", []byte("line one\nline two\n"), false, Options{})
+	assert.Empty(t, skipped)
+	assert.Equal(t, map[string]string{"": "line one\nline two\n"}, docs)
+
+	docs, skipped = extractAll(t, "customers.json", []byte(`{"customers":[{"name":"Jane","email":"jane@example.com","card":4111111111111111,"vip":true,"note":null}]}`), false, Options{})
+	assert.Empty(t, skipped)
+	assert.Equal(t, "customers[0].name: Jane\ncustomers[0].email: jane@example.com\ncustomers[0].card: 4111111111111111\ncustomers[0].vip: true\n", docs[""])
+
+	// JSON Lines yield each document in turn
+	docs, _ = extractAll(t, "events.jsonl", []byte("{\"user\":\"a@example.com\"}\n{\"user\":\"b@example.com\"}\n"), false, Options{})
+	assert.Equal(t, "user: a@example.com\nuser: b@example.com\n", docs[""])
+
+	// Text that only looks like JSON is read as is
+	docs, _ = extractAll(t, "app.log", []byte("[INFO] started\n[WARN] slow\n"), false, Options{})
+	assert.Equal(t, "[INFO] started\n[WARN] slow\n", docs[""])
+
+	// A truncated document yields the values before the cut
+	docs, _ = extractAll(t, "big.json", []byte(`[{"email":"jane@example.com"},{"email":"jo`), true, Options{})
+	assert.Equal(t, "[0].email: jane@example.com\n", docs[""])
+}
+
+func TestExtract_Archives(t *testing.T) {
+	var tarBuf bytes.Buffer
+	tw := tar.NewWriter(&tarBuf)
+	content := []byte("AWS_SECRET=abc\n")
+	assert.NoError(t, tw.WriteHeader(&tar.Header{Name: "app/.env", Mode: 0600, Size: int64(len(content)), Typeflag: tar.TypeReg}))
+	_, err := tw.Write(content)
+	assert.NoError(t, err)
+	assert.NoError(t, tw.Close())
+
+	inner := zipFile(t, map[string][]byte{"deep.txt": []byte("deep text\n")}, "deep.txt")
+	archive := zipFile(t, map[string][]byte{
+		"customers.csv": []byte("name,email\njane,jane@example.com\n"),
+		"logo.png":      []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR"),
+		"config.tgz":    gzipFile(t, tarBuf.Bytes()),
+		"nested.zip":    inner,
+	}, "customers.csv", "logo.png", "config.tgz", "nested.zip")
+
+	docs, skipped := extractAll(t, "backup.zip", archive, false, Options{})
+
+	assert.Equal(t, map[string]string{
+		"customers.csv":                  "name,email\njane,jane@example.com\n",
+		"config.tgz!config.tar!app/.env": "AWS_SECRET=abc\n",
+		"nested.zip!deep.txt":            "deep text\n",
+	}, docs)
+	assert.Equal(t, []Skipped{{Name: "logo.png", Format: "image/png", Reason: "unsupported format image/png"}}, skipped)
+
+	// Gzip files are named after the object without the extension
+	docs, _ = extractAll(t, "logs/app.log.gz", gzipFile(t, []byte("GET /index.html\n")), false, Options{})
+	assert.Equal(t, map[string]string{"app.log": "GET /index.html\n"}, docs)
+}
+
+func TestExtract_Limits(t *testing.T) {
+	t.Run("Nesting depth", func(t *testing.T) {
+		level3 := zipFile(t, map[string][]byte{"secret.txt": []byte("hidden\n")}, "secret.txt")
+		level2 := zipFile(t, map[string][]byte{"c.zip": level3}, "c.zip")
+		level1 := zipFile(t, map[string][]byte{"b.zip": level2}, "b.zip")
+
+		docs, skipped := extractAll(t, "a.zip", level1, false, Options{MaxDepth: 2})
+
+		assert.Empty(t, docs)
+		assert.Equal(t, []Skipped{{Name: "b.zip!c.zip", Format: FormatZIP, Reason: "archives nested more than 2 deep"}}, skipped)
+	})
+
+	t.Run("Entries per archive", func(t *testing.T) {
+		archive := zipFile(t, map[string][]byte{"1.txt": []byte("one"), "2.txt": []byte("two"), "3.txt": []byte("three")}, "1.txt", "2.txt", "3.txt")
+
+		docs, skipped := extractAll(t, "many.zip", archive, false, Options{MaxEntries: 2})
+
+		assert.Len(t, docs, 2)
+		assert.Equal(t, []Skipped{{Name: "", Format: FormatZIP, Reason: "only the first 2 of 3 files were read"}}, skipped)
+	})
+
+	t.Run("Gzip bomb", func(t *testing.T) {
+		bomb := gzipFile(t, bytes.Repeat([]byte("a"), 20*1024*1024))
+
+		var read int
+		skipped, err := Extract("bomb.txt.gz", bytes.NewReader(bomb), false, Options{}, func(doc Document) error {
+			n, err := io.Copy(io.Discard, doc.Text)
+			read = int(n)
+			return err
+		})
+
+		assert.NoError(t, err)
+		assert.Less(t, read, 20*1024*1024)
+		assert.Len(t, skipped, 1)
+		assert.Contains(t, skipped[0].Reason, "compression ratio above 100")
+	})
+
+	t.Run("ZIP bomb rejected from its header", func(t *testing.T) {
+		archive := zipFile(t, map[string][]byte{
+			"zeros.txt": bytes.Repeat([]byte("0"), 10*1024*1024),
+			"small.txt": []byte("small\n"),
+		}, "zeros.txt", "small.txt")
+
+		docs, skipped := extractAll(t, "bomb.zip", archive, false, Options{})
+
+		assert.Equal(t, map[string]string{"small.txt": "small\n"}, docs)
+		assert.Equal(t, []Skipped{{Name: "zeros.txt", Reason: "compression ratio above 100"}}, skipped)
+	})
+
+	t.Run("Total size", func(t *testing.T) {
+		archive := zipFile(t, map[string][]byte{
+			"a.txt": []byte(strings.Repeat("a", 600)),
+			"b.txt": []byte(strings.Repeat("b", 600)),
+		}, "a.txt", "b.txt")
+
+		docs, skipped := extractAll(t, "big.zip", archive, false, Options{MaxBytes: 1000})
+
+		assert.Len(t, docs, 1)
+		assert.Len(t, skipped, 1)
+		assert.Equal(t, "b.txt", skipped[0].Name)
+		assert.Contains(t, skipped[0].Reason, "more than 1000 bytes")
+	})
+}
+
+func TestExtract_ObjectLargerThanLimit(t *testing.T) {
+	pdf := []byte("%PDF-1.4\n" + strings.Repeat("%", 2000))
+
+	docs, skipped := extractAll(t, "big.pdf", pdf, false, Options{MaxBytes: 1000})
+
+	assert.Empty(t, docs)
+	assert.Equal(t, []Skipped{{Reason: "extraction limit exceeded: object is larger than 1000 bytes"}}, skipped)
+}
+
+func TestExtract_Truncated(t *testing.T) {
+	archive := zipFile(t, map[string][]byte{"a.txt": []byte("a\n")}, "a.txt")
+
+	docs, skipped := extractAll(t, "backup.zip", archive[:len(archive)/2], true, Options{})
+
+	assert.Empty(t, docs)
+	assert.Equal(t, []Skipped{{Format: FormatZIP, Reason: "only the start of the object was read, which is not enough for this format"}}, skipped)
+
+	// Gzip streams are read up to the cut
+	compressed := gzipFile(t, []byte(strings.Repeat("line of text\n", 1000)))
+	docs, skipped = extractAll(t, "app.log.gz", compressed[:len(compressed)-20], true, Options{})
+	assert.Empty(t, skipped)
+	assert.True(t, strings.HasPrefix(docs["app.log"], "line of text\n"))
+}
@@ -0,0 +1,117 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// extractJSON yields one line per value in a JSON document, or in each
+// document of a JSON Lines file, prefixed with its path, as in
+// customers[0].email: jane@example.com. Minified JSON would otherwise be a
+// single line too long to scan. Input that is not valid JSON is read as text.
+func (e *extractor) extractJSON(name string, r *bufio.Reader, truncated bool) error {
+	data, err := io.ReadAll(tolerateTruncation(r, truncated))
+	if err != nil {
+		return err
+	}
+
+	var text strings.Builder
+	writeValue := func(path string, value any) {
+		if value == nil {
+			return
+		}
+		if path != "" {
+			text.WriteString(path)
+			text.WriteString(": ")
+		}
+		// Keep each value on its own line
+		text.WriteString(strings.ReplaceAll(fmt.Sprint(value), "\n", " "))
+		text.WriteByte('\n')
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	for {
+		err := walkJSON(decoder, writeValue)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			// A truncated document still yields the values before the cut
+			if truncated && text.Len() > 0 {
+				break
+			}
+			return e.emit(name, FormatText, bytes.NewReader(data))
+		}
+	}
+	return e.emit(name, FormatJSON, strings.NewReader(text.String()))
+}
+
+// jsonFrame tracks an object or array being flattened
+type jsonFrame struct {
+	path  string
+	array bool
+	index int
+	// key is the object key of the next value
+	key string
+	// expectKey reports whether the next string is a key
+	expectKey bool
+}
+
+// walkJSON calls fn with each value of the next JSON document in the decoder
+// and its path. Values are strings, json.Number, booleans or nil.
+func walkJSON(decoder *json.Decoder, fn func(path string, value any)) error {
+	var stack []*jsonFrame
+	// valuePath returns the path of the next value and advances the frame
+	valuePath := func() string {
+		if len(stack) == 0 {
+			return ""
+		}
+		frame := stack[len(stack)-1]
+		if frame.array {
+			p := fmt.Sprintf("%s[%d]", frame.path, frame.index)
+			frame.index++
+			return p
+		}
+		frame.expectKey = true
+		if frame.path == "" {
+			return frame.key
+		}
+		return frame.path + "." + frame.key
+	}
+
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			return err
+		}
+		if len(stack) > 0 {
+			if frame := stack[len(stack)-1]; !frame.array && frame.expectKey {
+				if key, ok := token.(string); ok {
+					frame.key = key
+					frame.expectKey = false
+					continue
+				}
+			}
+		}
+
+		switch t := token.(type) {
+		case json.Delim:
+			switch t {
+			case '{', '[':
+				stack = append(stack, &jsonFrame{path: valuePath(), array: t == '[', expectKey: t == '{'})
+				continue
+			default:
+				stack = stack[:len(stack)-1]
+			}
+		default:
+			fn(valuePath(), t)
+		}
+		if len(stack) == 0 {
+			return nil
+		}
+	}
+}
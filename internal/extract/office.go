@@ -0,0 +1,294 @@
+package extract
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// extractDOCX yields the text of a Word document, one paragraph per line,
+// followed by its headers, footers, footnotes and comments
+func (e *extractor) extractDOCX(name string, zr *zip.Reader) error {
+	var parts []*zip.File
+	for _, f := range zr.File {
+		dir, file := path.Split(f.Name)
+		if dir != "word/" {
+			continue
+		}
+		if file == "document.xml" || file == "footnotes.xml" || file == "comments.xml" ||
+			strings.HasPrefix(file, "header") || strings.HasPrefix(file, "footer") {
+			parts = append(parts, f)
+		}
+	}
+	if len(parts) == 0 {
+		e.skip(name, FormatDOCX, "no document.xml in the document")
+		return nil
+	}
+	// The body first, then the rest in a stable order
+	sort.SliceStable(parts, func(i, j int) bool {
+		return parts[i].Name == "word/document.xml" && parts[j].Name != "word/document.xml"
+	})
+
+	var text strings.Builder
+	for _, f := range parts {
+		data, err := e.readZIPFile(f)
+		if err != nil {
+			return err
+		}
+		if err := wordText(&text, data); err != nil {
+			e.skip(name, FormatDOCX, fmt.Sprintf("invalid %s: %v", f.Name, err))
+			return nil
+		}
+	}
+	return e.emit(name, FormatDOCX, strings.NewReader(text.String()))
+}
+
+// wordText writes the text runs of a WordprocessingML part, ending each
+// paragraph with a newline
+func wordText(w *strings.Builder, data []byte) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "t":
+				inText = true
+			case "tab":
+				w.WriteByte('\t')
+			case "br", "cr":
+				w.WriteByte('\n')
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "t":
+				inText = false
+			case "p":
+				w.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inText {
+				w.Write(t)
+			}
+		}
+	}
+}
+
+// extractXLSX yields each worksheet of an Excel workbook as a document named
+// after the sheet, one row per line with the cells separated by commas
+func (e *extractor) extractXLSX(name string, zr *zip.Reader) error {
+	files := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		files[f.Name] = f
+	}
+
+	var shared []string
+	if f := files["xl/sharedStrings.xml"]; f != nil {
+		data, err := e.readZIPFile(f)
+		if err != nil {
+			return err
+		}
+		if shared, err = sharedStrings(data); err != nil {
+			e.skip(name, FormatXLSX, fmt.Sprintf("invalid shared strings: %v", err))
+			return nil
+		}
+	}
+
+	sheets, err := e.worksheets(files)
+	if err != nil {
+		return err
+	}
+	if len(sheets) == 0 {
+		e.skip(name, FormatXLSX, "no worksheets in the workbook")
+		return nil
+	}
+	for _, sheet := range sheets {
+		data, err := e.readZIPFile(sheet.file)
+		if err != nil {
+			return err
+		}
+		var text strings.Builder
+		if err := sheetText(&text, data, shared); err != nil {
+			e.skip(memberName(name, sheet.name), FormatXLSX, fmt.Sprintf("invalid worksheet: %v", err))
+			continue
+		}
+		if err := e.emit(memberName(name, sheet.name), FormatXLSX, strings.NewReader(text.String())); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type worksheet struct {
+	name string
+	file *zip.File
+}
+
+// worksheets lists the sheets of a workbook in order with their names, or the
+// worksheet files by file name when the workbook cannot be read
+func (e *extractor) worksheets(files map[string]*zip.File) ([]worksheet, error) {
+	var workbook struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"http://schemas.openxmlformats.org/officeDocument/2006/relationships id,attr"`
+		} `xml:"sheets>sheet"`
+	}
+	var rels struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Target string `xml:"Target,attr"`
+		} `xml:"Relationship"`
+	}
+	if err := e.unmarshalZIPFile(files["xl/workbook.xml"], &workbook); err != nil {
+		return nil, err
+	}
+	if err := e.unmarshalZIPFile(files["xl/_rels/workbook.xml.rels"], &rels); err != nil {
+		return nil, err
+	}
+	targets := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		target := rel.Target
+		if strings.HasPrefix(target, "/") {
+			target = strings.TrimPrefix(target, "/")
+		} else {
+			target = path.Join("xl", target)
+		}
+		targets[rel.ID] = target
+	}
+
+	var sheets []worksheet
+	for _, sheet := range workbook.Sheets {
+		if f := files[targets[sheet.ID]]; f != nil {
+			sheets = append(sheets, worksheet{name: sheet.Name, file: f})
+		}
+	}
+	if len(sheets) > 0 {
+		return sheets, nil
+	}
+	for fileName, f := range files {
+		if strings.HasPrefix(fileName, "xl/worksheets/") && path.Ext(fileName) == ".xml" {
+			sheets = append(sheets, worksheet{name: strings.TrimSuffix(path.Base(fileName), ".xml"), file: f})
+		}
+	}
+	sort.Slice(sheets, func(i, j int) bool { return sheets[i].name < sheets[j].name })
+	return sheets, nil
+}
+
+// unmarshalZIPFile decodes an XML file, leaving v empty when the file is
+// missing or invalid
+func (e *extractor) unmarshalZIPFile(f *zip.File, v any) error {
+	if f == nil {
+		return nil
+	}
+	data, err := e.readZIPFile(f)
+	if err != nil {
+		return err
+	}
+	_ = xml.Unmarshal(data, v)
+	return nil
+}
+
+// sharedStrings returns the strings cells refer to by index
+func sharedStrings(data []byte) ([]string, error) {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var strs []string
+	var current strings.Builder
+	inText := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return strs, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "si":
+				current.Reset()
+			case "t":
+				inText = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "si":
+				strs = append(strs, current.String())
+			case "t":
+				inText = false
+			}
+		case xml.CharData:
+			if inText {
+				current.Write(t)
+			}
+		}
+	}
+}
+
+// sheetText writes the rows of a worksheet, one per line
+func sheetText(w *strings.Builder, data []byte, shared []string) error {
+	decoder := xml.NewDecoder(bytes.NewReader(data))
+	var cells []string
+	var cellType string
+	var value strings.Builder
+	inValue := false
+	for {
+		token, err := decoder.Token()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			switch t.Name.Local {
+			case "row":
+				cells = cells[:0]
+			case "c":
+				cellType = ""
+				for _, attr := range t.Attr {
+					if attr.Name.Local == "t" {
+						cellType = attr.Value
+					}
+				}
+				value.Reset()
+			case "v", "t":
+				inValue = true
+			}
+		case xml.EndElement:
+			switch t.Name.Local {
+			case "v", "t":
+				inValue = false
+			case "c":
+				cell := value.String()
+				if cellType == "s" {
+					if i, err := strconv.Atoi(cell); err == nil && i >= 0 && i < len(shared) {
+						cell = shared[i]
+					}
+				}
+				cells = append(cells, cell)
+			case "row":
+				w.WriteString(strings.Join(cells, ","))
+				w.WriteByte('\n')
+			}
+		case xml.CharData:
+			if inValue {
+				value.Write(t)
+			}
+		}
+	}
+}
@@ -0,0 +1,67 @@
+package extract
+
+import (
+	"bytes"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExtract_DOCX(t *testing.T) {
+	document := `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<w:document xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:body>
+<w:p><w:r><w:t>Employee: Jane Doe</w:t></w:r></w:p>
+<w:p><w:r><w:t xml:space="preserve">SSN: </w:t></w:r><w:r><w:t>123-45-6789</w:t></w:r></w:p>
+</w:body></w:document>`
+	footer := `<w:ftr xmlns:w="http://schemas.openxmlformats.org/wordprocessingml/2006/main"><w:p><w:r><w:t>Confidential</w:t></w:r></w:p></w:ftr>`
+	docx := zipFile(t, map[string][]byte{
+		"[Content_Types].xml": []byte(`<Types/>`),
+		"word/footer1.xml":    []byte(footer),
+		"word/document.xml":   []byte(document),
+	}, "[Content_Types].xml", "word/footer1.xml", "word/document.xml")
+
+	docs, skipped := extractAll(t, "hr/contract.docx", docx, false, Options{})
+
+	assert.Empty(t, skipped)
+	assert.Equal(t, map[string]string{"": "Employee: Jane Doe\nSSN: 123-45-6789\nConfidential\n"}, docs)
+
+	// Documents are recognised inside archives whatever their name
+	docs, _ = extractAll(t, "backup.zip", zipFile(t, map[string][]byte{"contract": docx}, "contract"), false, Options{})
+	assert.Equal(t, map[string]string{"contract": "Employee: Jane Doe\nSSN: 123-45-6789\nConfidential\n"}, docs)
+}
+
+func TestExtract_XLSX(t *testing.T) {
+	const ns = `xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships"`
+	xlsx := zipFile(t, map[string][]byte{
+		"xl/workbook.xml": []byte(`<workbook ` + ns + `><sheets>
+<sheet name="Customers" sheetId="1" r:id="rId2"/><sheet name="Notes" sheetId="2" r:id="rId1"/>
+</sheets></workbook>`),
+		"xl/_rels/workbook.xml.rels": []byte(`<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">
+<Relationship Id="rId1" Target="worksheets/sheet2.xml"/><Relationship Id="rId2" Target="/xl/worksheets/sheet1.xml"/>
+</Relationships>`),
+		"xl/sharedStrings.xml": []byte(`<sst ` + ns + `><si><t>name</t></si><si><t>card</t></si><si><r><t>Jane </t></r><r><t>Doe</t></r></si></sst>`),
+		"xl/worksheets/sheet1.xml": []byte(`<worksheet ` + ns + `><sheetData>
+<row r="1"><c r="A1" t="s"><v>0</v></c><c r="B1" t="s"><v>1</v></c></row>
+<row r="2"><c r="A2" t="s"><v>2</v></c><c r="B2"><v>4111111111111111</v></c></row>
+</sheetData></worksheet>`),
+		"xl/worksheets/sheet2.xml": []byte(`<worksheet ` + ns + `><sheetData>
+<row r="1"><c r="A1" t="inlineStr"><is><t>call back tomorrow</t></is></c></row>
+</sheetData></worksheet>`),
+	}, "xl/workbook.xml", "xl/_rels/workbook.xml.rels", "xl/sharedStrings.xml", "xl/worksheets/sheet1.xml", "xl/worksheets/sheet2.xml")
+
+	var names []string
+	docs := map[string]string{}
+	skipped, err := Extract("payroll.xlsx", bytes.NewReader(xlsx), false, Options{}, func(doc Document) error {
+		names = append(names, doc.Name)
+		text, err := io.ReadAll(doc.Text)
+		docs[doc.Name] = string(text)
+		return err
+	})
+
+	assert.NoError(t, err)
+	assert.Empty(t, skipped)
+	assert.Equal(t, []string{"Customers", "Notes"}, names)
+	assert.Equal(t, "name,card\nJane Doe,4111111111111111\n", docs["Customers"])
+	assert.Equal(t, "call back tomorrow\n", docs["Notes"])
+}
@@ -0,0 +1,358 @@
+package extract
+
+import (
+	"bytes"
+	"compress/gzip"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Parquet enums, from parquet.thrift
+const (
+	parquetInt32     = 1
+	parquetInt64     = 2
+	parquetByteArray = 6
+
+	parquetUncompressed = 0
+	parquetSnappy       = 1
+	parquetGzip         = 2
+
+	parquetDataPage       = 0
+	parquetDictionaryPage = 2
+	parquetDataPageV2     = 3
+
+	parquetPlain = 0
+
+	parquetRequired = 0
+	parquetRepeated = 2
+)
+
+// parquetMaxNesting caps how deeply schema groups may nest
+const parquetMaxNesting = 64
+
+// parquetCodecs names the compression codecs for skip reasons
+var parquetCodecs = map[int64]string{3: "LZO", 4: "Brotli", 5: "LZ4", 6: "Zstandard", 7: "LZ4 raw"}
+
+// parquetColumn describes a leaf column of the schema
+type parquetColumn struct {
+	maxDefinition int
+	maxRepetition int
+}
+
+// extractParquet yields the string and integer values of a Parquet file, one
+// per line, prefixed with the column name, as in email: jane@example.com.
+func (e *extractor) extractParquet(name string, data []byte) error {
+	var text strings.Builder
+	skipped := len(e.skipped)
+	_, err := e.parquetValues(name, data, func(column, value string) bool {
+		text.WriteString(column)
+		text.WriteString(": ")
+		text.WriteString(value)
+		text.WriteByte('\n')
+		return true
+	})
+	if errors.Is(err, ErrLimitExceeded) {
+		return err
+	}
+	if err != nil {
+		e.skip(name, FormatParquet, err.Error())
+		return nil
+	}
+	if text.Len() == 0 {
+		if len(e.skipped) == skipped {
+			e.skip(name, FormatParquet, "no string or integer columns")
+		}
+		return nil
+	}
+	return e.emit(name, FormatParquet, strings.NewReader(text.String()))
+}
+
+// parquetValues calls fn with each string and integer value of a Parquet
+// file and the dotted path of its column, one column chunk after another.
+// Values are read from dictionary pages and from plain-encoded data pages;
+// columns compressed with codecs other than Snappy and gzip are skipped. fn
+// returns false to stop reading the column chunk. parquetValues returns the
+// number of rows in the file.
+func (e *extractor) parquetValues(name string, data []byte, fn func(column, value string) bool) (int64, error) {
+	metadata, err := parquetMetadata(data)
+	if err != nil {
+		return 0, err
+	}
+	columns, err := parquetSchema(metadata.list(2))
+	if err != nil {
+		return 0, err
+	}
+
+	skippedColumns := map[string]bool{}
+	for _, rowGroup := range metadata.list(4) {
+		rowGroup, _ := rowGroup.(thriftStruct)
+		for _, chunk := range rowGroup.list(1) {
+			chunk, _ := chunk.(thriftStruct)
+			meta := chunk.structField(3)
+			if meta == nil {
+				continue
+			}
+			var path []string
+			for _, part := range meta.list(3) {
+				part, _ := part.([]byte)
+				path = append(path, string(part))
+			}
+			column := strings.Join(path, ".")
+			valueType := meta.int(1)
+			if valueType != parquetByteArray && valueType != parquetInt32 && valueType != parquetInt64 {
+				continue
+			}
+			if codec := meta.int(4); codec != parquetUncompressed && codec != parquetSnappy && codec != parquetGzip {
+				if !skippedColumns[column] {
+					skippedColumns[column] = true
+					codecName, ok := parquetCodecs[codec]
+					if !ok {
+						codecName = strconv.FormatInt(codec, 10)
+					}
+					e.skip(memberName(name, column), FormatParquet, "column compressed with unsupported codec "+codecName)
+				}
+				continue
+			}
+			emit := func(value string) bool { return fn(column, value) }
+			if err := e.parquetChunk(data, meta, columns[column], emit); err != nil {
+				if errors.Is(err, ErrLimitExceeded) {
+					return 0, err
+				}
+				if !skippedColumns[column] {
+					skippedColumns[column] = true
+					e.skip(memberName(name, column), FormatParquet, err.Error())
+				}
+			}
+		}
+	}
+	return metadata.int(3), nil
+}
+
+// parquetMetadata decodes the FileMetaData in the footer
+func parquetMetadata(data []byte) (thriftStruct, error) {
+	if bytes.HasSuffix(data, []byte("PARE")) {
+		return nil, errors.New("encrypted Parquet file")
+	}
+	if len(data) < 12 || !bytes.HasSuffix(data, []byte("PAR1")) {
+		return nil, errors.New("invalid Parquet file: no footer")
+	}
+	length := int(binary.LittleEndian.Uint32(data[len(data)-8:]))
+	if length <= 0 || length > len(data)-12 {
+		return nil, errors.New("invalid Parquet file: bad footer length")
+	}
+	reader := thriftReader{data: data[len(data)-8-length : len(data)-8]}
+	metadata, err := reader.readStruct(0)
+	if err != nil {
+		return nil, fmt.Errorf("invalid Parquet metadata: %w", err)
+	}
+	return metadata, nil
+}
+
+// parquetSchema returns the levels of each leaf column by its dotted path.
+// The schema is a depth-first list of elements, groups giving their number of
+// children. Groups may nest up to parquetMaxNesting deep.
+func parquetSchema(elements []any) (map[string]parquetColumn, error) {
+	columns := map[string]parquetColumn{}
+	tooDeep := false
+	var walk func(i int, path []string, column parquetColumn) int
+	walk = func(i int, path []string, column parquetColumn) int {
+		if i >= len(elements) {
+			return i
+		}
+		if len(path) > parquetMaxNesting {
+			tooDeep = true
+			return len(elements)
+		}
+		element, _ := elements[i].(thriftStruct)
+		if i > 0 {
+			path = append(path, element.string(4))
+			switch element.int(3) {
+			case parquetRequired:
+			case parquetRepeated:
+				column.maxDefinition++
+				column.maxRepetition++
+			default:
+				column.maxDefinition++
+			}
+		}
+		children := int(element.int(5))
+		if children == 0 && i > 0 {
+			columns[strings.Join(path, ".")] = column
+			return i + 1
+		}
+		next := i + 1
+		for c := 0; c < children && next < len(elements); c++ {
+			next = walk(next, append([]string(nil), path...), column)
+		}
+		return next
+	}
+	walk(0, nil, parquetColumn{})
+	if tooDeep {
+		return nil, fmt.Errorf("invalid Parquet schema: groups nested more than %d deep", parquetMaxNesting)
+	}
+	return columns, nil
+}
+
+// parquetChunk passes the values of one column chunk to emit until it
+// returns false
+func (e *extractor) parquetChunk(data []byte, meta thriftStruct, levels parquetColumn, emit func(value string) bool) error {
+	start := meta.int(9)
+	if meta.has(11) && meta.int(11) > 0 && meta.int(11) < start {
+		start = meta.int(11)
+	}
+	// Compare sizes against what is left rather than adding them to offsets,
+	// which crafted values near the int64 limit would overflow
+	length := meta.int(7)
+	if start < 4 || start > int64(len(data)) || length <= 0 || length > int64(len(data))-start {
+		return errors.New("column chunk outside the file")
+	}
+	end := start + length
+
+	pos := start
+	for pos < end {
+		reader := thriftReader{data: data[pos:end]}
+		header, err := reader.readStruct(0)
+		if err != nil {
+			return fmt.Errorf("invalid page header: %w", err)
+		}
+		pos += int64(reader.pos)
+		size := header.int(3)
+		if size < 0 || size > end-pos {
+			return errors.New("page outside the column chunk")
+		}
+		page := data[pos : pos+size]
+		pos += size
+
+		var values []byte
+		switch header.int(1) {
+		case parquetDictionaryPage:
+			if values, err = e.decompressPage(meta.int(4), page, header.int(2)); err != nil {
+				return err
+			}
+		case parquetDataPage:
+			if header.structField(5).int(2) != parquetPlain {
+				// Dictionary indexes, whose values the dictionary page gave
+				continue
+			}
+			decompressed, err := e.decompressPage(meta.int(4), page, header.int(2))
+			if err != nil {
+				return err
+			}
+			if values, err = skipLevels(decompressed, levels); err != nil {
+				return err
+			}
+		case parquetDataPageV2:
+			v2 := header.structField(8)
+			if v2.int(4) != parquetPlain {
+				continue
+			}
+			// Levels come first and are never compressed
+			repetitionBytes, definitionBytes := v2.int(6), v2.int(5)
+			if repetitionBytes < 0 || definitionBytes < 0 || repetitionBytes > int64(len(page)) || definitionBytes > int64(len(page))-repetitionBytes {
+				return errors.New("invalid data page")
+			}
+			levelBytes := repetitionBytes + definitionBytes
+			values = page[levelBytes:]
+			if v2.bool(7, true) {
+				if values, err = e.decompressPage(meta.int(4), values, header.int(2)-levelBytes); err != nil {
+					return err
+				}
+			}
+		default:
+			continue
+		}
+		if !parquetPlainValues(meta.int(1), values, emit) {
+			return nil
+		}
+	}
+	return nil
+}
+
+// decompressPage decompresses a page, counting it against the limits
+func (e *extractor) decompressPage(codec int64, page []byte, size int64) ([]byte, error) {
+	if size < 0 {
+		return nil, errors.New("invalid page size")
+	}
+	if size > ratioGrace && size > int64(len(page))*e.opts.MaxRatio {
+		return nil, fmt.Errorf("%w: compression ratio above %d", ErrLimitExceeded, e.opts.MaxRatio)
+	}
+	if e.remaining -= size; e.remaining < 0 {
+		return nil, fmt.Errorf("%w: object expands to more than %d bytes", ErrLimitExceeded, e.opts.MaxBytes)
+	}
+
+	switch codec {
+	case parquetSnappy:
+		return decodeSnappy(page, size)
+	case parquetGzip:
+		gz, err := gzip.NewReader(bytes.NewReader(page))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return io.ReadAll(io.LimitReader(gz, size))
+	default:
+		return page, nil
+	}
+}
+
+// skipLevels returns the values of a version 1 data page, which follow the
+// repetition and definition levels, each prefixed with its length
+func skipLevels(page []byte, levels parquetColumn) ([]byte, error) {
+	for _, maxLevel := range []int{levels.maxRepetition, levels.maxDefinition} {
+		if maxLevel == 0 {
+			continue
+		}
+		if len(page) < 4 {
+			return nil, errors.New("invalid data page")
+		}
+		n := int64(binary.LittleEndian.Uint32(page))
+		if n > int64(len(page)-4) {
+			return nil, errors.New("invalid data page")
+		}
+		page = page[4+n:]
+	}
+	return page, nil
+}
+
+// parquetPlainValues passes plain-encoded values to emit, returning false
+// once emit does. Nulls are not stored, so the values run to the end of the
+// page.
+func parquetPlainValues(valueType int64, values []byte, emit func(value string) bool) bool {
+	for len(values) > 0 {
+		var value string
+		switch valueType {
+		case parquetByteArray:
+			if len(values) < 4 {
+				return true
+			}
+			n := int(binary.LittleEndian.Uint32(values))
+			if n > len(values)-4 {
+				return true
+			}
+			value = strings.ReplaceAll(string(values[4:4+n]), "\n", " ")
+			values = values[4+n:]
+		case parquetInt32:
+			if len(values) < 4 {
+				return true
+			}
+			value = strconv.FormatInt(int64(int32(binary.LittleEndian.Uint32(values))), 10)
+			values = values[4:]
+		case parquetInt64:
+			if len(values) < 8 {
+				return true
+			}
+			value = strconv.FormatInt(int64(binary.LittleEndian.Uint64(values)), 10)
+			values = values[8:]
+		default:
+			return true
+		}
+		if !emit(value) {
+			return false
+		}
+	}
+	return true
+}
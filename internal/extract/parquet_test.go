@@ -0,0 +1,243 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// Thrift compact protocol encoders, writing every field header in the long
+// form with its full ID
+
+func tField(id int16, fieldType byte, value []byte) []byte {
+	field := []byte{fieldType}
+	field = binary.AppendUvarint(field, uint64(int64(id)<<1^int64(id)>>63))
+	return append(field, value...)
+}
+
+func tInt(v int64) []byte {
+	return binary.AppendUvarint(nil, uint64(v<<1^v>>63))
+}
+
+func tBinary(s string) []byte {
+	return append(binary.AppendUvarint(nil, uint64(len(s))), s...)
+}
+
+func tStruct(fields ...[]byte) []byte {
+	return append(bytes.Join(fields, nil), thriftStop)
+}
+
+func tList(elemType byte, elems ...[]byte) []byte {
+	header := []byte{byte(len(elems))<<4 | elemType}
+	if len(elems) >= 15 {
+		header = binary.AppendUvarint([]byte{0xf0 | elemType}, uint64(len(elems)))
+	}
+	return append(header, bytes.Join(elems, nil)...)
+}
+
+// parquetPage encodes a page header followed by the page
+func parquetPage(pageType int64, uncompressed int, body []byte, header ...[]byte) []byte {
+	fields := append([][]byte{
+		tField(1, thriftI32, tInt(pageType)),
+		tField(2, thriftI32, tInt(int64(uncompressed))),
+		tField(3, thriftI32, tInt(int64(len(body)))),
+	}, header...)
+	return append(tStruct(fields...), body...)
+}
+
+func plainStrings(values ...string) []byte {
+	var b []byte
+	for _, v := range values {
+		b = binary.LittleEndian.AppendUint32(b, uint32(len(v)))
+		b = append(b, v...)
+	}
+	return b
+}
+
+// snappyLiteral encodes data as a single Snappy literal
+func snappyLiteral(data []byte) []byte {
+	b := binary.AppendUvarint(nil, uint64(len(data)))
+	b = append(b, byte(len(data)-1)<<2)
+	return append(b, data...)
+}
+
+type testColumn struct {
+	name       string
+	valueType  int64
+	repetition int64
+	codec      int64
+	pages      []byte
+	dictionary bool
+	// chunkLength overrides the length of the column chunk
+	chunkLength int64
+}
+
+// parquetFile lays out the pages of each column, one row group, and the footer
+func parquetFile(columns ...testColumn) []byte {
+	data := []byte("PAR1")
+	schema := [][]byte{tStruct(tField(4, thriftBinary, tBinary("schema")), tField(5, thriftI32, tInt(int64(len(columns)))))}
+	var chunks [][]byte
+	for _, c := range columns {
+		schema = append(schema, tStruct(
+			tField(1, thriftI32, tInt(c.valueType)),
+			tField(3, thriftI32, tInt(c.repetition)),
+			tField(4, thriftBinary, tBinary(c.name)),
+		))
+		offset := int64(len(data))
+		data = append(data, c.pages...)
+		length := int64(len(c.pages))
+		if c.chunkLength != 0 {
+			length = c.chunkLength
+		}
+		meta := [][]byte{
+			tField(1, thriftI32, tInt(c.valueType)),
+			tField(2, thriftList, tList(thriftI32, tInt(parquetPlain))),
+			tField(3, thriftList, tList(thriftBinary, tBinary(c.name))),
+			tField(4, thriftI32, tInt(c.codec)),
+			tField(7, thriftI64, tInt(length)),
+			tField(9, thriftI64, tInt(offset)),
+		}
+		if c.dictionary {
+			meta = append(meta, tField(11, thriftI64, tInt(offset)))
+		}
+		chunks = append(chunks, tStruct(tField(2, thriftI64, tInt(offset)), tField(3, thriftStructType, tStruct(meta...))))
+	}
+	footer := tStruct(
+		tField(1, thriftI32, tInt(1)),
+		tField(2, thriftList, tList(thriftStructType, schema...)),
+		tField(3, thriftI64, tInt(2)),
+		tField(4, thriftList, tList(thriftStructType, tStruct(tField(1, thriftList, tList(thriftStructType, chunks...))))),
+	)
+	data = append(data, footer...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(footer)))
+	return append(data, "PAR1"...)
+}
+
+func TestExtract_Parquet(t *testing.T) {
+	// An optional column: a v1 page with definition levels before the values
+	emails := plainStrings("jane@example.com", "john@example.com")
+	levels := []byte{0x02, 0x00, 0x00, 0x00, 0x04, 0x01}
+	emailPage := append(levels, emails...)
+	emailPages := parquetPage(parquetDataPage, len(emailPage), emailPage,
+		tField(5, thriftStructType, tStruct(tField(1, thriftI32, tInt(2)), tField(2, thriftI32, tInt(parquetPlain)))))
+
+	// A required Snappy column in a v2 page
+	ids := binary.LittleEndian.AppendUint64(binary.LittleEndian.AppendUint64(nil, 1001), 1002)
+	idPages := parquetPage(parquetDataPageV2, len(ids), snappyLiteral(ids),
+		tField(8, thriftStructType, tStruct(tField(1, thriftI32, tInt(2)), tField(4, thriftI32, tInt(parquetPlain)))))
+
+	// A dictionary-encoded column, whose values come from the dictionary page
+	names := plainStrings("Jane Doe", "John Doe")
+	namePages := append(
+		parquetPage(parquetDictionaryPage, len(names), gzipFile(t, names),
+			tField(7, thriftStructType, tStruct(tField(1, thriftI32, tInt(2))))),
+		parquetPage(parquetDataPage, 3, []byte{0x02, 0x03, 0x01},
+			tField(5, thriftStructType, tStruct(tField(1, thriftI32, tInt(2)), tField(2, thriftI32, tInt(8)))))...)
+
+	file := parquetFile(
+		testColumn{name: "email", valueType: parquetByteArray, repetition: 1, codec: parquetUncompressed, pages: emailPages},
+		testColumn{name: "id", valueType: parquetInt64, repetition: parquetRequired, codec: parquetSnappy, pages: idPages},
+		testColumn{name: "name", valueType: parquetByteArray, repetition: 1, codec: parquetGzip, pages: namePages, dictionary: true},
+		testColumn{name: "notes", valueType: parquetByteArray, repetition: 1, codec: 6, pages: parquetPage(parquetDataPage, 4, []byte("zstd"))},
+	)
+
+	docs, skipped := extractAll(t, "exports/part-0000.parquet", file, false, Options{})
+
+	assert.Equal(t, map[string]string{"": "email: jane@example.com\nemail: john@example.com\nid: 1001\nid: 1002\nname: Jane Doe\nname: John Doe\n"}, docs)
+	assert.Equal(t, []Skipped{{Name: "notes", Format: FormatParquet, Reason: "column compressed with unsupported codec Zstandard"}}, skipped)
+}
+
+func TestExtract_ParquetSkipped(t *testing.T) {
+	tests := []struct {
+		name   string
+		data   []byte
+		reason string
+	}{
+		{name: "Encrypted footer", data: []byte("PAR1\x00\x00\x00\x00PARE"), reason: "encrypted Parquet file"},
+		{name: "Bad footer length", data: []byte("PAR1\x00\x00\x00\x00\xff\x00\x00\x00PAR1"), reason: "invalid Parquet file: bad footer length"},
+		{name: "No readable columns", data: parquetFile(testColumn{name: "price", valueType: 5, pages: []byte{}}), reason: "no string or integer columns"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, skipped := extractAll(t, "data.parquet", tt.data, false, Options{})
+
+			assert.Empty(t, docs)
+			assert.Equal(t, []Skipped{{Format: FormatParquet, Reason: tt.reason}}, skipped)
+		})
+	}
+}
+
+// parquetFooter wraps an encoded FileMetaData in the start and end of a file
+func parquetFooter(footer []byte) []byte {
+	data := append([]byte("PAR1"), footer...)
+	data = binary.LittleEndian.AppendUint32(data, uint32(len(footer)))
+	return append(data, "PAR1"...)
+}
+
+func TestExtract_ParquetMalformed(t *testing.T) {
+	// A page header whose size overflows the offset it is added to
+	hugePage := append(tStruct(
+		tField(1, thriftI32, tInt(parquetDataPage)),
+		tField(2, thriftI32, tInt(math.MaxInt64-8)),
+		tField(3, thriftI32, tInt(math.MaxInt64-8)),
+	), "jane@example.com"...)
+
+	// A schema of groups each holding the next
+	var schema [][]byte
+	for i := 0; i < 100; i++ {
+		schema = append(schema, tStruct(tField(4, thriftBinary, tBinary("group")), tField(5, thriftI32, tInt(1))))
+	}
+
+	tests := []struct {
+		name     string
+		data     []byte
+		expected []Skipped
+	}{
+		{
+			name:     "Page size near the int64 limit",
+			data:     parquetFile(testColumn{name: "email", valueType: parquetByteArray, pages: hugePage}),
+			expected: []Skipped{{Name: "email", Format: FormatParquet, Reason: "page outside the column chunk"}},
+		},
+		{
+			name:     "Column chunk length near the int64 limit",
+			data:     parquetFile(testColumn{name: "email", valueType: parquetByteArray, pages: plainStrings("a"), chunkLength: math.MaxInt64 - 2}),
+			expected: []Skipped{{Name: "email", Format: FormatParquet, Reason: "column chunk outside the file"}},
+		},
+		{
+			name:     "Lists nested too deep",
+			data:     parquetFooter(append(bytes.Repeat([]byte{0x19}, 100), 0x09, thriftStop)),
+			expected: []Skipped{{Format: FormatParquet, Reason: "invalid Parquet metadata: invalid Thrift data"}},
+		},
+		{
+			name:     "Schema groups nested too deep",
+			data:     parquetFooter(tStruct(tField(2, thriftList, tList(thriftStructType, schema...)))),
+			expected: []Skipped{{Format: FormatParquet, Reason: "invalid Parquet schema: groups nested more than 64 deep"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, skipped := extractAll(t, "data.parquet", tt.data, false, Options{})
+
+			assert.Empty(t, docs)
+			assert.Equal(t, tt.expected, skipped)
+		})
+	}
+}
+
+func TestDecodeSnappy(t *testing.T) {
+	// A literal "abc" then a copy of nine bytes from three back
+	decoded, err := decodeSnappy([]byte{0x0c, 0x08, 'a', 'b', 'c', 0x15, 0x03}, 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "abcabcabcabc", string(decoded))
+
+	_, err = decodeSnappy([]byte{0x0c, 0x08, 'a', 'b', 'c', 0x15, 0x03}, 10)
+	assert.Error(t, err, "longer than the limit")
+
+	_, err = decodeSnappy([]byte{0x0c, 0x08, 'a', 'b', 'c', 0x15, 0x09}, 100)
+	assert.Error(t, err, "copy from before the start")
+}
@@ -0,0 +1,345 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var (
+	pdfStreamPattern  = regexp.MustCompile(`stream\r?\n`)
+	pdfFilterPattern  = regexp.MustCompile(`/Filter\s*(\[[^\]]*\]|/\w+)`)
+	pdfSkippedPattern = regexp.MustCompile(`/Subtype\s*/Image|/Type\s*/(?:XRef|ObjStm|Metadata|EmbeddedFile)|/Length[123]\b`)
+)
+
+// extractPDF yields the text shown by the content streams of a PDF, in the
+// order the streams appear in the file. Text drawn with fonts that have no
+// standard encoding, such as many CJK fonts, and scanned pages come out empty
+// or garbled, and encrypted PDFs are skipped.
+func (e *extractor) extractPDF(name string, data []byte) error {
+	if bytes.Contains(data, []byte("/Encrypt")) {
+		e.skip(name, FormatPDF, "encrypted PDF")
+		return nil
+	}
+
+	var text strings.Builder
+	for _, loc := range pdfStreamPattern.FindAllIndex(data, -1) {
+		start, end := loc[0], loc[1]
+		// Skip "endstream" and the keyword inside names or strings
+		if start >= 3 && string(data[start-3:start]) == "end" {
+			continue
+		}
+		dictStart := bytes.LastIndex(data[:start], []byte("obj"))
+		if dictStart < 0 {
+			continue
+		}
+		dict := data[dictStart:start]
+		length := bytes.Index(data[end:], []byte("endstream"))
+		if length < 0 {
+			continue
+		}
+		if pdfSkippedPattern.Match(dict) {
+			continue
+		}
+
+		content := data[end : end+length]
+		if m := pdfFilterPattern.FindSubmatch(dict); m != nil {
+			// Only Flate is decoded; images and fonts use the other filters
+			if filters := strings.Fields(strings.Trim(string(m[1]), "[]")); len(filters) != 1 || filters[0] != "/FlateDecode" {
+				continue
+			}
+			zr, err := zlib.NewReader(bytes.NewReader(content))
+			if err != nil {
+				continue
+			}
+			decoded, err := io.ReadAll(e.bound(zr, func() int64 { return int64(length) }))
+			zr.Close()
+			if errors.Is(err, ErrLimitExceeded) {
+				return err
+			}
+			// A stream cut short by a bad length still yields its text
+			if err != nil && len(decoded) == 0 {
+				continue
+			}
+			content = decoded
+		} else {
+			content = bytes.TrimRight(content, "\r\n")
+		}
+		if bytes.Contains(content, []byte("BT")) {
+			pdfText(&text, content)
+		}
+	}
+
+	if strings.TrimSpace(text.String()) == "" {
+		e.skip(name, FormatPDF, "no extractable text, such as a scanned document")
+		return nil
+	}
+	return e.emit(name, FormatPDF, strings.NewReader(text.String()))
+}
+
+// pdfText writes the strings a content stream shows, starting a new line
+// where the text moves to a new line
+func pdfText(w *strings.Builder, content []byte) {
+	var operands []any
+	newline := func() {
+		if w.Len() > 0 && !strings.HasSuffix(w.String(), "\n") {
+			w.WriteByte('\n')
+		}
+	}
+	show := func(operand any) {
+		switch v := operand.(type) {
+		case string:
+			w.WriteString(v)
+		case []any:
+			for _, item := range v {
+				switch item := item.(type) {
+				case string:
+					w.WriteString(item)
+				case float64:
+					// A large negative adjustment is a gap between words
+					if item < -200 {
+						w.WriteByte(' ')
+					}
+				}
+			}
+		}
+	}
+
+	lexer := pdfLexer{data: content}
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			break
+		}
+		op, isOp := token.(pdfOperator)
+		if !isOp {
+			operands = append(operands, token)
+			continue
+		}
+		switch op {
+		case "Tj", "TJ":
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "'", `"`:
+			newline()
+			if len(operands) > 0 {
+				show(operands[len(operands)-1])
+			}
+		case "T*", "ET", "Tm":
+			newline()
+		case "Td", "TD":
+			if len(operands) >= 2 {
+				if ty, ok := operands[len(operands)-1].(float64); ok && ty != 0 {
+					newline()
+				} else {
+					w.WriteByte(' ')
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	newline()
+}
+
+// pdfOperator is a content stream operator such as Tj
+type pdfOperator string
+
+// pdfMaxNesting caps how deeply arrays in a content stream may nest
+const pdfMaxNesting = 32
+
+// pdfLexer splits a content stream into operands, which are strings, numbers
+// and arrays of them, and operators. Names and dictionaries are dropped.
+type pdfLexer struct {
+	data []byte
+	pos  int
+	// depth is the number of arrays being read
+	depth int
+}
+
+func (l *pdfLexer) next() (any, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case isPDFSpace(c):
+			l.pos++
+		case c == '(':
+			return l.literalString(), true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.skipDictionary()
+		case c == '<':
+			return l.hexString(), true
+		case c == '[':
+			if l.depth >= pdfMaxNesting {
+				// Drop the bracket rather than recurse without bound
+				l.pos++
+				continue
+			}
+			return l.array(), true
+		case c == '/':
+			l.pos++
+			l.word()
+		case c == '+' || c == '-' || c == '.' || c >= '0' && c <= '9':
+			word := l.word()
+			if n, err := strconv.ParseFloat(word, 64); err == nil {
+				return n, true
+			}
+		case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+			l.pos++
+		default:
+			if word := l.word(); word != "" {
+				return pdfOperator(word), true
+			}
+			l.pos++
+		}
+	}
+	return nil, false
+}
+
+// array reads the items of an [array], which ends early at an operator
+func (l *pdfLexer) array() []any {
+	l.pos++
+	l.depth++
+	defer func() { l.depth-- }()
+	var items []any
+	for {
+		l.skipSpace()
+		if l.pos >= len(l.data) || l.data[l.pos] == ']' {
+			l.pos++
+			return items
+		}
+		item, ok := l.next()
+		if !ok {
+			return items
+		}
+		if _, isOp := item.(pdfOperator); isOp {
+			return items
+		}
+		items = append(items, item)
+	}
+}
+
+func (l *pdfLexer) skipSpace() {
+	for l.pos < len(l.data) && isPDFSpace(l.data[l.pos]) {
+		l.pos++
+	}
+}
+
+// word reads up to the next delimiter
+func (l *pdfLexer) word() string {
+	start := l.pos
+	for l.pos < len(l.data) && !isPDFSpace(l.data[l.pos]) && !strings.ContainsRune("()<>[]{}/%", rune(l.data[l.pos])) {
+		l.pos++
+	}
+	return string(l.data[start:l.pos])
+}
+
+func (l *pdfLexer) skipDictionary() {
+	depth := 0
+	for l.pos+1 < len(l.data) {
+		switch {
+		case l.data[l.pos] == '<' && l.data[l.pos+1] == '<':
+			depth++
+			l.pos += 2
+		case l.data[l.pos] == '>' && l.data[l.pos+1] == '>':
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+		default:
+			l.pos++
+		}
+	}
+	l.pos = len(l.data)
+}
+
+// literalString decodes a (string) with its escapes and balanced parentheses
+func (l *pdfLexer) literalString() string {
+	var s strings.Builder
+	depth := 0
+	for l.pos++; l.pos < len(l.data); l.pos++ {
+		c := l.data[l.pos]
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			if depth == 0 {
+				l.pos++
+				return s.String()
+			}
+			depth--
+		case '\\':
+			l.pos++
+			if l.pos >= len(l.data) {
+				return s.String()
+			}
+			c = l.data[l.pos]
+			switch c {
+			case 'n':
+				s.WriteByte('\n')
+			case 'r':
+				s.WriteByte('\r')
+			case 't':
+				s.WriteByte('\t')
+			case 'b', 'f':
+			case '\r', '\n':
+				// A line continuation
+			case '0', '1', '2', '3', '4', '5', '6', '7':
+				end := l.pos + 1
+				for end < len(l.data) && end < l.pos+3 && l.data[end] >= '0' && l.data[end] <= '7' {
+					end++
+				}
+				n, _ := strconv.ParseUint(string(l.data[l.pos:end]), 8, 8)
+				s.WriteRune(rune(n))
+				l.pos = end - 1
+			default:
+				s.WriteByte(c)
+			}
+			continue
+		}
+		s.WriteRune(rune(c))
+	}
+	return s.String()
+}
+
+// hexString decodes a <hex string>
+func (l *pdfLexer) hexString() string {
+	end := bytes.IndexByte(l.data[l.pos:], '>')
+	if end < 0 {
+		l.pos = len(l.data)
+		return ""
+	}
+	digits := strings.Map(func(r rune) rune {
+		if isPDFSpace(byte(r)) {
+			return -1
+		}
+		return r
+	}, string(l.data[l.pos+1:l.pos+end]))
+	l.pos += end + 1
+	if len(digits)%2 == 1 {
+		digits += "0"
+	}
+	decoded, err := hex.DecodeString(digits)
+	if err != nil {
+		return ""
+	}
+	var s strings.Builder
+	for _, b := range decoded {
+		s.WriteRune(rune(b))
+	}
+	return s.String()
+}
+
+func isPDFSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\r' || c == '\n' || c == '\f' || c == 0
+}
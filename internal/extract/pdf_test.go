@@ -0,0 +1,97 @@
+package extract
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// pdfFile builds a minimal PDF from stream objects, given as dictionary
+// entries and content
+func pdfFile(t *testing.T, streams ...[2]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	buf.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	for i, s := range streams {
+		fmt.Fprintf(&buf, "%d 0 obj\n<< %s /Length %d >>\nstream\n%s\nendstream\nendobj\n", i+3, s[0], len(s[1]), s[1])
+	}
+	buf.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return buf.Bytes()
+}
+
+func flate(t *testing.T, data string) string {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zlib.NewWriter(&buf)
+	_, err := zw.Write([]byte(data))
+	assert.NoError(t, err)
+	assert.NoError(t, zw.Close())
+	return buf.String()
+}
+
+func TestExtract_PDF(t *testing.T) {
+	pdf := pdfFile(t,
+		[2]string{"", "BT /F1 12 Tf 72 720 Td (Invoice for Jane Doe) Tj 0 -14 Td (Card: 4111 1111 1111 1111) Tj ET"},
+		[2]string{"/Subtype /Image /Width 1 /Height 1", "BT (not text) Tj ET"},
+		[2]string{"/Filter /FlateDecode", flate(t, "BT [(Email:)-250(jane@example.com)] TJ T* <4e6f7465205c29> Tj (Paid \\(in full\\)) ' ET")},
+		[2]string{"/Filter [/ASCII85Decode /FlateDecode]", "garbage"},
+	)
+
+	docs, skipped := extractAll(t, "invoice.pdf", pdf, false, Options{})
+
+	assert.Empty(t, skipped)
+	assert.Equal(t, map[string]string{"": "Invoice for Jane Doe\nCard: 4111 1111 1111 1111\nEmail: jane@example.com\nNote \\)\nPaid (in full)\n"}, docs)
+}
+
+func TestExtract_PDFSkipped(t *testing.T) {
+	tests := []struct {
+		name      string
+		pdf       []byte
+		truncated bool
+		reason    string
+	}{
+		{
+			name:   "Encrypted",
+			pdf:    append(pdfFile(t, [2]string{"", "BT (secret) Tj ET"}), []byte("<< /Encrypt 9 0 R >>")...),
+			reason: "encrypted PDF",
+		},
+		{
+			name:   "Scanned",
+			pdf:    pdfFile(t, [2]string{"/Subtype /Image /Filter /DCTDecode", "\xff\xd8\xff"}),
+			reason: "no extractable text, such as a scanned document",
+		},
+		{
+			name:      "Truncated",
+			pdf:       pdfFile(t, [2]string{"", "BT (text) Tj ET"}),
+			truncated: true,
+			reason:    "only the start of the object was read, which is not enough for this format",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			docs, skipped := extractAll(t, "scan.pdf", tt.pdf, tt.truncated, Options{})
+
+			assert.Empty(t, docs)
+			assert.Equal(t, []Skipped{{Format: FormatPDF, Reason: tt.reason}}, skipped)
+		})
+	}
+}
+
+func TestExtract_PDFMalformed(t *testing.T) {
+	// Arrays nested far deeper than any content stream needs, before a stream
+	// that is still read
+	nested := "BT " + strings.Repeat("[", 1024*1024) + "(hidden) Tj ET"
+	docs, skipped := extractAll(t, "deep.pdf", pdfFile(t, [2]string{"", nested}, [2]string{"", "BT (Jane Doe) Tj ET"}), false, Options{})
+	assert.Empty(t, skipped)
+	assert.Equal(t, map[string]string{"": "Jane Doe\n"}, docs)
+
+	// Streams without an end and a stream keyword with no object
+	docs, skipped = extractAll(t, "broken.pdf", []byte("%PDF-1.4\nstream\nBT (a) Tj ET\n1 0 obj << >> stream\nBT [(b"), false, Options{})
+	assert.Empty(t, docs)
+	assert.Equal(t, []Skipped{{Format: FormatPDF, Reason: "no extractable text, such as a scanned document"}}, skipped)
+}
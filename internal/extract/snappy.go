@@ -0,0 +1,82 @@
+package extract
+
+import (
+	"encoding/binary"
+	"errors"
+)
+
+var errSnappy = errors.New("invalid Snappy data")
+
+// decodeSnappy decompresses a Snappy block, the format Parquet compresses
+// pages with by default. maxLen caps the decoded length.
+func decodeSnappy(src []byte, maxLen int64) ([]byte, error) {
+	length, n := binary.Uvarint(src)
+	if n <= 0 {
+		return nil, errSnappy
+	}
+	if int64(length) > maxLen || length > uint64(len(src))*255 {
+		return nil, errSnappy
+	}
+	src = src[n:]
+	dst := make([]byte, 0, length)
+
+	for len(src) > 0 {
+		tag := src[0]
+		var literal, copyLen, offset int
+		switch tag & 0x03 {
+		case 0:
+			literal = int(tag >> 2)
+			src = src[1:]
+			if literal >= 60 {
+				extra := literal - 59
+				if len(src) < extra {
+					return nil, errSnappy
+				}
+				literal = 0
+				for i := extra - 1; i >= 0; i-- {
+					literal = literal<<8 | int(src[i])
+				}
+				src = src[extra:]
+			}
+			literal++
+			if literal > len(src) {
+				return nil, errSnappy
+			}
+			dst = append(dst, src[:literal]...)
+			src = src[literal:]
+			continue
+		case 1:
+			if len(src) < 2 {
+				return nil, errSnappy
+			}
+			copyLen = 4 + int(tag>>2)&0x07
+			offset = int(tag&0xe0)<<3 | int(src[1])
+			src = src[2:]
+		case 2:
+			if len(src) < 3 {
+				return nil, errSnappy
+			}
+			copyLen = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint16(src[1:]))
+			src = src[3:]
+		case 3:
+			if len(src) < 5 {
+				return nil, errSnappy
+			}
+			copyLen = 1 + int(tag>>2)
+			offset = int(binary.LittleEndian.Uint32(src[1:]))
+			src = src[5:]
+		}
+		if offset <= 0 || offset > len(dst) || uint64(len(dst)+copyLen) > length {
+			return nil, errSnappy
+		}
+		// Copies may overlap their own output, so go byte by byte
+		for i := 0; i < copyLen; i++ {
+			dst = append(dst, dst[len(dst)-offset])
+		}
+	}
+	if uint64(len(dst)) != length {
+		return nil, errSnappy
+	}
+	return dst, nil
+}
@@ -0,0 +1,224 @@
+package extract
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"regexp"
+	"strings"
+)
+
+// ErrNotTabular is returned by Columns for objects that hold no table
+var ErrNotTabular = errors.New("not a CSV, JSON or Parquet file")
+
+// jsonIndexPattern matches the array indexes in a JSON path
+var jsonIndexPattern = regexp.MustCompile(`\[\d+\]`)
+
+// Table describes what Columns read
+type Table struct {
+	// Format is the MIME type of the table, inside any gzip compression
+	Format string
+	// Rows is the number of rows read
+	Rows int
+	// Skipped lists the columns whose values could not be read, such as
+	// Parquet columns compressed with unsupported codecs
+	Skipped []Skipped
+}
+
+// Columns reads up to maxRows rows of a tabular object and calls fn with each
+// value and the name of its column. Tables are CSV and TSV files with a header
+// row, JSON Lines files or JSON arrays of records, and Parquet files, each
+// optionally gzip compressed. Empty cells and JSON nulls are passed as empty
+// values. Nested JSON fields are named by their path without array indexes,
+// as in address.city or orders.total. Parquet files are read column by
+// column, up to maxRows values of each. maxRows of zero reads every row.
+//
+// name and truncated are as for Extract. Objects in other formats return
+// ErrNotTabular.
+func Columns(name string, r io.Reader, truncated bool, opts Options, maxRows int, fn func(column, value string)) (Table, error) {
+	e := &extractor{key: name, opts: opts.withDefaults()}
+	e.remaining = e.opts.MaxBytes
+
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(512)
+	format := Detect(name, head)
+	if format == FormatGzip {
+		compressed := &counting{r: br}
+		gz, err := gzip.NewReader(compressed)
+		if err != nil {
+			return Table{Format: format}, fmt.Errorf("invalid gzip stream: %w", err)
+		}
+		defer gz.Close()
+		name = strings.TrimSuffix(name, path.Ext(name))
+		br = bufio.NewReader(tolerateTruncation(e.bound(gz, func() int64 { return compressed.n }), truncated))
+		head, _ = br.Peek(512)
+		format = Detect(name, head)
+	}
+
+	table := Table{Format: format}
+	var err error
+	switch format {
+	case FormatCSV:
+		table.Rows, err = csvColumns(name, tolerateTruncation(br, truncated), truncated, maxRows, fn)
+	case FormatJSON:
+		table.Rows, err = jsonColumns(tolerateTruncation(br, truncated), truncated, maxRows, fn)
+	case FormatParquet:
+		if truncated {
+			return table, errors.New("only the start of the object was read, which is not enough for this format")
+		}
+		var data []byte
+		if data, err = e.readAll(br); err != nil {
+			return table, err
+		}
+		var rows int64
+		rows, err = e.parquetColumns(name, data, maxRows, fn)
+		table.Rows = int(rows)
+	default:
+		return table, ErrNotTabular
+	}
+	table.Skipped = e.skipped
+	return table, err
+}
+
+// csvColumns reads the rows of a CSV file after its header. The last row of a
+// truncated file may be cut short, so it is dropped.
+func csvColumns(name string, r io.Reader, truncated bool, maxRows int, fn func(column, value string)) (int, error) {
+	br := bufio.NewReader(r)
+	reader := csv.NewReader(br)
+	reader.FieldsPerRecord = -1
+	reader.LazyQuotes = true
+	reader.ReuseRecord = true
+	if strings.EqualFold(path.Ext(name), ".tsv") {
+		reader.Comma = '\t'
+	} else if line, _ := br.Peek(4096); bytes.Count(line, []byte("\t")) > bytes.Count(line, []byte(",")) {
+		reader.Comma = '\t'
+	}
+
+	record, err := reader.Read()
+	if err == io.EOF {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, fmt.Errorf("invalid CSV header: %w", err)
+	}
+	header := make([]string, len(record))
+	for i, column := range record {
+		header[i] = strings.TrimSpace(strings.TrimPrefix(column, "\ufeff"))
+	}
+	columnName := func(i int) string {
+		if i < len(header) && header[i] != "" {
+			return header[i]
+		}
+		return fmt.Sprintf("column %d", i+1)
+	}
+
+	var pending []string
+	rows := 0
+	for maxRows <= 0 || rows < maxRows {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return rows, fmt.Errorf("invalid CSV: %w", err)
+		}
+		if truncated {
+			// Hold each row back until the next one shows it is complete
+			record, pending = pending, append(pending[:0:0], record...)
+			if record == nil {
+				continue
+			}
+		}
+		for i := 0; i < max(len(record), len(header)); i++ {
+			value := ""
+			if i < len(record) {
+				value = record[i]
+			}
+			fn(columnName(i), value)
+		}
+		rows++
+	}
+	return rows, nil
+}
+
+// jsonColumns reads the records of a JSON Lines file or a JSON array
+func jsonColumns(r io.Reader, truncated bool, maxRows int, fn func(column, value string)) (int, error) {
+	br := bufio.NewReader(r)
+	decoder := json.NewDecoder(br)
+	decoder.UseNumber()
+	// A document that is an array holds one record per element
+	inArray := false
+	if head, _ := br.Peek(512); bytes.HasPrefix(bytes.TrimLeft(head, " \t\r\n\ufeff"), []byte("[")) {
+		if _, err := decoder.Token(); err != nil {
+			return 0, ErrNotTabular
+		}
+		inArray = true
+	}
+
+	rows := 0
+	for maxRows <= 0 || rows < maxRows {
+		if inArray && !decoder.More() {
+			break
+		}
+		var values [][2]string
+		err := walkJSON(decoder, func(p string, value any) {
+			column := strings.TrimPrefix(jsonIndexPattern.ReplaceAllString(p, ""), ".")
+			if column == "" {
+				column = "value"
+			}
+			text := ""
+			if value != nil {
+				text = fmt.Sprint(value)
+			}
+			values = append(values, [2]string{column, text})
+		})
+		if err == io.EOF {
+			break
+		}
+		if errors.Is(err, ErrLimitExceeded) {
+			return rows, err
+		}
+		if err != nil && !truncated {
+			if rows == 0 {
+				return 0, ErrNotTabular
+			}
+			return rows, fmt.Errorf("invalid JSON: %w", err)
+		}
+		// The record a truncated object is cut in keeps the values before
+		// the cut
+		if len(values) > 0 {
+			for _, v := range values {
+				fn(v[0], v[1])
+			}
+			rows++
+		}
+		if err != nil {
+			break
+		}
+	}
+	return rows, nil
+}
+
+// parquetColumns reads up to maxRows values of each column of a Parquet file
+// and returns the number of rows read
+func (e *extractor) parquetColumns(name string, data []byte, maxRows int, fn func(column, value string)) (int64, error) {
+	counts := map[string]int{}
+	rows, err := e.parquetValues(name, data, func(column, value string) bool {
+		if maxRows > 0 && counts[column] >= maxRows {
+			return false
+		}
+		counts[column]++
+		fn(column, value)
+		return true
+	})
+	if maxRows > 0 && rows > int64(maxRows) {
+		rows = int64(maxRows)
+	}
+	return rows, err
+}
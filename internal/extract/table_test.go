@@ -0,0 +1,165 @@
+package extract
+
+import (
+	"bytes"
+	"encoding/binary"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// readColumns returns the values Columns passes for each column
+func readColumns(t *testing.T, name string, data []byte, truncated bool, maxRows int) (map[string][]string, Table, error) {
+	t.Helper()
+	columns := map[string][]string{}
+	table, err := Columns(name, bytes.NewReader(data), truncated, Options{}, maxRows, func(column, value string) {
+		columns[column] = append(columns[column], value)
+	})
+	return columns, table, err
+}
+
+func TestColumns(t *testing.T) {
+	ids := binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(binary.LittleEndian.AppendUint32(nil, 7), 8), 9)
+	parquet := parquetFile(
+		testColumn{name: "email", valueType: parquetByteArray, repetition: 1, pages: parquetPage(parquetDataPage, 42, append([]byte{0x02, 0x00, 0x00, 0x00, 0x04, 0x01}, plainStrings("jane@example.com", "john@example.com")...),
+			tField(5, thriftStructType, tStruct(tField(1, thriftI32, tInt(2)), tField(2, thriftI32, tInt(parquetPlain)))))},
+		testColumn{name: "id", valueType: parquetInt32, pages: parquetPage(parquetDataPage, len(ids), ids,
+			tField(5, thriftStructType, tStruct(tField(1, thriftI32, tInt(3)), tField(2, thriftI32, tInt(parquetPlain)))))},
+	)
+
+	tests := []struct {
+		name      string
+		fileName  string
+		data      []byte
+		truncated bool
+		maxRows   int
+		format    string
+		rows      int
+		columns   map[string][]string
+	}{
+		{
+			name:     "CSV with a header",
+			fileName: "customers.csv",
+			data:     []byte("\ufeffname, email ,\nJane Doe,jane@example.com,x\n\"Doe, John\",,y,extra\n"),
+			format:   FormatCSV,
+			rows:     2,
+			columns: map[string][]string{
+				"name":     {"Jane Doe", "Doe, John"},
+				"email":    {"jane@example.com", ""},
+				"column 3": {"x", "y"},
+				"column 4": {"extra"},
+			},
+		},
+		{
+			name:     "Delimited text without a table extension",
+			fileName: "export",
+			data:     []byte("id\tphone\n1\t555-010-0199\n"),
+			format:   FormatText,
+		},
+		{
+			name:     "Gzipped TSV",
+			fileName: "export.tsv.gz",
+			data:     gzipFile(t, []byte("id\tphone\n1\t555-010-0199\n2\t555-010-0142\n")),
+			format:   FormatCSV,
+			rows:     2,
+			columns:  map[string][]string{"id": {"1", "2"}, "phone": {"555-010-0199", "555-010-0142"}},
+		},
+		{
+			name:     "Row limit",
+			fileName: "ids.csv",
+			data:     []byte("id\n1\n2\n3\n"),
+			maxRows:  2,
+			format:   FormatCSV,
+			rows:     2,
+			columns:  map[string][]string{"id": {"1", "2"}},
+		},
+		{
+			name:      "Truncated CSV drops the row it is cut in",
+			fileName:  "ids.csv",
+			data:      []byte("id,email\n1,jane@example.com\n2,jo"),
+			truncated: true,
+			format:    FormatCSV,
+			rows:      1,
+			columns:   map[string][]string{"id": {"1"}, "email": {"jane@example.com"}},
+		},
+		{
+			name:     "JSON Lines",
+			fileName: "events.jsonl",
+			data:     []byte("{\"user\":{\"email\":\"a@example.com\"},\"tags\":[\"x\",\"y\"],\"ok\":true}\n{\"user\":{\"email\":null},\"n\":1.5}\n"),
+			format:   FormatJSON,
+			rows:     2,
+			columns: map[string][]string{
+				"user.email": {"a@example.com", ""},
+				"tags":       {"x", "y"},
+				"ok":         {"true"},
+				"n":          {"1.5"},
+			},
+		},
+		{
+			name:     "JSON array of records",
+			fileName: "customers.json",
+			data:     []byte(` [{"name":"Jane","cards":[{"number":"4111111111111111"}]}, {"name":"John"}, 42]`),
+			format:   FormatJSON,
+			rows:     3,
+			columns:  map[string][]string{"name": {"Jane", "John"}, "cards.number": {"4111111111111111"}, "value": {"42"}},
+		},
+		{
+			name:      "Truncated JSON keeps the values before the cut",
+			fileName:  "customers.json",
+			data:      []byte(`[{"name":"Jane"},{"name":"John","email":"jo`),
+			truncated: true,
+			format:    FormatJSON,
+			rows:      2,
+			columns:   map[string][]string{"name": {"Jane", "John"}},
+		},
+		{
+			name:     "Parquet",
+			fileName: "part-00000.parquet",
+			data:     parquet,
+			maxRows:  2,
+			format:   FormatParquet,
+			rows:     2,
+			columns:  map[string][]string{"email": {"jane@example.com", "john@example.com"}, "id": {"7", "8"}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, table, err := readColumns(t, tt.fileName, tt.data, tt.truncated, tt.maxRows)
+
+			assert.Equal(t, tt.format, table.Format)
+			if tt.columns == nil {
+				assert.ErrorIs(t, err, ErrNotTabular)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.rows, table.Rows)
+			assert.Equal(t, tt.columns, columns)
+		})
+	}
+}
+
+func TestColumns_NotTabular(t *testing.T) {
+	tests := []struct {
+		name     string
+		fileName string
+		data     []byte
+	}{
+		{name: "Text", fileName: "notes.txt", data: []byte("hello\n")},
+		{name: "Log that looks like JSON", fileName: "app.log", data: []byte("[INFO] started\n")},
+		{name: "PDF", fileName: "report.pdf", data: pdfFile(t, [2]string{"", "BT (x) Tj ET"})},
+		{name: "ZIP", fileName: "backup.zip", data: zipFile(t, map[string][]byte{"a.csv": []byte("a\n1\n")}, "a.csv")},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			columns, _, err := readColumns(t, tt.fileName, tt.data, false, 0)
+
+			assert.ErrorIs(t, err, ErrNotTabular)
+			assert.Empty(t, columns)
+		})
+	}
+
+	_, _, err := readColumns(t, "part-0.parquet", []byte("PAR1\x00\x00\x00\x00PAR1"), true, 0)
+	assert.EqualError(t, err, "only the start of the object was read, which is not enough for this format")
+}
@@ -0,0 +1,222 @@
+package extract
+
+import (
+	"encoding/binary"
+	"errors"
+	"math"
+)
+
+// Parquet metadata is encoded with the Thrift compact protocol. thriftReader
+// decodes it generically: structs become maps from field ID to value, lists
+// become slices, integers int64 and binary fields []byte.
+type thriftReader struct {
+	data []byte
+	pos  int
+}
+
+type thriftStruct map[int16]any
+
+// Thrift compact protocol types
+const (
+	thriftStop       = 0
+	thriftTrue       = 1
+	thriftFalse      = 2
+	thriftByte       = 3
+	thriftI16        = 4
+	thriftI32        = 5
+	thriftI64        = 6
+	thriftDouble     = 7
+	thriftBinary     = 8
+	thriftList       = 9
+	thriftSet        = 10
+	thriftMap        = 11
+	thriftStructType = 12
+)
+
+// thriftMaxDepth caps how deeply structs, lists, sets and maps may nest
+const thriftMaxDepth = 64
+
+var errThrift = errors.New("invalid Thrift data")
+
+func (t *thriftReader) readStruct(depth int) (thriftStruct, error) {
+	if depth > thriftMaxDepth {
+		return nil, errThrift
+	}
+	s := thriftStruct{}
+	var id int16
+	for {
+		header, err := t.readByte()
+		if err != nil {
+			return nil, err
+		}
+		fieldType := header & 0x0f
+		if fieldType == thriftStop {
+			return s, nil
+		}
+		if delta := header >> 4; delta != 0 {
+			id += int16(delta)
+		} else {
+			v, err := t.readVarint()
+			if err != nil {
+				return nil, err
+			}
+			id = int16(zigzag(v))
+		}
+		value, err := t.readValue(fieldType, depth)
+		if err != nil {
+			return nil, err
+		}
+		s[id] = value
+	}
+}
+
+func (t *thriftReader) readValue(fieldType byte, depth int) (any, error) {
+	if depth > thriftMaxDepth {
+		return nil, errThrift
+	}
+	switch fieldType {
+	case thriftTrue:
+		return true, nil
+	case thriftFalse:
+		return false, nil
+	case thriftByte:
+		b, err := t.readByte()
+		return int64(int8(b)), err
+	case thriftI16, thriftI32, thriftI64:
+		v, err := t.readVarint()
+		return zigzag(v), err
+	case thriftDouble:
+		if t.pos+8 > len(t.data) {
+			return nil, errThrift
+		}
+		v := math.Float64frombits(binary.LittleEndian.Uint64(t.data[t.pos:]))
+		t.pos += 8
+		return v, nil
+	case thriftBinary:
+		n, err := t.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if n > uint64(len(t.data)-t.pos) {
+			return nil, errThrift
+		}
+		b := t.data[t.pos : t.pos+int(n)]
+		t.pos += int(n)
+		return b, nil
+	case thriftList, thriftSet:
+		header, err := t.readByte()
+		if err != nil {
+			return nil, err
+		}
+		size := uint64(header >> 4)
+		if size == 15 {
+			if size, err = t.readVarint(); err != nil {
+				return nil, err
+			}
+		}
+		if size > uint64(len(t.data)-t.pos) {
+			return nil, errThrift
+		}
+		elemType := header & 0x0f
+		list := make([]any, 0, size)
+		for i := uint64(0); i < size; i++ {
+			var value any
+			if elemType == thriftTrue || elemType == thriftFalse {
+				// List elements carry booleans in a byte of their own
+				b, err := t.readByte()
+				if err != nil {
+					return nil, err
+				}
+				value = b == thriftTrue
+			} else if value, err = t.readValue(elemType, depth+1); err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		return list, nil
+	case thriftMap:
+		size, err := t.readVarint()
+		if err != nil {
+			return nil, err
+		}
+		if size == 0 {
+			return nil, nil
+		}
+		if size > uint64(len(t.data)-t.pos) {
+			return nil, errThrift
+		}
+		types, err := t.readByte()
+		if err != nil {
+			return nil, err
+		}
+		for i := uint64(0); i < size; i++ {
+			if _, err := t.readValue(types>>4, depth+1); err != nil {
+				return nil, err
+			}
+			if _, err := t.readValue(types&0x0f, depth+1); err != nil {
+				return nil, err
+			}
+		}
+		return nil, nil
+	case thriftStructType:
+		return t.readStruct(depth + 1)
+	default:
+		return nil, errThrift
+	}
+}
+
+func (t *thriftReader) readByte() (byte, error) {
+	if t.pos >= len(t.data) {
+		return 0, errThrift
+	}
+	b := t.data[t.pos]
+	t.pos++
+	return b, nil
+}
+
+func (t *thriftReader) readVarint() (uint64, error) {
+	v, n := binary.Uvarint(t.data[t.pos:])
+	if n <= 0 {
+		return 0, errThrift
+	}
+	t.pos += n
+	return v, nil
+}
+
+func zigzag(v uint64) int64 {
+	return int64(v>>1) ^ -int64(v&1)
+}
+
+// Accessors return the zero value when a field is missing or of another type
+
+func (s thriftStruct) int(id int16) int64 {
+	v, _ := s[id].(int64)
+	return v
+}
+
+func (s thriftStruct) has(id int16) bool {
+	_, ok := s[id]
+	return ok
+}
+
+func (s thriftStruct) bool(id int16, fallback bool) bool {
+	if v, ok := s[id].(bool); ok {
+		return v
+	}
+	return fallback
+}
+
+func (s thriftStruct) string(id int16) string {
+	v, _ := s[id].([]byte)
+	return string(v)
+}
+
+func (s thriftStruct) structField(id int16) thriftStruct {
+	v, _ := s[id].(thriftStruct)
+	return v
+}
+
+func (s thriftStruct) list(id int16) []any {
+	v, _ := s[id].([]any)
+	return v
+}
@@ -0,0 +1,120 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// bedrockClient calls the Bedrock Converse API, which accepts the same
+// request for every model Bedrock serves. Requests are signed with SigV4.
+type bedrockClient struct {
+	cfg Config
+}
+
+type bedrockContent struct {
+	Text string `json:"text"`
+}
+
+type bedrockMessage struct {
+	Role    string           `json:"role"`
+	Content []bedrockContent `json:"content"`
+}
+
+type bedrockRequest struct {
+	Messages        []bedrockMessage `json:"messages"`
+	System          []bedrockContent `json:"system,omitempty"`
+	InferenceConfig struct {
+		MaxTokens   int     `json:"maxTokens"`
+		Temperature float64 `json:"temperature"`
+	} `json:"inferenceConfig"`
+}
+
+type bedrockResponse struct {
+	Output struct {
+		Message bedrockMessage `json:"message"`
+	} `json:"output"`
+	Usage struct {
+		InputTokens  int `json:"inputTokens"`
+		OutputTokens int `json:"outputTokens"`
+	} `json:"usage"`
+}
+
+func (c *bedrockClient) Complete(ctx context.Context, prompt Prompt) (Completion, error) {
+	var request bedrockRequest
+	// Converse has no JSON mode, so the system prompt has to ask for JSON
+	if prompt.System != "" {
+		request.System = []bedrockContent{{Text: prompt.System}}
+	}
+	request.Messages = []bedrockMessage{{Role: "user", Content: []bedrockContent{{Text: prompt.User}}}}
+	request.InferenceConfig.MaxTokens = c.cfg.MaxOutputTokens
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return Completion{}, err
+	}
+	endpoint := fmt.Sprintf("%s/model/%s/converse", c.cfg.Endpoint, url.PathEscape(c.cfg.Model))
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, bytes.NewReader(body))
+	if err != nil {
+		return Completion{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if err := c.sign(ctx, req, body); err != nil {
+		return Completion{}, fmt.Errorf("unable to sign Bedrock request: %w", err)
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return Completion{}, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Completion{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Completion{}, statusError(resp, respBody)
+	}
+
+	var response bedrockResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return Completion{}, fmt.Errorf("unable to parse model response: %w", err)
+	}
+	var text strings.Builder
+	for _, content := range response.Output.Message.Content {
+		text.WriteString(content.Text)
+	}
+	if text.Len() == 0 {
+		return Completion{}, errors.New("model response has no text")
+	}
+	return Completion{
+		Text: text.String(),
+		Usage: Usage{
+			InputTokens:  response.Usage.InputTokens,
+			OutputTokens: response.Usage.OutputTokens,
+		},
+	}, nil
+}
+
+func (c *bedrockClient) sign(ctx context.Context, req *http.Request, body []byte) error {
+	if c.cfg.AWS.Credentials == nil {
+		return errors.New("no AWS credentials configured")
+	}
+	creds, err := c.cfg.AWS.Credentials.Retrieve(ctx)
+	if err != nil {
+		return err
+	}
+	hash := sha256.Sum256(body)
+	return v4.NewSigner().SignHTTP(ctx, creds, req, hex.EncodeToString(hash[:]), "bedrock", c.cfg.AWS.Region, time.Now())
+}
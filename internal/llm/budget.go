@@ -0,0 +1,73 @@
+package llm
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// ErrBudgetExhausted is returned once a request would exceed the budget
+var ErrBudgetExhausted = errors.New("LLM budget exhausted")
+
+// Budget caps the tokens and money a run spends on model requests. It is safe
+// for concurrent use, so buckets audited in parallel share one budget.
+type Budget struct {
+	// MaxTokens caps input and output tokens together; zero means no limit
+	MaxTokens int
+	// MaxCost caps the spend in USD; zero means no limit
+	MaxCost float64
+	// InputPrice and OutputPrice are the USD prices per million tokens
+	InputPrice  float64
+	OutputPrice float64
+
+	mu       sync.Mutex
+	used     Usage
+	reserved int
+}
+
+// Reserve claims room for a request that may use up to tokens input and
+// output tokens. Release the reservation with Record once the request is done.
+func (b *Budget) Reserve(tokens int) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.MaxTokens > 0 && b.used.InputTokens+b.used.OutputTokens+b.reserved+tokens > b.MaxTokens {
+		return fmt.Errorf("%w: %d of %d tokens used", ErrBudgetExhausted, b.used.InputTokens+b.used.OutputTokens, b.MaxTokens)
+	}
+	if b.MaxCost > 0 {
+		// Price reserved tokens at the higher of the two prices
+		cost := b.cost(b.used) + float64(b.reserved+tokens)*max(b.InputPrice, b.OutputPrice)/1e6
+		if cost > b.MaxCost {
+			return fmt.Errorf("%w: $%.4f of $%.2f spent", ErrBudgetExhausted, b.cost(b.used), b.MaxCost)
+		}
+	}
+	b.reserved += tokens
+	return nil
+}
+
+// Record releases a reservation and counts the tokens the request used
+func (b *Budget) Record(reserved int, usage Usage) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.reserved -= reserved
+	b.used.InputTokens += usage.InputTokens
+	b.used.OutputTokens += usage.OutputTokens
+}
+
+// Used returns the tokens used so far and what they cost
+func (b *Budget) Used() (Usage, float64) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.used, b.cost(b.used)
+}
+
+func (b *Budget) cost(usage Usage) float64 {
+	return (float64(usage.InputTokens)*b.InputPrice + float64(usage.OutputTokens)*b.OutputPrice) / 1e6
+}
+
+// EstimateTokens approximates the tokens in text at four characters a token,
+// which is close for English text and code across common tokenizers
+func EstimateTokens(text string) int {
+	return len(text)/4 + 1
+}
@@ -0,0 +1,125 @@
+// Package llm sends prompts to large language models served over HTTP, either
+// by an OpenAI-compatible endpoint such as a local llama.cpp, Ollama or vLLM
+// server, or by Amazon Bedrock.
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+)
+
+// API styles a Client can speak
+const (
+	APIStyleOpenAI  = "openai"
+	APIStyleBedrock = "bedrock"
+)
+
+// defaultMaxOutputTokens caps the length of each response unless configured
+const defaultMaxOutputTokens = 1024
+
+// Client sends a prompt to a model and returns its response
+type Client interface {
+	Complete(ctx context.Context, prompt Prompt) (Completion, error)
+}
+
+// Prompt is a single request to the model
+type Prompt struct {
+	System string
+	User   string
+	// JSON asks the model for a JSON object, where the API supports it
+	JSON bool
+}
+
+// Completion is the model's response
+type Completion struct {
+	Text  string
+	Usage Usage
+}
+
+// Usage counts the tokens a request used
+type Usage struct {
+	InputTokens  int
+	OutputTokens int
+}
+
+// Config selects the endpoint and model
+type Config struct {
+	// APIStyle is openai or bedrock
+	APIStyle string
+	// Endpoint is the base URL, e.g. http://localhost:11434/v1 for OpenAI
+	// style or https://bedrock-runtime.us-east-1.amazonaws.com for Bedrock
+	Endpoint string
+	Model    string
+	// APIKey is sent as a bearer token to OpenAI-compatible endpoints
+	APIKey string
+	// MaxOutputTokens caps the length of each response
+	MaxOutputTokens int
+	// AWS provides the credentials and region Bedrock requests are signed with
+	AWS aws.Config
+	// HTTPClient defaults to a client with a two minute timeout
+	HTTPClient *http.Client
+}
+
+// NewClient returns a client for the configured API style
+func NewClient(cfg Config) (Client, error) {
+	if cfg.Model == "" {
+		return nil, errors.New("no model configured")
+	}
+	if cfg.MaxOutputTokens <= 0 {
+		cfg.MaxOutputTokens = defaultMaxOutputTokens
+	}
+	if cfg.HTTPClient == nil {
+		cfg.HTTPClient = &http.Client{Timeout: 2 * time.Minute}
+	}
+	cfg.Endpoint = strings.TrimSuffix(cfg.Endpoint, "/")
+
+	switch cfg.APIStyle {
+	case APIStyleOpenAI, "":
+		if cfg.Endpoint == "" {
+			return nil, errors.New("no endpoint configured")
+		}
+		return &openAIClient{cfg: cfg}, nil
+	case APIStyleBedrock:
+		// Requests are signed for the region, even at a custom endpoint
+		if cfg.AWS.Region == "" {
+			return nil, errors.New("no AWS region configured")
+		}
+		if cfg.Endpoint == "" {
+			cfg.Endpoint = fmt.Sprintf("https://bedrock-runtime.%s.amazonaws.com", cfg.AWS.Region)
+		}
+		return &bedrockClient{cfg: cfg}, nil
+	default:
+		return nil, fmt.Errorf("unknown API style %q (valid styles: %s, %s)", cfg.APIStyle, APIStyleOpenAI, APIStyleBedrock)
+	}
+}
+
+// ExtractJSON returns the JSON object in a response, ignoring any text or
+// Markdown code fence a model wraps it in
+func ExtractJSON(text string) (json.RawMessage, error) {
+	start := strings.Index(text, "{")
+	end := strings.LastIndex(text, "}")
+	if start < 0 || end < start {
+		return nil, errors.New("response contains no JSON object")
+	}
+	raw := json.RawMessage(text[start : end+1])
+	if !json.Valid(raw) {
+		return nil, errors.New("response contains invalid JSON")
+	}
+	return raw, nil
+}
+
+// statusError describes a failed HTTP response
+func statusError(resp *http.Response, body []byte) error {
+	message := strings.TrimSpace(string(body))
+	if len(message) > 200 {
+		message = message[:200] + "..."
+	}
+	return fmt.Errorf("model endpoint returned %s: %s", resp.Status, message)
+}
@@ -0,0 +1,185 @@
+package llm
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOpenAIClient_Complete(t *testing.T) {
+	var request openAIRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/v1/chat/completions", r.URL.Path)
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"choices":[{"message":{"role":"assistant","content":"{\"detections\":[]}"}}],"usage":{"prompt_tokens":120,"completion_tokens":8}}`))
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Endpoint: server.URL + "/v1/", Model: "llama3.1", APIKey: "secret"})
+	assert.NoError(t, err)
+	completion, err := client.Complete(context.Background(), Prompt{System: "classify", User: "1: hello", JSON: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"detections":[]}`, completion.Text)
+	assert.Equal(t, Usage{InputTokens: 120, OutputTokens: 8}, completion.Usage)
+	assert.Equal(t, "llama3.1", request.Model)
+	assert.Equal(t, defaultMaxOutputTokens, request.MaxTokens)
+	assert.Equal(t, []openAIMessage{{Role: "system", Content: "classify"}, {Role: "user", Content: "1: hello"}}, request.Messages)
+	if assert.NotNil(t, request.ResponseFormat) {
+		assert.Equal(t, "json_object", request.ResponseFormat.Type)
+	}
+}
+
+func TestOpenAIClient_Error(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, `{"error":"model not found"}`, http.StatusNotFound)
+	}))
+	defer server.Close()
+
+	client, err := NewClient(Config{Endpoint: server.URL, Model: "missing"})
+	assert.NoError(t, err)
+	_, err = client.Complete(context.Background(), Prompt{User: "hello"})
+
+	assert.ErrorContains(t, err, "404")
+	assert.ErrorContains(t, err, "model not found")
+}
+
+func TestBedrockClient_Complete(t *testing.T) {
+	var request bedrockRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "/model/anthropic.claude-3-haiku-20240307-v1:0/converse", r.URL.Path)
+		assert.True(t, strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/"))
+		assert.Contains(t, r.Header.Get("Authorization"), "/eu-west-1/bedrock/aws4_request")
+		assert.NoError(t, json.NewDecoder(r.Body).Decode(&request))
+		w.Write([]byte(`{"output":{"message":{"role":"assistant","content":[{"text":"{\"detections\":"},{"text":"[]}"}]}},"usage":{"inputTokens":90,"outputTokens":6}}`))
+	}))
+	defer server.Close()
+
+	cfg := aws.Config{
+		Region: "eu-west-1",
+		Credentials: aws.CredentialsProviderFunc(func(context.Context) (aws.Credentials, error) {
+			return aws.Credentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "secret"}, nil
+		}),
+	}
+	client, err := NewClient(Config{APIStyle: APIStyleBedrock, Endpoint: server.URL, Model: "anthropic.claude-3-haiku-20240307-v1:0", MaxOutputTokens: 256, AWS: cfg})
+	assert.NoError(t, err)
+	completion, err := client.Complete(context.Background(), Prompt{System: "classify", User: "1: hello", JSON: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, `{"detections":[]}`, completion.Text)
+	assert.Equal(t, Usage{InputTokens: 90, OutputTokens: 6}, completion.Usage)
+	assert.Equal(t, []bedrockContent{{Text: "classify"}}, request.System)
+	assert.Equal(t, 256, request.InferenceConfig.MaxTokens)
+}
+
+func TestNewClient(t *testing.T) {
+	tests := []struct {
+		name          string
+		cfg           Config
+		expectedError string
+	}{
+		{
+			name:          "Model is required",
+			cfg:           Config{Endpoint: "http://localhost:11434/v1"},
+			expectedError: "no model configured",
+		},
+		{
+			name:          "OpenAI style needs an endpoint",
+			cfg:           Config{Model: "llama3.1"},
+			expectedError: "no endpoint configured",
+		},
+		{
+			name: "Bedrock style defaults to the regional endpoint",
+			cfg:  Config{APIStyle: APIStyleBedrock, Model: "model", AWS: aws.Config{Region: "us-east-1"}},
+		},
+		{
+			name:          "Bedrock style needs a region to sign for, even with an endpoint",
+			cfg:           Config{APIStyle: APIStyleBedrock, Endpoint: "https://vpce.example.com", Model: "model"},
+			expectedError: "no AWS region configured",
+		},
+		{
+			name:          "Unknown API style",
+			cfg:           Config{APIStyle: "grpc", Endpoint: "http://localhost", Model: "model"},
+			expectedError: `unknown API style "grpc"`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewClient(tt.cfg)
+			if tt.expectedError != "" {
+				assert.ErrorContains(t, err, tt.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "https://bedrock-runtime.us-east-1.amazonaws.com", client.(*bedrockClient).cfg.Endpoint)
+		})
+	}
+}
+
+func TestExtractJSON(t *testing.T) {
+	tests := []struct {
+		name          string
+		text          string
+		expected      string
+		expectedError bool
+	}{
+		{name: "Bare object", text: `{"detections":[]}`, expected: `{"detections":[]}`},
+		{name: "Code fence", text: "```json\n{\"detections\":[]}\n```", expected: `{"detections":[]}`},
+		{name: "Surrounding prose", text: `Here is the result: {"a":{"b":1}} Hope this helps.`, expected: `{"a":{"b":1}}`},
+		{name: "No object", text: "I cannot help with that.", expectedError: true},
+		{name: "Truncated object", text: `{"detections":[{"category":`, expectedError: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			raw, err := ExtractJSON(tt.text)
+			if tt.expectedError {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tt.expected, string(raw))
+		})
+	}
+}
+
+func TestBudget(t *testing.T) {
+	t.Run("Token limit counts reservations", func(t *testing.T) {
+		budget := &Budget{MaxTokens: 1000}
+		assert.NoError(t, budget.Reserve(600))
+		assert.True(t, errors.Is(budget.Reserve(600), ErrBudgetExhausted))
+
+		budget.Record(600, Usage{InputTokens: 300, OutputTokens: 50})
+		assert.NoError(t, budget.Reserve(600))
+		budget.Record(600, Usage{InputTokens: 500, OutputTokens: 100})
+		assert.True(t, errors.Is(budget.Reserve(100), ErrBudgetExhausted))
+
+		usage, _ := budget.Used()
+		assert.Equal(t, Usage{InputTokens: 800, OutputTokens: 150}, usage)
+	})
+
+	t.Run("Cost limit", func(t *testing.T) {
+		budget := &Budget{MaxCost: 0.01, InputPrice: 3, OutputPrice: 15}
+		assert.NoError(t, budget.Reserve(500))
+		budget.Record(500, Usage{InputTokens: 1000, OutputTokens: 200})
+
+		_, cost := budget.Used()
+		assert.InDelta(t, 0.006, cost, 1e-9)
+		assert.True(t, errors.Is(budget.Reserve(500), ErrBudgetExhausted))
+		assert.NoError(t, budget.Reserve(200))
+	})
+
+	t.Run("Zero means no limit", func(t *testing.T) {
+		budget := &Budget{}
+		assert.NoError(t, budget.Reserve(1e9))
+	})
+}
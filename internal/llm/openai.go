@@ -0,0 +1,100 @@
+package llm
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// openAIClient calls the chat completions API implemented by OpenAI and by
+// local servers such as llama.cpp, Ollama and vLLM
+type openAIClient struct {
+	cfg Config
+}
+
+type openAIMessage struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+type openAIRequest struct {
+	Model          string          `json:"model"`
+	Messages       []openAIMessage `json:"messages"`
+	MaxTokens      int             `json:"max_tokens"`
+	Temperature    float64         `json:"temperature"`
+	ResponseFormat *struct {
+		Type string `json:"type"`
+	} `json:"response_format,omitempty"`
+}
+
+type openAIResponse struct {
+	Choices []struct {
+		Message openAIMessage `json:"message"`
+	} `json:"choices"`
+	Usage struct {
+		PromptTokens     int `json:"prompt_tokens"`
+		CompletionTokens int `json:"completion_tokens"`
+	} `json:"usage"`
+}
+
+func (c *openAIClient) Complete(ctx context.Context, prompt Prompt) (Completion, error) {
+	request := openAIRequest{
+		Model:       c.cfg.Model,
+		MaxTokens:   c.cfg.MaxOutputTokens,
+		Temperature: 0,
+	}
+	if prompt.System != "" {
+		request.Messages = append(request.Messages, openAIMessage{Role: "system", Content: prompt.System})
+	}
+	request.Messages = append(request.Messages, openAIMessage{Role: "user", Content: prompt.User})
+	if prompt.JSON {
+		request.ResponseFormat = &struct {
+			Type string `json:"type"`
+		}{Type: "json_object"}
+	}
+
+	body, err := json.Marshal(request)
+	if err != nil {
+		return Completion{}, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.cfg.Endpoint+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return Completion{}, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if c.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+c.cfg.APIKey)
+	}
+
+	resp, err := c.cfg.HTTPClient.Do(req)
+	if err != nil {
+		return Completion{}, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return Completion{}, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return Completion{}, statusError(resp, respBody)
+	}
+
+	var response openAIResponse
+	if err := json.Unmarshal(respBody, &response); err != nil {
+		return Completion{}, fmt.Errorf("unable to parse model response: %w", err)
+	}
+	if len(response.Choices) == 0 {
+		return Completion{}, errors.New("model response has no choices")
+	}
+	return Completion{
+		Text: response.Choices[0].Message.Content,
+		Usage: Usage{
+			InputTokens:  response.Usage.PromptTokens,
+			OutputTokens: response.Usage.CompletionTokens,
+		},
+	}, nil
+}
@@ -3,16 +3,59 @@ package models
 import "time"
 
 type BucketBasicInfo struct {
-	Name   string
-	Region string
+	Name   string `json:"name"`
+	Region string `json:"region"`
 }
 
 type BucketInfo struct {
-	Name             string
-	Region           string
-	IsPublic         bool
-	Encryption       string
-	VersioningStatus string
-	SensitiveData    bool
-	AuditDuration    time.Duration
+	Name              string                   `json:"name"`
+	Region            string                   `json:"region"`
+	IsPublic          bool                     `json:"isPublic"`
+	PublicAccessBlock *PublicAccessBlockStatus `json:"publicAccessBlock,omitempty"`
+	Ownership         *OwnershipStatus         `json:"ownership,omitempty"`
+	PolicyAccess      string                   `json:"policyAccess,omitempty"`
+	TLS               *TLSStatus               `json:"tls,omitempty"`
+	Website           *WebsiteStatus           `json:"website,omitempty"`
+	CORS              *CORSStatus              `json:"cors,omitempty"`
+	Encryption        string                   `json:"encryption,omitempty"`
+	EncryptionDetails *EncryptionStatus        `json:"encryptionDetails,omitempty"`
+	VersioningStatus  string                   `json:"versioningStatus,omitempty"`
+	VersioningDetails *VersioningStatus        `json:"versioningDetails,omitempty"`
+	Lifecycle         *LifecycleStatus         `json:"lifecycle,omitempty"`
+	Replication       *ReplicationStatus       `json:"replication,omitempty"`
+	Logging           *LoggingStatus           `json:"logging,omitempty"`
+	ObjectSample      *ObjectSample            `json:"objectSample,omitempty"`
+	// Secrets is set when object contents were scanned for secrets
+	Secrets       *SecretScan `json:"secrets,omitempty"`
+	SensitiveData bool        `json:"sensitiveData"`
+	// SensitiveDataDetails breaks down what Macie found, per object
+	SensitiveDataDetails *SensitiveDataSummary `json:"sensitiveDataDetails,omitempty"`
+	AuditDuration        time.Duration         `json:"auditDuration"`
+	// Checks lists the checks that were run; empty means all of them
+	Checks   []string  `json:"checks,omitempty"`
+	Findings []Finding `json:"findings,omitempty"`
+}
+
+// MaxSeverity returns the severity of the most serious finding
+func (b BucketInfo) MaxSeverity() Severity {
+	highest := SeverityNone
+	for _, f := range b.Findings {
+		if f.Severity > highest {
+			highest = f.Severity
+		}
+	}
+	return highest
+}
+
+// Ran reports whether the named check was part of this audit
+func (b BucketInfo) Ran(check string) bool {
+	if len(b.Checks) == 0 {
+		return true
+	}
+	for _, c := range b.Checks {
+		if c == check {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CORSStatus is the cross-origin resource sharing configuration of a bucket
+type CORSStatus struct {
+	Configured bool       `json:"configured"`
+	Rules      []CORSRule `json:"rules,omitempty"`
+}
+
+// CORSRule is a single CORS rule
+type CORSRule struct {
+	ID             string   `json:"id,omitempty"`
+	AllowedOrigins []string `json:"allowedOrigins"`
+	AllowedMethods []string `json:"allowedMethods"`
+	AllowedHeaders []string `json:"allowedHeaders,omitempty"`
+	ExposeHeaders  []string `json:"exposeHeaders,omitempty"`
+	MaxAgeSeconds  int32    `json:"maxAgeSeconds,omitempty"`
+}
+
+// corsWriteMethods are the methods that let a browser change bucket contents
+var corsWriteMethods = map[string]bool{
+	"PUT":    true,
+	"POST":   true,
+	"DELETE": true,
+}
+
+// AllowsAnyOrigin reports whether the rule allows requests from every origin
+func (r CORSRule) AllowsAnyOrigin() bool {
+	for _, origin := range r.AllowedOrigins {
+		if origin == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+// WriteMethods lists the allowed methods that modify objects
+func (r CORSRule) WriteMethods() []string {
+	var methods []string
+	for _, method := range r.AllowedMethods {
+		if corsWriteMethods[strings.ToUpper(method)] {
+			methods = append(methods, method)
+		}
+	}
+	return methods
+}
+
+// Summary describes the rule in one line, e.g.
+// "* -> GET, PUT (headers: *; exposes: ETag)"
+func (r CORSRule) Summary() string {
+	summary := fmt.Sprintf("%s -> %s", strings.Join(r.AllowedOrigins, ", "), strings.Join(r.AllowedMethods, ", "))
+	if r.ID != "" {
+		summary = r.ID + ": " + summary
+	}
+
+	var details []string
+	if len(r.AllowedHeaders) > 0 {
+		details = append(details, "headers: "+strings.Join(r.AllowedHeaders, ", "))
+	}
+	if len(r.ExposeHeaders) > 0 {
+		details = append(details, "exposes: "+strings.Join(r.ExposeHeaders, ", "))
+	}
+	if len(details) > 0 {
+		summary += " (" + strings.Join(details, "; ") + ")"
+	}
+	return summary
+}
@@ -0,0 +1,34 @@
+package models
+
+// EncryptionStatus describes a bucket's default encryption configuration
+type EncryptionStatus struct {
+	Configured bool             `json:"configured"`
+	Rules      []EncryptionRule `json:"rules,omitempty"`
+	// DeniesUnencryptedUploads is nil when the bucket policy could not be read
+	DeniesUnencryptedUploads *bool `json:"deniesUnencryptedUploads,omitempty"`
+}
+
+// EncryptionRule is one server-side encryption rule of a bucket
+type EncryptionRule struct {
+	Algorithm        string `json:"algorithm"`
+	KMSKeyID         string `json:"kmsKeyId,omitempty"`
+	KeyManager       string `json:"keyManager,omitempty"`
+	BucketKeyEnabled bool   `json:"bucketKeyEnabled"`
+	// DualLayer is set for DSSE-KMS, which does not support S3 Bucket Keys
+	DualLayer bool `json:"dualLayer,omitempty"`
+}
+
+// Key managers reported for KMS keys
+const (
+	KeyManagerAWS      = "AWS"
+	KeyManagerCustomer = "CUSTOMER"
+	KeyManagerUnknown  = "UNKNOWN"
+)
+
+// Summary returns the default algorithm, or "Not Enabled"
+func (e EncryptionStatus) Summary() string {
+	if !e.Configured || len(e.Rules) == 0 {
+		return "Not Enabled"
+	}
+	return e.Rules[0].Algorithm
+}
@@ -0,0 +1,23 @@
+package models
+
+import "fmt"
+
+// Finding is a single issue raised by an audit check
+type Finding struct {
+	CheckID     string   `json:"checkId"`
+	Severity    Severity `json:"severity"`
+	Title       string   `json:"title"`
+	Resource    string   `json:"resource"`
+	Evidence    []string `json:"evidence,omitempty"`
+	Remediation string   `json:"remediation,omitempty"`
+}
+
+// BucketARN returns the ARN of an S3 bucket
+func BucketARN(bucketName string) string {
+	return fmt.Sprintf("arn:aws:s3:::%s", bucketName)
+}
+
+// ObjectARN returns the ARN of an object in an S3 bucket
+func ObjectARN(bucketName, key string) string {
+	return fmt.Sprintf("arn:aws:s3:::%s/%s", bucketName, key)
+}
@@ -0,0 +1,58 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+// Sources an inventory can be built from
+const (
+	InventorySourceListing    = "listing"
+	InventorySourceCloudWatch = "cloudwatch"
+)
+
+// BucketInventory describes what is stored in a bucket
+type BucketInventory struct {
+	Bucket      string `json:"bucket"`
+	Region      string `json:"region,omitempty"`
+	Source      string `json:"source"`
+	ObjectCount int64  `json:"objectCount"`
+	TotalSize   int64  `json:"totalSize"`
+	// StorageClasses breaks the contents down by storage class; CloudWatch
+	// only reports sizes, so Objects is zero for that source
+	StorageClasses map[string]StorageClassUsage `json:"storageClasses,omitempty"`
+	// LargestObjects, Oldest and Newest are only known from a listing
+	LargestObjects []ObjectSummary `json:"largestObjects,omitempty"`
+	Oldest         *time.Time      `json:"oldest,omitempty"`
+	Newest         *time.Time      `json:"newest,omitempty"`
+	// Truncated is true when the listing stopped at the object limit
+	Truncated bool `json:"truncated,omitempty"`
+}
+
+// StorageClassUsage is the amount of data in one storage class
+type StorageClassUsage struct {
+	Objects int64 `json:"objects,omitempty"`
+	Size    int64 `json:"size"`
+}
+
+// ObjectSummary identifies an object and its size
+type ObjectSummary struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	StorageClass string    `json:"storageClass,omitempty"`
+	LastModified time.Time `json:"lastModified"`
+}
+
+// FormatSize renders a byte count in binary units, e.g. "1.5 GiB"
+func FormatSize(bytes int64) string {
+	const unit = 1024
+	if bytes < unit {
+		return fmt.Sprintf("%d B", bytes)
+	}
+	div, exp := int64(unit), 0
+	for n := bytes / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(bytes)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,170 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// LifecycleStatus is the lifecycle configuration of a bucket
+type LifecycleStatus struct {
+	Configured bool            `json:"configured"`
+	Rules      []LifecycleRule `json:"rules,omitempty"`
+}
+
+// LifecycleRule is a single lifecycle rule; day counts are zero when the
+// rule does not set the corresponding action
+type LifecycleRule struct {
+	ID      string `json:"id,omitempty"`
+	Enabled bool   `json:"enabled"`
+	// Scope describes the filter of the rule; empty applies to every object
+	Scope          string `json:"scope,omitempty"`
+	ExpirationDays int32  `json:"expirationDays,omitempty"`
+	// ExpirationDate is set instead of ExpirationDays for rules that expire
+	// objects on a date, as YYYY-MM-DD
+	ExpirationDate string `json:"expirationDate,omitempty"`
+	// ExpiresDeleteMarkers is set when the rule removes expired object
+	// delete markers
+	ExpiresDeleteMarkers      bool                  `json:"expiresDeleteMarkers,omitempty"`
+	NoncurrentExpirationDays  int32                 `json:"noncurrentExpirationDays,omitempty"`
+	AbortIncompleteUploadDays int32                 `json:"abortIncompleteUploadDays,omitempty"`
+	Transitions               []LifecycleTransition `json:"transitions,omitempty"`
+	NoncurrentTransitions     []LifecycleTransition `json:"noncurrentTransitions,omitempty"`
+}
+
+// LifecycleTransition moves objects to another storage class after Days, or
+// on Date
+type LifecycleTransition struct {
+	StorageClass string `json:"storageClass"`
+	Days         int32  `json:"days"`
+	// Date is set instead of Days for transitions on a date, as YYYY-MM-DD
+	Date string `json:"date,omitempty"`
+}
+
+// when describes when the transition happens, e.g. "after 30 day(s)"
+func (t LifecycleTransition) when() string {
+	if t.Date != "" {
+		return "on " + t.Date
+	}
+	return fmt.Sprintf("after %d day(s)", t.Days)
+}
+
+// archiveStorageClasses are the Glacier classes we report transitions to
+var archiveStorageClasses = map[string]bool{
+	"GLACIER":      true,
+	"GLACIER_IR":   true,
+	"DEEP_ARCHIVE": true,
+}
+
+// ExpiresNoncurrentVersions reports whether an enabled rule without a filter
+// expires noncurrent object versions across the whole bucket
+func (l LifecycleStatus) ExpiresNoncurrentVersions() bool {
+	for _, r := range l.Rules {
+		if r.Enabled && r.Scope == "" && r.NoncurrentExpirationDays > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopedNoncurrentExpirations lists the scopes of enabled rules that expire
+// noncurrent object versions for part of the bucket only
+func (l LifecycleStatus) ScopedNoncurrentExpirations() []string {
+	var scopes []string
+	for _, r := range l.Rules {
+		if r.Enabled && r.Scope != "" && r.NoncurrentExpirationDays > 0 {
+			scopes = append(scopes, r.Scope)
+		}
+	}
+	return scopes
+}
+
+// AbortsIncompleteUploads reports whether an enabled rule without a filter
+// aborts incomplete multipart uploads across the whole bucket
+func (l LifecycleStatus) AbortsIncompleteUploads() bool {
+	for _, r := range l.Rules {
+		if r.Enabled && r.Scope == "" && r.AbortIncompleteUploadDays > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// ScopedUploadAborts lists the scopes of enabled rules that abort incomplete
+// multipart uploads for part of the bucket only
+func (l LifecycleStatus) ScopedUploadAborts() []string {
+	var scopes []string
+	for _, r := range l.Rules {
+		if r.Enabled && r.Scope != "" && r.AbortIncompleteUploadDays > 0 {
+			scopes = append(scopes, r.Scope)
+		}
+	}
+	return scopes
+}
+
+// ArchiveTransitions lists the transitions of enabled rules to Glacier and
+// Deep Archive storage classes
+func (l LifecycleStatus) ArchiveTransitions() []string {
+	var transitions []string
+	for _, r := range l.Rules {
+		if !r.Enabled {
+			continue
+		}
+		for _, t := range r.Transitions {
+			if archiveStorageClasses[t.StorageClass] {
+				transitions = append(transitions, fmt.Sprintf("%s %s", t.StorageClass, t.when()))
+			}
+		}
+		for _, t := range r.NoncurrentTransitions {
+			if archiveStorageClasses[t.StorageClass] {
+				transitions = append(transitions, fmt.Sprintf("noncurrent to %s %s", t.StorageClass, t.when()))
+			}
+		}
+	}
+	return transitions
+}
+
+// Summary describes the rule in one line, e.g.
+// "logs (prefix logs/): expire after 90d, abort uploads after 7d"
+func (r LifecycleRule) Summary() string {
+	var actions []string
+	if r.ExpirationDays > 0 {
+		actions = append(actions, fmt.Sprintf("expire after %dd", r.ExpirationDays))
+	}
+	if r.ExpirationDate != "" {
+		actions = append(actions, "expire on "+r.ExpirationDate)
+	}
+	if r.ExpiresDeleteMarkers {
+		actions = append(actions, "remove expired delete markers")
+	}
+	if r.NoncurrentExpirationDays > 0 {
+		actions = append(actions, fmt.Sprintf("expire noncurrent after %dd", r.NoncurrentExpirationDays))
+	}
+	if r.AbortIncompleteUploadDays > 0 {
+		actions = append(actions, fmt.Sprintf("abort uploads after %dd", r.AbortIncompleteUploadDays))
+	}
+	for _, t := range r.Transitions {
+		if t.Date != "" {
+			actions = append(actions, fmt.Sprintf("%s on %s", t.StorageClass, t.Date))
+			continue
+		}
+		actions = append(actions, fmt.Sprintf("%s after %dd", t.StorageClass, t.Days))
+	}
+	for _, t := range r.NoncurrentTransitions {
+		actions = append(actions, fmt.Sprintf("noncurrent %s after %dd", t.StorageClass, t.Days))
+	}
+	if len(actions) == 0 {
+		actions = append(actions, "no actions")
+	}
+
+	name := r.ID
+	if name == "" {
+		name = "(unnamed)"
+	}
+	if r.Scope != "" {
+		name += " (" + r.Scope + ")"
+	}
+	if !r.Enabled {
+		name += " [disabled]"
+	}
+	return name + ": " + strings.Join(actions, ", ")
+}
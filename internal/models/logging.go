@@ -0,0 +1,18 @@
+package models
+
+// LoggingStatus describes how requests against a bucket are recorded
+type LoggingStatus struct {
+	Enabled      bool   `json:"enabled"`
+	TargetBucket string `json:"targetBucket,omitempty"`
+	TargetPrefix string `json:"targetPrefix,omitempty"`
+	// Denied is true when the logging configuration could not be read
+	Denied bool `json:"denied,omitempty"`
+	// DataEventsChecked is false when CloudTrail could not be consulted
+	DataEventsChecked bool `json:"dataEventsChecked"`
+	// DataEventTrails record write data events for the bucket, and reads
+	// unless ReadOnly data events are excluded
+	DataEventTrails []string `json:"dataEventTrails,omitempty"`
+	// ReadOnlyDataEventTrails record only read data events for the bucket, so
+	// changes to its objects go unrecorded
+	ReadOnlyDataEventTrails []string `json:"readOnlyDataEventTrails,omitempty"`
+}
@@ -0,0 +1,154 @@
+package models
+
+import (
+	"sort"
+	"time"
+)
+
+// MacieJobRecord is a Macie classification job the tool created and has not
+// yet collected the results of
+type MacieJobRecord struct {
+	JobID  string `json:"jobId"`
+	Bucket string `json:"bucket"`
+	// AccountID and Region locate the job; Macie jobs are regional, so only
+	// a session in the same account and region can see it
+	AccountID string `json:"accountId,omitempty"`
+	Region    string `json:"region,omitempty"`
+	// Definition fingerprints the scope, sampling and data identifiers the
+	// job was created with
+	Definition string    `json:"definition,omitempty"`
+	CreatedAt  time.Time `json:"createdAt"`
+}
+
+// InSession reports whether the job belongs to the given account and region.
+// Records that do not say where the job runs belong to no session.
+func (j MacieJobRecord) InSession(accountID, region string) bool {
+	return j.AccountID != "" && j.AccountID == accountID && j.Region != "" && j.Region == region
+}
+
+// SensitiveDataSummary breaks down the sensitive data found in a bucket
+type SensitiveDataSummary struct {
+	// Classifier is the backend that produced the summary: macie, local or llm
+	Classifier string `json:"classifier,omitempty"`
+	JobID      string `json:"jobId,omitempty"`
+	// ObjectsScanned is the number of objects the local or LLM classifier read
+	ObjectsScanned int `json:"objectsScanned,omitempty"`
+	// FindingCount is the number of findings the Macie job produced, or the
+	// number of affected objects for the local and LLM classifiers
+	FindingCount int `json:"findingCount"`
+	// Objects are ordered by severity, then by number of occurrences
+	Objects []SensitiveObject `json:"objects,omitempty"`
+	// Skipped lists the objects, and files inside them, the local or LLM
+	// classifier could not read text from
+	Skipped []SkippedObject `json:"skipped,omitempty"`
+}
+
+// SkippedObject is an object, or a file inside one, that content scanning
+// could not read text from
+type SkippedObject struct {
+	Key string `json:"key"`
+	// File is the path of the file inside the object, e.g. a file in an
+	// archive, with the paths in nested archives joined by "!"
+	File string `json:"file,omitempty"`
+	// Format is the detected MIME type, when known
+	Format string `json:"format,omitempty"`
+	Reason string `json:"reason"`
+}
+
+// SensitiveObject is an object Macie found sensitive data in
+type SensitiveObject struct {
+	Key         string   `json:"key"`
+	FindingID   string   `json:"findingId"`
+	FindingType string   `json:"findingType,omitempty"`
+	Severity    Severity `json:"severity"`
+	// Detections are ordered by number of occurrences
+	Detections []SensitiveDetection `json:"detections,omitempty"`
+}
+
+// SensitiveDetection counts the occurrences of one type of sensitive data
+type SensitiveDetection struct {
+	// Category is FINANCIAL_INFORMATION, PERSONAL_INFORMATION, CREDENTIALS
+	// or CUSTOM_IDENTIFIER
+	Category string `json:"category"`
+	// Type is the managed data identifier, e.g. CREDIT_CARD_NUMBER, or the
+	// name of the custom data identifier
+	Type  string `json:"type"`
+	Count int64  `json:"count"`
+	// Locations describe where occurrences were found, e.g. "line 12" or
+	// "cell C4"
+	Locations []string `json:"locations,omitempty"`
+}
+
+// Occurrences returns the number of occurrences of sensitive data in the object
+func (o SensitiveObject) Occurrences() int64 {
+	var total int64
+	for _, d := range o.Detections {
+		total += d.Count
+	}
+	return total
+}
+
+// Categories returns the distinct categories of sensitive data in the object
+func (o SensitiveObject) Categories() []string {
+	var categories []string
+	seen := map[string]bool{}
+	for _, d := range o.Detections {
+		if !seen[d.Category] {
+			seen[d.Category] = true
+			categories = append(categories, d.Category)
+		}
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Occurrences returns the number of occurrences of sensitive data in the bucket
+func (s SensitiveDataSummary) Occurrences() int64 {
+	var total int64
+	for _, o := range s.Objects {
+		total += o.Occurrences()
+	}
+	return total
+}
+
+// CategoryCounts totals the occurrences per category across all objects
+func (s SensitiveDataSummary) CategoryCounts() map[string]int64 {
+	counts := map[string]int64{}
+	for _, o := range s.Objects {
+		for _, d := range o.Detections {
+			counts[d.Category] += d.Count
+		}
+	}
+	return counts
+}
+
+// TypeCounts totals the occurrences per type across all objects
+func (s SensitiveDataSummary) TypeCounts() map[string]int64 {
+	counts := map[string]int64{}
+	for _, o := range s.Objects {
+		for _, d := range o.Detections {
+			counts[d.Type] += d.Count
+		}
+	}
+	return counts
+}
+
+// Sort orders the objects by severity, then by number of occurrences, and
+// the detections of each object by number of occurrences
+func (s *SensitiveDataSummary) Sort() {
+	for _, o := range s.Objects {
+		sort.SliceStable(o.Detections, func(i, j int) bool {
+			return o.Detections[i].Count > o.Detections[j].Count
+		})
+	}
+	sort.SliceStable(s.Objects, func(i, j int) bool {
+		a, b := s.Objects[i], s.Objects[j]
+		if a.Severity != b.Severity {
+			return a.Severity > b.Severity
+		}
+		if a.Occurrences() != b.Occurrences() {
+			return a.Occurrences() > b.Occurrences()
+		}
+		return a.Key < b.Key
+	})
+}
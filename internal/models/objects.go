@@ -0,0 +1,16 @@
+package models
+
+// ObjectSample is the result of inspecting individual objects in a bucket
+type ObjectSample struct {
+	Prefix string `json:"prefix,omitempty"`
+	// Sampled is the number of objects inspected
+	Sampled            int      `json:"sampled"`
+	PublicObjects      []string `json:"publicObjects,omitempty"`
+	UnencryptedObjects []string `json:"unencryptedObjects,omitempty"`
+	// Encryption counts objects per server-side encryption algorithm
+	Encryption map[string]int `json:"encryption,omitempty"`
+	// KMSKeys counts objects per KMS key
+	KMSKeys map[string]int `json:"kmsKeys,omitempty"`
+	// Errors lists objects that could not be inspected
+	Errors []string `json:"errors,omitempty"`
+}
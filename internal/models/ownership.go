@@ -0,0 +1,55 @@
+package models
+
+// Object Ownership settings; a bucket without ownership controls behaves as
+// ObjectOwnershipObjectWriter
+const (
+	ObjectOwnershipEnforced     = "BucketOwnerEnforced"
+	ObjectOwnershipPreferred    = "BucketOwnerPreferred"
+	ObjectOwnershipObjectWriter = "ObjectWriter"
+)
+
+// OwnershipStatus describes who owns new objects and whether ACLs still
+// grant access to the bucket
+type OwnershipStatus struct {
+	ObjectOwnership string `json:"objectOwnership"`
+	// Configured is false when the bucket has no ownership controls
+	Configured     bool       `json:"configured"`
+	NonOwnerGrants []ACLGrant `json:"nonOwnerGrants,omitempty"`
+}
+
+// ACLGrant is a bucket ACL grant to someone other than the bucket owner
+type ACLGrant struct {
+	// Type is CanonicalUser, Group or AmazonCustomerByEmail
+	Type string `json:"type"`
+	// Grantee is a canonical user ID, group URI or email address
+	Grantee    string `json:"grantee"`
+	Permission string `json:"permission"`
+}
+
+// groupNames are the ACL groups S3 predefines
+var groupNames = map[string]string{
+	"http://acs.amazonaws.com/groups/global/AllUsers":           "everyone",
+	"http://acs.amazonaws.com/groups/global/AuthenticatedUsers": "any AWS account",
+	"http://acs.amazonaws.com/groups/s3/LogDelivery":            "the S3 log delivery group",
+}
+
+// ACLsEnabled reports whether ACLs still affect access to the bucket
+func (o OwnershipStatus) ACLsEnabled() bool {
+	return o.ObjectOwnership != ObjectOwnershipEnforced
+}
+
+// Summary names the grantee, e.g. "canonical user 79a5..." or
+// "the S3 log delivery group"
+func (g ACLGrant) Summary() string {
+	if name, ok := groupNames[g.Grantee]; ok {
+		return name
+	}
+	switch g.Type {
+	case "CanonicalUser":
+		return "canonical user " + g.Grantee
+	case "Group":
+		return "group " + g.Grantee
+	default:
+		return g.Grantee
+	}
+}
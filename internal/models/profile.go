@@ -0,0 +1,79 @@
+package models
+
+// Kinds of personal data a profiled column can hold
+const (
+	ColumnEmail      = "email"
+	ColumnName       = "name"
+	ColumnPhone      = "phone"
+	ColumnCardNumber = "card_number"
+	ColumnNationalID = "national_id"
+	ColumnFreeText   = "free_text"
+)
+
+// BucketProfile is the result of profiling the tabular objects of a bucket
+type BucketProfile struct {
+	Bucket string `json:"bucket"`
+	Region string `json:"region,omitempty"`
+	// ObjectsRead is the number of objects rows were read from
+	ObjectsRead int              `json:"objectsRead"`
+	Datasets    []DatasetProfile `json:"datasets,omitempty"`
+	// Skipped lists the objects, and columns inside them, no rows could be
+	// read from
+	Skipped []SkippedObject `json:"skipped,omitempty"`
+}
+
+// DatasetProfile describes the columns of a dataset: a single object, or the
+// part files of a partitioned table
+type DatasetProfile struct {
+	// Name is the key of the object, or the prefix of a partitioned table
+	// ending in "/"
+	Name string `json:"name"`
+	// Format is csv, json or parquet
+	Format  string `json:"format"`
+	Objects int    `json:"objects"`
+	// Rows is the number of rows sampled
+	Rows    int             `json:"rows"`
+	Columns []ColumnProfile `json:"columns"`
+}
+
+// ColumnProfile describes a column from a sample of its values. Values are
+// never recorded, so profiles are safe to share.
+type ColumnProfile struct {
+	Name string `json:"name"`
+	// Type is inferred from the values: integer, number, boolean, date,
+	// timestamp or string, or empty when every value sampled was
+	Type string `json:"type"`
+	// Values is the number of values sampled, Empty how many of them were
+	// empty or null
+	Values int `json:"values"`
+	Empty  int `json:"empty"`
+	// Classification names the kind of personal data the column holds, e.g.
+	// email, when enough of its values match
+	Classification string `json:"classification,omitempty"`
+	// MatchRatio is the share of the non-empty values that match the
+	// classification
+	MatchRatio float64  `json:"matchRatio,omitempty"`
+	Severity   Severity `json:"severity"`
+}
+
+// Sensitivity returns the severity of the most sensitive column
+func (d DatasetProfile) Sensitivity() Severity {
+	highest := SeverityNone
+	for _, c := range d.Columns {
+		if c.Severity > highest {
+			highest = c.Severity
+		}
+	}
+	return highest
+}
+
+// SensitiveColumns returns the number of columns that hold personal data
+func (d DatasetProfile) SensitiveColumns() int {
+	n := 0
+	for _, c := range d.Columns {
+		if c.Classification != "" {
+			n++
+		}
+	}
+	return n
+}
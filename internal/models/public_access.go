@@ -0,0 +1,82 @@
+package models
+
+import "strings"
+
+// PublicAccessBlock holds the four S3 Block Public Access settings
+type PublicAccessBlock struct {
+	BlockPublicAcls       bool `json:"blockPublicAcls"`
+	IgnorePublicAcls      bool `json:"ignorePublicAcls"`
+	BlockPublicPolicy     bool `json:"blockPublicPolicy"`
+	RestrictPublicBuckets bool `json:"restrictPublicBuckets"`
+}
+
+// PublicAccessBlockStatus combines the account and bucket level settings.
+// S3 enforces a setting when either level enables it.
+type PublicAccessBlockStatus struct {
+	Account           PublicAccessBlock `json:"account"`
+	AccountConfigured bool              `json:"accountConfigured"`
+	Bucket            PublicAccessBlock `json:"bucket"`
+	BucketConfigured  bool              `json:"bucketConfigured"`
+	// BucketDenied is true when the bucket settings could not be read, so
+	// only the account settings are known
+	BucketDenied bool              `json:"bucketDenied,omitempty"`
+	Effective    PublicAccessBlock `json:"effective"`
+}
+
+// NewPublicAccessBlockStatus builds the status with its effective setting
+func NewPublicAccessBlockStatus(account PublicAccessBlock, accountConfigured bool, bucket PublicAccessBlock, bucketConfigured bool) PublicAccessBlockStatus {
+	return PublicAccessBlockStatus{
+		Account:           account,
+		AccountConfigured: accountConfigured,
+		Bucket:            bucket,
+		BucketConfigured:  bucketConfigured,
+		Effective:         account.Merge(bucket),
+	}
+}
+
+// Merge returns the settings enforced when both p and other apply
+func (p PublicAccessBlock) Merge(other PublicAccessBlock) PublicAccessBlock {
+	return PublicAccessBlock{
+		BlockPublicAcls:       p.BlockPublicAcls || other.BlockPublicAcls,
+		IgnorePublicAcls:      p.IgnorePublicAcls || other.IgnorePublicAcls,
+		BlockPublicPolicy:     p.BlockPublicPolicy || other.BlockPublicPolicy,
+		RestrictPublicBuckets: p.RestrictPublicBuckets || other.RestrictPublicBuckets,
+	}
+}
+
+// AllEnabled reports whether every setting is enabled
+func (p PublicAccessBlock) AllEnabled() bool {
+	return p.BlockPublicAcls && p.IgnorePublicAcls && p.BlockPublicPolicy && p.RestrictPublicBuckets
+}
+
+// Disabled returns the names of the settings that are not enabled
+func (p PublicAccessBlock) Disabled() []string {
+	var names []string
+	for _, field := range []struct {
+		name    string
+		enabled bool
+	}{
+		{"BlockPublicAcls", p.BlockPublicAcls},
+		{"IgnorePublicAcls", p.IgnorePublicAcls},
+		{"BlockPublicPolicy", p.BlockPublicPolicy},
+		{"RestrictPublicBuckets", p.RestrictPublicBuckets},
+	} {
+		if !field.enabled {
+			names = append(names, field.name)
+		}
+	}
+	return names
+}
+
+// Summary describes the settings for reports, e.g. "all enabled"
+func (p PublicAccessBlock) Summary() string {
+	disabled := p.Disabled()
+	switch len(disabled) {
+	case 0:
+		return "all enabled"
+	case 4:
+		return "none enabled"
+	default:
+		return "missing " + strings.Join(disabled, ", ")
+	}
+}
@@ -0,0 +1,71 @@
+package models
+
+import "strings"
+
+// ReplicationStatus describes how a bucket's data is copied elsewhere
+type ReplicationStatus struct {
+	Configured bool              `json:"configured"`
+	Role       string            `json:"role,omitempty"`
+	Rules      []ReplicationRule `json:"rules,omitempty"`
+	// Critical is true when the bucket is tagged as holding critical data
+	Critical bool `json:"critical"`
+	// TagsDenied is true when the bucket tags could not be read, leaving
+	// Critical false
+	TagsDenied bool `json:"tagsDenied,omitempty"`
+	// BackupProtected is nil when AWS Backup could not be consulted
+	BackupProtected *bool `json:"backupProtected,omitempty"`
+}
+
+// ReplicationRule is a single replication rule and its destination
+type ReplicationRule struct {
+	ID                 string `json:"id,omitempty"`
+	Enabled            bool   `json:"enabled"`
+	DestinationBucket  string `json:"destinationBucket"`
+	DestinationAccount string `json:"destinationAccount,omitempty"`
+	StorageClass       string `json:"storageClass,omitempty"`
+	// ReplicaKMSKeyID is the key replicas of SSE-KMS objects are encrypted with
+	ReplicaKMSKeyID         string `json:"replicaKmsKeyId,omitempty"`
+	ReplicatesKMSObjects    bool   `json:"replicatesKmsObjects"`
+	DeleteMarkerReplication bool   `json:"deleteMarkerReplication"`
+	// DestinationVersioning is "Unknown" when the destination cannot be read,
+	// typically because it belongs to another account
+	DestinationVersioning string `json:"destinationVersioning"`
+}
+
+// Replicates reports whether any rule is enabled
+func (r ReplicationStatus) Replicates() bool {
+	for _, rule := range r.Rules {
+		if rule.Enabled {
+			return true
+		}
+	}
+	return false
+}
+
+// Summary describes the rule in one line, e.g.
+// "dr -> backup-bucket (account 111122223333): versioning Enabled, delete markers"
+func (r ReplicationRule) Summary() string {
+	name := r.ID
+	if name == "" {
+		name = "(unnamed)"
+	}
+	if !r.Enabled {
+		name += " [disabled]"
+	}
+	destination := r.DestinationBucket
+	if r.DestinationAccount != "" {
+		destination += " (account " + r.DestinationAccount + ")"
+	}
+
+	details := []string{"versioning " + r.DestinationVersioning}
+	if r.StorageClass != "" {
+		details = append(details, r.StorageClass)
+	}
+	if r.ReplicatesKMSObjects {
+		details = append(details, "KMS objects with "+r.ReplicaKMSKeyID)
+	}
+	if r.DeleteMarkerReplication {
+		details = append(details, "delete markers")
+	}
+	return name + " -> " + destination + ": " + strings.Join(details, ", ")
+}
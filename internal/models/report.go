@@ -0,0 +1,16 @@
+package models
+
+import "time"
+
+// AuditReport is the document written by headless audit runs
+type AuditReport struct {
+	GeneratedAt time.Time     `json:"generatedAt"`
+	Buckets     []BucketInfo  `json:"buckets"`
+	Errors      []BucketError `json:"errors,omitempty"`
+}
+
+// BucketError records a bucket that could not be audited
+type BucketError struct {
+	Bucket string `json:"bucket"`
+	Error  string `json:"error"`
+}
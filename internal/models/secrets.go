@@ -0,0 +1,41 @@
+package models
+
+// Confidence levels of a secret detection rule
+const (
+	ConfidenceLow    = "low"
+	ConfidenceMedium = "medium"
+	ConfidenceHigh   = "high"
+)
+
+// SecretScan is the result of scanning object contents for secrets
+type SecretScan struct {
+	// ObjectsScanned is the number of objects read
+	ObjectsScanned int           `json:"objectsScanned"`
+	Secrets        []SecretMatch `json:"secrets,omitempty"`
+	// Skipped lists the objects, and files inside them, no text could be
+	// read from
+	Skipped []SkippedObject `json:"skipped,omitempty"`
+}
+
+// SecretMatch is a secret found in an object
+type SecretMatch struct {
+	Key string `json:"key"`
+	// File is the path of the file inside the object the secret is in, for
+	// archives and workbooks
+	File string `json:"file,omitempty"`
+	// Rule names the kind of secret, e.g. aws-access-key-id
+	Rule       string `json:"rule"`
+	Confidence string `json:"confidence"`
+	Line       int    `json:"line"`
+	// Snippet is the surrounding text with the secret redacted
+	Snippet string `json:"snippet"`
+}
+
+// Objects returns the number of distinct objects that contain secrets
+func (s SecretScan) Objects() int {
+	keys := map[string]bool{}
+	for _, secret := range s.Secrets {
+		keys[secret.Key] = true
+	}
+	return len(keys)
+}
@@ -0,0 +1,14 @@
+package models
+
+// TLSStatus describes whether a bucket policy refuses insecure transport
+type TLSStatus struct {
+	// Enforced is true when plain-HTTP requests are denied for every
+	// principal and action on both the bucket and its objects
+	Enforced bool `json:"enforced"`
+	// Partial is true when a SecureTransport deny exists but leaves gaps
+	Partial       bool     `json:"partial,omitempty"`
+	Gaps          []string `json:"gaps,omitempty"`
+	MinTLSVersion string   `json:"minTlsVersion,omitempty"`
+	// Denied is true when the bucket policy could not be read
+	Denied bool `json:"denied,omitempty"`
+}
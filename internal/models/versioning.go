@@ -0,0 +1,42 @@
+package models
+
+import "fmt"
+
+// Versioning states reported by S3; a bucket that never had versioning
+// enabled has no status, which we report as VersioningDisabled, and one we
+// cannot read is VersioningUnknown
+const (
+	VersioningEnabled   = "Enabled"
+	VersioningSuspended = "Suspended"
+	VersioningDisabled  = "Disabled"
+	VersioningUnknown   = "Unknown"
+)
+
+// VersioningStatus describes how well a bucket's objects are protected
+// against overwrites and deletes
+type VersioningStatus struct {
+	Status     string            `json:"status"`
+	MFADelete  bool              `json:"mfaDelete"`
+	ObjectLock *ObjectLockStatus `json:"objectLock,omitempty"`
+}
+
+// ObjectLockStatus is the Object Lock configuration of a bucket
+type ObjectLockStatus struct {
+	Enabled bool `json:"enabled"`
+	// Mode is GOVERNANCE or COMPLIANCE when a default retention is set
+	Mode  string `json:"mode,omitempty"`
+	Days  int32  `json:"days,omitempty"`
+	Years int32  `json:"years,omitempty"`
+}
+
+// Retention describes the default retention period, e.g. "30 days"
+func (o ObjectLockStatus) Retention() string {
+	switch {
+	case o.Years > 0:
+		return fmt.Sprintf("%d year(s)", o.Years)
+	case o.Days > 0:
+		return fmt.Sprintf("%d day(s)", o.Days)
+	default:
+		return "none"
+	}
+}
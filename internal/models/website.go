@@ -0,0 +1,35 @@
+package models
+
+// WebsiteStatus describes the static website hosting configuration of a
+// bucket
+type WebsiteStatus struct {
+	Enabled       bool   `json:"enabled"`
+	IndexDocument string `json:"indexDocument,omitempty"`
+	ErrorDocument string `json:"errorDocument,omitempty"`
+	// RedirectAllTo is set when every request is redirected to another host
+	RedirectAllTo string   `json:"redirectAllTo,omitempty"`
+	RoutingRules  []string `json:"routingRules,omitempty"`
+	// PubliclyReadable is nil when public access was not checked
+	PubliclyReadable *bool `json:"publiclyReadable,omitempty"`
+}
+
+// Summary describes the website configuration, e.g.
+// "website-hosted and publicly readable"
+func (w WebsiteStatus) Summary() string {
+	if !w.Enabled {
+		return "Not Enabled"
+	}
+
+	summary := "website-hosted"
+	if w.RedirectAllTo != "" {
+		summary = "redirects all requests to " + w.RedirectAllTo
+	}
+	if w.PubliclyReadable != nil {
+		if *w.PubliclyReadable {
+			summary += " and publicly readable"
+		} else {
+			summary += " but not publicly readable"
+		}
+	}
+	return summary
+}